@@ -0,0 +1,201 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+
+	"plassstic.tech/trainee/avito/internal/schema"
+)
+
+func (c *Client) AddTeam(ctx context.Context, team Team) (*Team, error) {
+	var resp schema.AddTeamResponse
+	if err := c.do(ctx, call{method: http.MethodPost, path: "/team/add", body: team, idempotent: true}, &resp); err != nil {
+		return nil, err
+	}
+	return &resp.Team, nil
+}
+
+func (c *Client) GetTeam(ctx context.Context, teamName string) (*TeamTree, error) {
+	var team TeamTree
+	q := url.Values{"team_name": {teamName}}
+	if err := c.do(ctx, call{method: http.MethodGet, path: "/team/get", query: q, idempotent: true}, &team); err != nil {
+		return nil, err
+	}
+	return &team, nil
+}
+
+func (c *Client) AddTeamMembers(ctx context.Context, teamName string, members []TeamMember) (*Team, error) {
+	var resp schema.AddTeamResponse
+	req := schema.AddTeamMembersRequest{TeamName: teamName, Members: members}
+	if err := c.do(ctx, call{method: http.MethodPost, path: "/team/addMembers", body: req, idempotent: true}, &resp); err != nil {
+		return nil, err
+	}
+	return &resp.Team, nil
+}
+
+func (c *Client) RemoveTeamMembers(ctx context.Context, teamName string, userIDs ...string) (*Team, error) {
+	var resp schema.AddTeamResponse
+	req := schema.RemoveTeamMembersRequest{TeamName: teamName, UserIDs: userIDs}
+	if err := c.do(ctx, call{method: http.MethodPost, path: "/team/removeMembers", body: req, idempotent: true}, &resp); err != nil {
+		return nil, err
+	}
+	return &resp.Team, nil
+}
+
+func (c *Client) MoveTeamMember(ctx context.Context, req MoveTeamMemberRequest) (*MoveTeamMemberResponse, error) {
+	var resp MoveTeamMemberResponse
+	if err := c.do(ctx, call{method: http.MethodPost, path: "/team/moveMember", body: req, idempotent: true}, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// SetTeamParent puts teamName under parentTeam, or makes it a top-level team
+// when parentTeam is empty.
+func (c *Client) SetTeamParent(ctx context.Context, teamName, parentTeam string) (*Team, error) {
+	var resp schema.AddTeamResponse
+	req := SetTeamParentRequest{TeamName: teamName, ParentTeam: parentTeam}
+	if err := c.do(ctx, call{method: http.MethodPost, path: "/team/setParent", body: req, idempotent: true}, &resp); err != nil {
+		return nil, err
+	}
+	return &resp.Team, nil
+}
+
+// DeleteTeam hides the team; PurgeTeam removes it for good.
+func (c *Client) DeleteTeam(ctx context.Context, teamName string) (*Team, error) {
+	return c.deleteTeam(ctx, "/team/delete", teamName)
+}
+
+func (c *Client) PurgeTeam(ctx context.Context, teamName string) (*Team, error) {
+	return c.deleteTeam(ctx, "/team/purge", teamName)
+}
+
+func (c *Client) deleteTeam(ctx context.Context, path, teamName string) (*Team, error) {
+	var resp schema.AddTeamResponse
+	req := schema.DeleteTeamRequest{TeamName: teamName}
+	if err := c.do(ctx, call{method: http.MethodPost, path: path, body: req, idempotent: true}, &resp); err != nil {
+		return nil, err
+	}
+	return &resp.Team, nil
+}
+
+func (c *Client) SetUserActive(ctx context.Context, userID string, isActive bool) (*User, error) {
+	var resp schema.UserResponse
+	req := schema.SetUserActiveRequest{UserID: userID, IsActive: isActive}
+	if err := c.do(ctx, call{method: http.MethodPost, path: "/users/setIsActive", body: req, idempotent: true}, &resp); err != nil {
+		return nil, err
+	}
+	return &resp.User, nil
+}
+
+func (c *Client) GetUserReviews(ctx context.Context, userID string) (*UserReviewsResponse, error) {
+	var resp UserReviewsResponse
+	q := url.Values{"user_id": {userID}}
+	if err := c.do(ctx, call{method: http.MethodGet, path: "/users/getReview", query: q, idempotent: true}, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// DeleteUser hides the user and hands their open reviews to others; PurgeUser
+// removes them for good.
+func (c *Client) DeleteUser(ctx context.Context, userID string) (*DeleteUserResponse, error) {
+	return c.deleteUser(ctx, "/users/delete", userID)
+}
+
+func (c *Client) PurgeUser(ctx context.Context, userID string) (*DeleteUserResponse, error) {
+	return c.deleteUser(ctx, "/users/purge", userID)
+}
+
+func (c *Client) deleteUser(ctx context.Context, path, userID string) (*DeleteUserResponse, error) {
+	var resp DeleteUserResponse
+	req := schema.DeleteUserRequest{UserID: userID}
+	if err := c.do(ctx, call{method: http.MethodPost, path: path, body: req, idempotent: true}, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+func (c *Client) CreatePR(ctx context.Context, req CreatePRRequest) (*PullRequest, error) {
+	var resp schema.PRResponse
+	if err := c.do(ctx, call{method: http.MethodPost, path: "/pullRequest/create", body: req, idempotent: true}, &resp); err != nil {
+		return nil, err
+	}
+	return &resp.PR, nil
+}
+
+// CreatePRBatch returns the per-item results of a best-effort batch even when
+// some items failed; a rolled back atomic batch comes back as an *Error with
+// the code of the first failed item.
+func (c *Client) CreatePRBatch(ctx context.Context, req CreatePRBatchRequest) (*CreatePRBatchResponse, error) {
+	var resp CreatePRBatchResponse
+	if err := c.do(ctx, call{method: http.MethodPost, path: "/pullRequest/createBatch", body: req, idempotent: true}, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+func (c *Client) MergePR(ctx context.Context, prID string) (*PullRequest, error) {
+	var resp schema.PRResponse
+	req := schema.MergePRRequest{PRId: prID}
+	if err := c.do(ctx, call{method: http.MethodPost, path: "/pullRequest/merge", body: req, idempotent: true}, &resp); err != nil {
+		return nil, err
+	}
+	return &resp.PR, nil
+}
+
+func (c *Client) ReassignReviewer(ctx context.Context, prID, oldUserID string) (*ReassignResponse, error) {
+	var resp ReassignResponse
+	req := schema.ReassignReviewerRequest{PRId: prID, OldUserID: oldUserID}
+	if err := c.do(ctx, call{method: http.MethodPost, path: "/pullRequest/reassign", body: req, idempotent: true}, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+func (c *Client) IssueAPIKey(ctx context.Context, req IssueAPIKeyRequest) (*APIKey, error) {
+	var resp schema.APIKeyResponse
+	if err := c.do(ctx, call{method: http.MethodPost, path: "/auth/issueKey", body: req, idempotent: true}, &resp); err != nil {
+		return nil, err
+	}
+	return &resp.Key, nil
+}
+
+func (c *Client) RevokeAPIKey(ctx context.Context, keyID string) (*APIKey, error) {
+	var resp schema.APIKeyResponse
+	req := schema.RevokeAPIKeyRequest{KeyID: keyID}
+	if err := c.do(ctx, call{method: http.MethodPost, path: "/auth/revokeKey", body: req, idempotent: true}, &resp); err != nil {
+		return nil, err
+	}
+	return &resp.Key, nil
+}
+
+func (c *Client) Live(ctx context.Context) error {
+	return c.do(ctx, call{method: http.MethodGet, path: "/livez", idempotent: true}, nil)
+}
+
+// Ready returns the readiness report; a not-ready service yields the report
+// together with an *Error carrying status 503.
+func (c *Client) Ready(ctx context.Context) (*ReadinessReport, error) {
+	req, err := c.newRequest(ctx, call{method: http.MethodGet, path: "/readyz"}, nil, "")
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	var report ReadinessReport
+	if err = json.NewDecoder(resp.Body).Decode(&report); err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return &report, &Error{StatusCode: resp.StatusCode, Code: Unknown, Msg: "service is " + report.Status}
+	}
+	return &report, nil
+}
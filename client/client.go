@@ -0,0 +1,233 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"plassstic.tech/trainee/avito/internal/schema"
+)
+
+const (
+	apiKeyHeader      = "X-API-Key"
+	idempotencyHeader = "Idempotency-Key"
+	ifMatchHeader     = "If-Match"
+	requestIDHeader   = "X-Request-ID"
+)
+
+type Client struct {
+	baseURL *url.URL
+	http    *http.Client
+	editors []func(*http.Request)
+
+	retries    int
+	backoff    time.Duration
+	maxBackoff time.Duration
+}
+
+type Option func(*Client)
+
+// WithHTTPClient replaces the default client, e.g. to add a transport or timeout.
+func WithHTTPClient(hc *http.Client) Option {
+	return func(c *Client) {
+		c.http = hc
+	}
+}
+
+func WithAPIKey(key string) Option {
+	return WithRequestEditor(func(r *http.Request) {
+		r.Header.Set(apiKeyHeader, key)
+	})
+}
+
+func WithBearerToken(token string) Option {
+	return WithRequestEditor(func(r *http.Request) {
+		r.Header.Set("Authorization", "Bearer "+token)
+	})
+}
+
+// WithRequestEditor runs fn on every outgoing request before it is sent.
+func WithRequestEditor(fn func(*http.Request)) Option {
+	return func(c *Client) {
+		c.editors = append(c.editors, fn)
+	}
+}
+
+// WithRetries sets how many times idempotent calls are retried after a
+// transport error, 429 or 502/503/504, and the initial backoff between tries.
+func WithRetries(n int, backoff time.Duration) Option {
+	return func(c *Client) {
+		c.retries = n
+		c.backoff = backoff
+	}
+}
+
+func New(baseURL string, opts ...Option) (*Client, error) {
+	u, err := url.Parse(strings.TrimSuffix(baseURL, "/"))
+	if err != nil {
+		return nil, err
+	}
+
+	c := &Client{
+		baseURL:    u,
+		http:       &http.Client{Timeout: 10 * time.Second},
+		retries:    3,
+		backoff:    200 * time.Millisecond,
+		maxBackoff: 5 * time.Second,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c, nil
+}
+
+type ifMatchKey struct{}
+
+// IfMatch makes MergePR and ReassignReviewer calls made with the returned
+// context fail with PreconditionFailed unless the PR is still at version.
+func IfMatch(ctx context.Context, version int64) context.Context {
+	return context.WithValue(ctx, ifMatchKey{}, version)
+}
+
+type call struct {
+	method string
+	path   string
+	query  url.Values
+	body   any
+	// idempotent calls are retried; POSTs become idempotent by sending an
+	// Idempotency-Key that stays the same across attempts.
+	idempotent bool
+}
+
+func (c *Client) do(ctx context.Context, cl call, out any) error {
+	var payload []byte
+	if cl.body != nil {
+		var err error
+		if payload, err = json.Marshal(cl.body); err != nil {
+			return err
+		}
+	}
+
+	var idemKey string
+	if cl.method == http.MethodPost && cl.idempotent {
+		idemKey = rand.Text()
+	}
+
+	backoff := c.backoff
+	for attempt := 0; ; attempt++ {
+		req, err := c.newRequest(ctx, cl, payload, idemKey)
+		if err != nil {
+			return err
+		}
+
+		resp, err := c.http.Do(req)
+		retry, wait := c.shouldRetry(cl, resp, err, attempt)
+		if !retry {
+			if err != nil {
+				return err
+			}
+			return decode(resp, out)
+		}
+
+		if resp != nil {
+			_, _ = io.Copy(io.Discard, resp.Body)
+			_ = resp.Body.Close()
+		}
+		if wait == 0 {
+			wait = backoff
+			backoff = min(backoff*2, c.maxBackoff)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(wait):
+		}
+	}
+}
+
+func (c *Client) newRequest(ctx context.Context, cl call, payload []byte, idemKey string) (*http.Request, error) {
+	u := c.baseURL.JoinPath(cl.path)
+	if len(cl.query) > 0 {
+		u.RawQuery = cl.query.Encode()
+	}
+
+	var body io.Reader
+	if payload != nil {
+		body = bytes.NewReader(payload)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, cl.method, u.String(), body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if idemKey != "" {
+		req.Header.Set(idempotencyHeader, idemKey)
+	}
+	if version, ok := ctx.Value(ifMatchKey{}).(int64); ok {
+		req.Header.Set(ifMatchHeader, strconv.Quote(strconv.FormatInt(version, 10)))
+	}
+	for _, edit := range c.editors {
+		edit(req)
+	}
+	return req, nil
+}
+
+func (c *Client) shouldRetry(cl call, resp *http.Response, err error, attempt int) (bool, time.Duration) {
+	if !cl.idempotent || attempt >= c.retries {
+		return false, 0
+	}
+	if err != nil {
+		return !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded), 0
+	}
+
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests, http.StatusServiceUnavailable:
+		if secs, perr := strconv.Atoi(resp.Header.Get("Retry-After")); perr == nil {
+			return true, time.Duration(secs) * time.Second
+		}
+		return true, 0
+	case http.StatusBadGateway, http.StatusGatewayTimeout:
+		return true, 0
+	default:
+		return false, 0
+	}
+}
+
+func decode(resp *http.Response, out any) error {
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode >= 300 {
+		var er schema.ErrorResponse
+		if err := json.NewDecoder(resp.Body).Decode(&er); err != nil || er.Code == "" {
+			return &Error{
+				StatusCode: resp.StatusCode,
+				Code:       Unknown,
+				Msg:        resp.Status,
+				RequestID:  resp.Header.Get(requestIDHeader),
+			}
+		}
+		return fromResponse(resp.StatusCode, er)
+	}
+
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("decode %s response: %w", resp.Request.URL.Path, err)
+	}
+	return nil
+}
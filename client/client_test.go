@@ -0,0 +1,260 @@
+package client_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"plassstic.tech/trainee/avito/client"
+	"plassstic.tech/trainee/avito/internal/router"
+	"plassstic.tech/trainee/avito/internal/utils"
+)
+
+const adminKey = "test-admin-key"
+
+// newServer serves the real router over the in-memory demo store, so every
+// test starts from the demo teams and pull requests.
+func newServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	cfg, err := utils.LoadConfig(map[string]string{
+		"SERVER_DEMO":       "true",
+		"AUTH_ENABLED":      "true",
+		"AUTH_ADMIN_KEY":    adminKey,
+		"RATELIMIT_ENABLED": "false",
+		"METRICS_PORT":      "0",
+		"METRICS_TOKEN":     "",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	srv := httptest.NewServer(router.New(utils.SetupBox(t.Context(), cfg)))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func newClient(t *testing.T, url string, opts ...client.Option) *client.Client {
+	t.Helper()
+	c, err := client.New(url, append([]client.Option{client.WithRetries(0, 0)}, opts...)...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func wantCode(t *testing.T, err error, code client.ErrorCode, status int) *client.Error {
+	t.Helper()
+	if !client.IsCode(err, code) {
+		t.Fatalf("expected %s, got %v", code, err)
+	}
+	e := err.(*client.Error)
+	if e.StatusCode != status {
+		t.Fatalf("expected status %d, got %d", status, e.StatusCode)
+	}
+	if e.RequestID == "" {
+		t.Fatalf("error has no request id: %v", e)
+	}
+	return e
+}
+
+func TestTeams(t *testing.T) {
+	c := newClient(t, newServer(t).URL, client.WithAPIKey(adminKey))
+	ctx := t.Context()
+
+	backend, err := c.GetTeam(ctx, "backend")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(backend.Members) != 4 || backend.ActiveMembers != 3 {
+		t.Fatalf("unexpected backend %+v", backend)
+	}
+
+	mobile := client.Team{TeamName: "mobile", Members: []client.TeamMember{{UserID: "m1", UserName: "Mia", IsActive: true}}}
+	if _, err = c.AddTeam(ctx, mobile); err != nil {
+		t.Fatal(err)
+	}
+	_, err = c.AddTeam(ctx, mobile)
+	wantCode(t, err, client.TeamExists, http.StatusBadRequest)
+
+	if _, err = c.SetTeamParent(ctx, "mobile", "backend"); err != nil {
+		t.Fatal(err)
+	}
+	backend, err = c.GetTeam(ctx, "backend")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(backend.SubTeams) != 1 || backend.SubTeams[0].TeamName != "mobile" || backend.ActiveMembers != 4 {
+		t.Fatalf("unexpected backend tree %+v", backend)
+	}
+	_, err = c.SetTeamParent(ctx, "backend", "mobile")
+	wantCode(t, err, client.TeamCycle, http.StatusConflict)
+
+	_, err = c.GetTeam(ctx, "nope")
+	wantCode(t, err, client.NotFound, http.StatusNotFound)
+}
+
+func TestPullRequests(t *testing.T) {
+	c := newClient(t, newServer(t).URL, client.WithAPIKey(adminKey))
+	ctx := t.Context()
+
+	pr, err := c.CreatePR(ctx, client.CreatePRRequest{PRId: "pr-1", Name: "Add client tests", AuthorID: "u1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pr.AssignedReviewers) != 2 || pr.Version != 1 || pr.TeamName != "backend" {
+		t.Fatalf("unexpected pr %+v", pr)
+	}
+	_, err = c.CreatePR(ctx, client.CreatePRRequest{PRId: "pr-1", Name: "Again", AuthorID: "u1"})
+	wantCode(t, err, client.PRExists, http.StatusConflict)
+
+	// both active teammates already review it
+	_, err = c.ReassignReviewer(ctx, "pr-1", pr.AssignedReviewers[0])
+	wantCode(t, err, client.NoCandidate, http.StatusNotFound)
+
+	if _, err = c.AddTeamMembers(ctx, "backend", []client.TeamMember{{UserID: "u7", UserName: "Grace", IsActive: true}}); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = c.ReassignReviewer(client.IfMatch(ctx, pr.Version+1), "pr-1", pr.AssignedReviewers[0])
+	wantCode(t, err, client.PreconditionFailed, http.StatusPreconditionFailed)
+
+	res, err := c.ReassignReviewer(client.IfMatch(ctx, pr.Version), "pr-1", pr.AssignedReviewers[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.NewUser != "u7" || res.PR.Version != 2 || !slices.Contains(res.PR.AssignedReviewers, "u7") {
+		t.Fatalf("unexpected reassignment %+v", res)
+	}
+
+	reviews, err := c.GetUserReviews(ctx, "u7")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reviews.PullRequests) != 1 || reviews.PullRequests[0].PRId != "pr-1" {
+		t.Fatalf("unexpected reviews %+v", reviews)
+	}
+
+	merged, err := c.MergePR(ctx, "pr-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	again, err := c.MergePR(ctx, "pr-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again.MergedAt != merged.MergedAt || again.Version != merged.Version {
+		t.Fatalf("merge is not idempotent: %+v then %+v", merged, again)
+	}
+
+	_, err = c.ReassignReviewer(ctx, "pr-1", "u7")
+	wantCode(t, err, client.PRMerged, http.StatusNotFound)
+}
+
+func TestValidation(t *testing.T) {
+	c := newClient(t, newServer(t).URL, client.WithAPIKey(adminKey))
+
+	_, err := c.CreatePR(t.Context(), client.CreatePRRequest{PRId: "pr 1", AuthorID: "u1"})
+	e := wantCode(t, err, client.ValidationFailed, http.StatusBadRequest)
+
+	fields := make([]string, len(e.Fields))
+	for i, f := range e.Fields {
+		fields[i] = f.Field
+	}
+	slices.Sort(fields)
+	if !slices.Equal(fields, []string{"pull_request_id", "pull_request_name"}) {
+		t.Fatalf("unexpected field errors %+v", e.Fields)
+	}
+}
+
+func TestAuth(t *testing.T) {
+	srv := newServer(t)
+	admin := newClient(t, srv.URL, client.WithAPIKey(adminKey))
+	ctx := t.Context()
+
+	_, err := newClient(t, srv.URL).GetTeam(ctx, "backend")
+	wantCode(t, err, client.Unauthorized, http.StatusUnauthorized)
+
+	key, err := admin.IssueAPIKey(ctx, client.IssueAPIKeyRequest{UserID: "u2", Role: client.RoleUser})
+	if err != nil {
+		t.Fatal(err)
+	}
+	user := newClient(t, srv.URL, client.WithAPIKey(key.Key))
+
+	if _, err = user.GetUserReviews(ctx, "u2"); err != nil {
+		t.Fatal(err)
+	}
+	_, err = user.AddTeam(ctx, client.Team{TeamName: "mine"})
+	wantCode(t, err, client.InsufficientRole, http.StatusForbidden)
+	_, err = user.SetUserActive(ctx, "u3", false)
+	wantCode(t, err, client.Forbidden, http.StatusForbidden)
+
+	if _, err = admin.RevokeAPIKey(ctx, key.KeyID); err != nil {
+		t.Fatal(err)
+	}
+	_, err = user.GetUserReviews(ctx, "u2")
+	wantCode(t, err, client.Unauthorized, http.StatusUnauthorized)
+}
+
+func TestHealth(t *testing.T) {
+	c := newClient(t, newServer(t).URL)
+	if err := c.Live(t.Context()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Ready(t.Context()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestRetries checks that a POST is retried after 503 with the same
+// Idempotency-Key, and that other errors are returned at once.
+func TestRetries(t *testing.T) {
+	var (
+		mu   sync.Mutex
+		keys []string
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		keys = append(keys, r.Header.Get("Idempotency-Key"))
+		n := len(keys)
+		mu.Unlock()
+
+		w.Header().Set("Content-Type", "application/json")
+		switch n {
+		case 1:
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusServiceUnavailable)
+		case 2:
+			w.WriteHeader(http.StatusBadGateway)
+		case 3:
+			_, _ = w.Write([]byte(`{"pr":{"pull_request_id":"pr-1","status":"MERGED","version":2}}`))
+		default:
+			w.WriteHeader(http.StatusConflict)
+			_, _ = w.Write([]byte(`{"error":{"code":"PR_MERGED","msg":"merged","request_id":"r1"}}`))
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	c, err := client.New(srv.URL, client.WithRetries(3, time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pr, err := c.MergePR(t.Context(), "pr-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pr.Version != 2 {
+		t.Fatalf("unexpected pr %+v", pr)
+	}
+	if len(keys) != 3 || keys[0] == "" || keys[0] != keys[1] || keys[1] != keys[2] {
+		t.Fatalf("expected 3 attempts with one Idempotency-Key, got %q", keys)
+	}
+
+	_, err = c.MergePR(t.Context(), "pr-1")
+	if !client.IsCode(err, client.PRMerged) || len(keys) != 4 {
+		t.Fatalf("expected one failed attempt, got %v after %d requests", err, len(keys))
+	}
+}
@@ -0,0 +1,110 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+
+	"plassstic.tech/trainee/avito/internal/health"
+	"plassstic.tech/trainee/avito/internal/schema"
+)
+
+// Aliases let callers outside this module name the API types.
+type (
+	Team                   = schema.Team
+	TeamTree               = schema.TeamTree
+	TeamMember             = schema.TeamMember
+	SetTeamParentRequest   = schema.SetTeamParentRequest
+	User                   = schema.User
+	MoveTeamMemberRequest  = schema.MoveTeamMemberRequest
+	MoveTeamMemberResponse = schema.MoveTeamMemberResponse
+	Reassignment           = schema.Reassignment
+	DeleteUserResponse     = schema.DeleteUserResponse
+	ReviewsOnMove          = schema.ReviewsOnMove
+	PullRequest            = schema.PullRequest
+	PullRequestShort       = schema.PullRequestShort
+	CreatePRRequest        = schema.CreatePRRequest
+	CreatePRBatchRequest   = schema.CreatePRBatchRequest
+	CreatePRBatchResponse  = schema.CreatePRBatchResponse
+	PRBatchItem            = schema.PRBatchItem
+	BatchMode              = schema.BatchMode
+	ReassignResponse       = schema.ReassignResponse
+	UserReviewsResponse    = schema.UserReviewsResponse
+	APIKey                 = schema.APIKey
+	IssueAPIKeyRequest     = schema.IssueAPIKeyRequest
+	Role                   = schema.Role
+	ErrorCode              = schema.ErrorCode
+	FieldError             = schema.FieldError
+	ReadinessReport        = health.Report
+)
+
+const (
+	ReviewsKeep     = schema.ReviewsKeep
+	ReviewsReassign = schema.ReviewsReassign
+)
+
+const (
+	BatchAtomic     = schema.BatchAtomic
+	BatchBestEffort = schema.BatchBestEffort
+)
+
+const (
+	RoleAdmin    = schema.RoleAdmin
+	RoleTeamLead = schema.RoleTeamLead
+	RoleUser     = schema.RoleUser
+)
+
+const (
+	PRMerged              = schema.PRMerged
+	TeamExists            = schema.TeamExists
+	PRExists              = schema.PRExists
+	NotAssigned           = schema.NotAssigned
+	NoCandidate           = schema.NoCandidate
+	NotFound              = schema.NotFound
+	Unknown               = schema.Unknown
+	Unauthorized          = schema.Unauthorized
+	InsufficientRole      = schema.InsufficientRole
+	Forbidden             = schema.Forbidden
+	IdempotencyKeyReused  = schema.IdempotencyKeyReused
+	IdempotencyInProgress = schema.IdempotencyInProgress
+	RateLimited           = schema.RateLimited
+	ValidationFailed      = schema.ValidationFailed
+	TooManyReviewers      = schema.TooManyReviewers
+	UserInOtherTeam       = schema.UserInOtherTeam
+	TeamCycle             = schema.TeamCycle
+	Conflict              = schema.Conflict
+	PreconditionFailed    = schema.PreconditionFailed
+)
+
+// Error is a non-2xx response decoded from the API's error envelope.
+type Error struct {
+	StatusCode int
+	Code       ErrorCode
+	Msg        string
+	Details    map[string]any
+	Fields     []FieldError
+	RequestID  string
+}
+
+func (e *Error) Error() string {
+	if e.RequestID != "" {
+		return fmt.Sprintf("%s: %s (status %d, request %s)", e.Code, e.Msg, e.StatusCode, e.RequestID)
+	}
+	return fmt.Sprintf("%s: %s (status %d)", e.Code, e.Msg, e.StatusCode)
+}
+
+func fromResponse(status int, resp schema.ErrorResponse) *Error {
+	return &Error{
+		StatusCode: status,
+		Code:       resp.Code,
+		Msg:        resp.Msg,
+		Details:    resp.Details,
+		Fields:     resp.Fields,
+		RequestID:  resp.RequestID,
+	}
+}
+
+// IsCode reports whether err is an API error with the given code.
+func IsCode(err error, code ErrorCode) bool {
+	var e *Error
+	return errors.As(err, &e) && e.Code == code
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"os"
+
+	"plassstic.tech/trainee/avito/internal/schema"
+	"plassstic.tech/trainee/avito/internal/utils"
+)
+
+func deactivateCmd(*flag.FlagSet, overrides) runFunc {
+	return func(ctx context.Context, cfg *utils.Config, args []string) error {
+		if len(args) == 0 {
+			return errors.New("usage: users deactivate <user_id>...")
+		}
+
+		svc := newService(ctx, cfg)
+		for _, userID := range args {
+			user, err := svc.SetUserActive(ctx, userID, false)
+			if err != nil {
+				return err
+			}
+			if werr := writeJSON(os.Stdout, schema.UserResponse{User: *user}); werr != nil {
+				return werr
+			}
+		}
+		return nil
+	}
+}
+
+func reassignCmd(*flag.FlagSet, overrides) runFunc {
+	return func(ctx context.Context, cfg *utils.Config, args []string) error {
+		if len(args) != 2 {
+			return errors.New("usage: pr reassign <pull_request_id> <old_reviewer_id>")
+		}
+
+		newUser, pr, err := newService(ctx, cfg).ReassignReviewer(ctx, args[0], args[1], nil)
+		if err != nil {
+			return err
+		}
+		return writeJSON(os.Stdout, schema.ReassignResponse{PR: *pr, NewUser: newUser})
+	}
+}
@@ -0,0 +1,23 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+
+	"github.com/rs/zerolog/log"
+	"plassstic.tech/trainee/avito/internal/utils"
+)
+
+func checkConfigCmd(*flag.FlagSet, overrides) runFunc {
+	return func(_ context.Context, cfg *utils.Config, _ []string) error {
+		for _, line := range cfg.Describe() {
+			fmt.Println(line)
+		}
+		if err := cfg.Validate(); err != nil {
+			return err
+		}
+		log.Info().Msg("configuration is valid")
+		return nil
+	}
+}
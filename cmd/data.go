@@ -0,0 +1,115 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"io"
+	"os"
+
+	"github.com/rs/zerolog/log"
+	"plassstic.tech/trainee/avito/internal/schema"
+	"plassstic.tech/trainee/avito/internal/service"
+	"plassstic.tech/trainee/avito/internal/utils"
+)
+
+func newService(ctx context.Context, cfg *utils.Config) service.Service {
+	box := utils.SetupBox(ctx, cfg)
+	return service.NewWithStore(box.Store(), box.Events(), box.EventSource())
+}
+
+func readJSON(path string, v any) error {
+	if path == "" {
+		return errors.New("-file is required")
+	}
+
+	var r io.Reader = os.Stdin
+	if path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		r = f
+	}
+	return json.NewDecoder(r).Decode(v)
+}
+
+func writeJSON(w io.Writer, v any) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+func seedCmd(fs *flag.FlagSet, _ overrides) runFunc {
+	file := fs.String("file", "", "JSON array of teams in the /team/add format, - for stdin")
+
+	return func(ctx context.Context, cfg *utils.Config, _ []string) error {
+		var teams []schema.Team
+		if err := readJSON(*file, &teams); err != nil {
+			return err
+		}
+		for _, team := range teams {
+			if verr := schema.Validate(team); verr != nil {
+				return verr
+			}
+		}
+
+		svc := newService(ctx, cfg)
+		for _, team := range teams {
+			_, err := svc.AddTeam(ctx, team)
+			switch {
+			case err == nil:
+				log.Info().Str("team", team.TeamName).Int("members", len(team.Members)).Msg("team added")
+			case err.Code == schema.TeamExists:
+				log.Warn().Str("team", team.TeamName).Msg("team exists, skipped")
+			default:
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+func exportCmd(fs *flag.FlagSet, _ overrides) runFunc {
+	out := fs.String("out", "-", "file to write the dump to, - for stdout")
+
+	return func(ctx context.Context, cfg *utils.Config, _ []string) error {
+		dump, err := newService(ctx, cfg).Export(ctx)
+		if err != nil {
+			return err
+		}
+
+		if *out == "-" {
+			return writeJSON(os.Stdout, dump)
+		}
+		f, ferr := os.Create(*out)
+		if ferr != nil {
+			return ferr
+		}
+		defer f.Close()
+		return writeJSON(f, dump)
+	}
+}
+
+func importCmd(fs *flag.FlagSet, _ overrides) runFunc {
+	file := fs.String("file", "", "dump produced by export, - for stdin")
+
+	return func(ctx context.Context, cfg *utils.Config, _ []string) error {
+		var dump schema.Dump
+		if err := readJSON(*file, &dump); err != nil {
+			return err
+		}
+		if verr := schema.Validate(dump); verr != nil {
+			return verr
+		}
+
+		res, err := newService(ctx, cfg).Import(ctx, dump)
+		if err != nil {
+			return err
+		}
+		log.Info().Int("teams", len(res.Teams)).Int("pull_requests", len(res.PullRequests)).Msg("imported")
+		return nil
+	}
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+)
+
+// overrides collects flag values by the env var they replace.
+type overrides map[string]string
+
+func (o overrides) set(key string) func(string) error {
+	return func(v string) error {
+		o[key] = v
+		return nil
+	}
+}
+
+func (o overrides) env(fs *flag.FlagSet, name, key, usage string) {
+	fs.Func(name, fmt.Sprintf("%s (%s)", usage, key), o.set(key))
+}
+
+func (o overrides) toggle(fs *flag.FlagSet, name, key, usage string) {
+	fs.BoolFunc(name, fmt.Sprintf("%s (%s)", usage, key), func(v string) error {
+		o[key] = v
+		return nil
+	})
+}
+
+func (o overrides) register(fs *flag.FlagSet) {
+	o.env(fs, "pg-host", "POSTGRES_HOST", "postgres host")
+	o.env(fs, "pg-port", "POSTGRES_PORT", "postgres port")
+	o.env(fs, "pg-user", "POSTGRES_USER", "postgres user")
+	o.env(fs, "pg-password", "POSTGRES_PASSWORD", "postgres password")
+	o.env(fs, "pg-db", "POSTGRES_DB", "postgres database")
+	o.env(fs, "tracing-endpoint", "TRACING_ENDPOINT", "OTLP/HTTP endpoint")
+	fs.Func("set", "override any setting, KEY=VALUE, repeatable", func(v string) error {
+		key, value, ok := strings.Cut(v, "=")
+		if !ok || key == "" {
+			return fmt.Errorf("expected KEY=VALUE, got %q", v)
+		}
+		o[key] = value
+		return nil
+	})
+}
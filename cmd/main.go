@@ -2,20 +2,84 @@ package main
 
 import (
 	"context"
+	"flag"
+	"fmt"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 
-	"plassstic.tech/trainee/avito/internal/router"
+	"github.com/rs/zerolog/log"
 	"plassstic.tech/trainee/avito/internal/utils"
 )
 
+type runFunc func(ctx context.Context, cfg *utils.Config, args []string) error
+
+type command struct {
+	name  string
+	usage string
+	// setup registers command specific flags and returns the command body.
+	setup func(fs *flag.FlagSet, o overrides) runFunc
+}
+
+var commands = []command{
+	{name: "serve", usage: "run the HTTP server (default)", setup: serveCmd},
+	{name: "migrate", usage: "migrate up|down|status|check", setup: migrateCmd},
+	{name: "seed", usage: "seed -file teams.json: add teams with members", setup: seedCmd},
+	{name: "export", usage: "export [-out dump.json]: dump teams and pull requests", setup: exportCmd},
+	{name: "import", usage: "import -file dump.json: load a dump in one transaction", setup: importCmd},
+	{name: "users deactivate", usage: "users deactivate <user_id>...", setup: deactivateCmd},
+	{name: "pr reassign", usage: "pr reassign <pull_request_id> <old_reviewer_id>", setup: reassignCmd},
+	{name: "check-config", usage: "validate and print the effective configuration", setup: checkConfigCmd},
+}
+
+func findCommand(args []string) (command, []string, bool) {
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		return commands[0], args, true
+	}
+	for _, c := range commands {
+		words := strings.Fields(c.name)
+		if len(args) >= len(words) && strings.Join(args[:len(words)], " ") == c.name {
+			return c, args[len(words):], true
+		}
+	}
+	return command{}, nil, false
+}
+
+func usage() {
+	_, _ = fmt.Fprintln(os.Stderr, "usage: service <command> [flags]")
+	_, _ = fmt.Fprintln(os.Stderr, "\ncommands:")
+	for _, c := range commands {
+		_, _ = fmt.Fprintf(os.Stderr, "  %-18s %s\n", c.name, c.usage)
+	}
+	_, _ = fmt.Fprintln(os.Stderr, "\nflags override the matching environment variables, see <command> -h")
+}
+
 func main() {
 	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
 	defer stop()
 
 	utils.SetupLogging()
-	cfg := utils.ParseConfig()
 
-	router.New(utils.SetupBox(ctx, cfg)).Serve(ctx, cfg.Server.Port)
+	cmd, args, ok := findCommand(os.Args[1:])
+	if !ok {
+		usage()
+		os.Exit(2)
+	}
+
+	fs := flag.NewFlagSet(cmd.name, flag.ExitOnError)
+	o := overrides{}
+	o.register(fs)
+	run := cmd.setup(fs, o)
+	_ = fs.Parse(args)
+
+	cfg, err := utils.LoadConfig(o)
+	if err != nil {
+		log.Fatal().Err(err).Msg("invalid configuration")
+	}
+	defer utils.SetupTracing(ctx, cfg.Tracing)()
+
+	if err = run(ctx, cfg, fs.Args()); err != nil {
+		log.Fatal().Err(err).Str("command", cmd.name).Msg("command failed")
+	}
 }
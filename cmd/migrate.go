@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/rs/zerolog/log"
+	"plassstic.tech/trainee/avito/internal/migrate"
+	"plassstic.tech/trainee/avito/internal/repo"
+	"plassstic.tech/trainee/avito/internal/utils"
+)
+
+func migrateCmd(*flag.FlagSet, overrides) runFunc {
+	return func(ctx context.Context, cfg *utils.Config, args []string) error {
+		if len(args) != 1 {
+			return errors.New("usage: migrate up|down|status|check")
+		}
+
+		if cfg.Storage.Backend == "sqlite" {
+			return migrateSQLite(ctx, cfg.Storage, args[0])
+		}
+
+		pool := utils.ConnectPg(ctx, cfg.PgConfig)
+		defer pool.Close()
+
+		if args[0] == "check" {
+			return checkSchema(ctx, pool)
+		}
+
+		m, err := migrate.FromPool(pool)
+		if err != nil {
+			return err
+		}
+		defer m.Close()
+
+		return runMigrator(ctx, m, args[0])
+	}
+}
+
+func migrateSQLite(ctx context.Context, cfg utils.Storage, command string) error {
+	if command == "check" {
+		return errors.New("migrate check compares postgres schemas, run it with STORAGE_BACKEND=postgres")
+	}
+
+	db, err := repo.OpenSQLite(cfg.SQLitePath)
+	if err != nil {
+		return err
+	}
+	m, err := migrate.NewSQLite(db)
+	if err != nil {
+		_ = db.Close()
+		return err
+	}
+	defer m.Close()
+
+	return runMigrator(ctx, m, command)
+}
+
+func runMigrator(ctx context.Context, m *migrate.Migrator, command string) error {
+	switch command {
+	case "up":
+		return m.Up(ctx)
+	case "down":
+		return m.Down(ctx)
+	case "status":
+		return printStatus(ctx, m)
+	default:
+		return fmt.Errorf("unknown migrate command %q", command)
+	}
+}
+
+func checkSchema(ctx context.Context, pool *pgxpool.Pool) error {
+	diff, err := migrate.Check(ctx, pool)
+	if err != nil {
+		return err
+	}
+	for _, d := range diff {
+		fmt.Println(d)
+	}
+	if len(diff) > 0 {
+		return fmt.Errorf("sqlc/schema.sql does not match migrations: %d differences", len(diff))
+	}
+	log.Info().Msg("sqlc/schema.sql matches migrations")
+	return nil
+}
+
+func printStatus(ctx context.Context, m *migrate.Migrator) error {
+	status, err := m.Status(ctx)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "VERSION\tSTATE\tAPPLIED AT\tFILE")
+	for _, s := range status {
+		applied := "-"
+		if !s.AppliedAt.IsZero() {
+			applied = s.AppliedAt.Format("2006-01-02 15:04:05")
+		}
+		_, _ = fmt.Fprintf(w, "%d\t%s\t%s\t%s\n", s.Source.Version, s.State, applied, s.Source.Path)
+	}
+	return w.Flush()
+}
@@ -0,0 +1,24 @@
+package main
+
+import (
+	"context"
+	"flag"
+
+	"plassstic.tech/trainee/avito/internal/router"
+	"plassstic.tech/trainee/avito/internal/utils"
+)
+
+func serveCmd(fs *flag.FlagSet, o overrides) runFunc {
+	o.env(fs, "port", "SERVER_PORT", "port to listen on")
+	o.env(fs, "metrics-port", "METRICS_PORT", "separate port for /metrics")
+	o.toggle(fs, "auto-migrate", "POSTGRES_AUTO_MIGRATE", "apply migrations on startup")
+	o.toggle(fs, "demo", "SERVER_DEMO", "serve from memory with sample data, no postgres needed")
+
+	return func(ctx context.Context, cfg *utils.Config, _ []string) error {
+		if err := cfg.Validate(); err != nil {
+			return err
+		}
+		router.New(utils.SetupBox(ctx, cfg)).Serve(ctx, cfg.Server.Port)
+		return nil
+	}
+}
@@ -10,12 +10,63 @@ import (
 	"errors"
 
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
 )
 
 var (
 	ErrBatchAlreadyClosed = errors.New("batch already closed")
 )
 
+const addReviewerBatch = `-- name: AddReviewerBatch :batchexec
+insert into reviewers_to_pull_requests (user_id, pull_req_id)
+values ($1, $2)
+`
+
+type AddReviewerBatchBatchResults struct {
+	br     pgx.BatchResults
+	tot    int
+	closed bool
+}
+
+type AddReviewerBatchParams struct {
+	UserID    string
+	PullReqID string
+}
+
+func (q *Queries) AddReviewerBatch(ctx context.Context, arg []AddReviewerBatchParams) *AddReviewerBatchBatchResults {
+	batch := &pgx.Batch{}
+	for _, a := range arg {
+		vals := []interface{}{
+			a.UserID,
+			a.PullReqID,
+		}
+		batch.Queue(addReviewerBatch, vals...)
+	}
+	br := q.db.SendBatch(ctx, batch)
+	return &AddReviewerBatchBatchResults{br, len(arg), false}
+}
+
+func (b *AddReviewerBatchBatchResults) Exec(f func(int, error)) {
+	defer b.br.Close()
+	for t := 0; t < b.tot; t++ {
+		if b.closed {
+			if f != nil {
+				f(t, ErrBatchAlreadyClosed)
+			}
+			continue
+		}
+		_, err := b.br.Exec()
+		if f != nil {
+			f(t, err)
+		}
+	}
+}
+
+func (b *AddReviewerBatchBatchResults) Close() error {
+	b.closed = true
+	return b.br.Close()
+}
+
 const addUsersToTeam = `-- name: AddUsersToTeam :batchexec
 insert into users_to_teams (user_id, team_name) 
 values ($1, $2) 
@@ -67,12 +118,79 @@ func (b *AddUsersToTeamBatchResults) Close() error {
 	return b.br.Close()
 }
 
+const createPRBatch = `-- name: CreatePRBatch :batchone
+insert into pull_requests (pull_req_id, pull_req_name, author_id, team_name)
+values ($1, $2, $3, $4)
+returning pull_req_id, pull_req_name, author_id, pull_req_status, created_at, merged_at, version, team_name
+`
+
+type CreatePRBatchBatchResults struct {
+	br     pgx.BatchResults
+	tot    int
+	closed bool
+}
+
+type CreatePRBatchParams struct {
+	PullReqID   string
+	PullReqName string
+	AuthorID    pgtype.Text
+	TeamName    pgtype.Text
+}
+
+func (q *Queries) CreatePRBatch(ctx context.Context, arg []CreatePRBatchParams) *CreatePRBatchBatchResults {
+	batch := &pgx.Batch{}
+	for _, a := range arg {
+		vals := []interface{}{
+			a.PullReqID,
+			a.PullReqName,
+			a.AuthorID,
+			a.TeamName,
+		}
+		batch.Queue(createPRBatch, vals...)
+	}
+	br := q.db.SendBatch(ctx, batch)
+	return &CreatePRBatchBatchResults{br, len(arg), false}
+}
+
+func (b *CreatePRBatchBatchResults) QueryRow(f func(int, PullRequest, error)) {
+	defer b.br.Close()
+	for t := 0; t < b.tot; t++ {
+		var i PullRequest
+		if b.closed {
+			if f != nil {
+				f(t, i, ErrBatchAlreadyClosed)
+			}
+			continue
+		}
+		row := b.br.QueryRow()
+		err := row.Scan(
+			&i.PullReqID,
+			&i.PullReqName,
+			&i.AuthorID,
+			&i.PullReqStatus,
+			&i.CreatedAt,
+			&i.MergedAt,
+			&i.Version,
+			&i.TeamName,
+		)
+		if f != nil {
+			f(t, i, err)
+		}
+	}
+}
+
+func (b *CreatePRBatchBatchResults) Close() error {
+	b.closed = true
+	return b.br.Close()
+}
+
 const ensureUsers = `-- name: EnsureUsers :batchexec
 insert into users (user_id, user_name, is_active) 
 values ($1, $2, $3) 
 on conflict (user_id) do update
-set user_name = excluded.user_name,
-    is_active = excluded.is_active
+set user_name  = excluded.user_name,
+    is_active  = excluded.is_active,
+    deleted_at = null
 `
 
 type EnsureUsersBatchResults struct {
@@ -121,3 +239,56 @@ func (b *EnsureUsersBatchResults) Close() error {
 	b.closed = true
 	return b.br.Close()
 }
+
+const insertUsers = `-- name: InsertUsers :batchexec
+insert into users (user_id, user_name, is_active) 
+values ($1, $2, $3) 
+on conflict (user_id) do nothing
+`
+
+type InsertUsersBatchResults struct {
+	br     pgx.BatchResults
+	tot    int
+	closed bool
+}
+
+type InsertUsersParams struct {
+	UserID   string
+	UserName string
+	IsActive bool
+}
+
+func (q *Queries) InsertUsers(ctx context.Context, arg []InsertUsersParams) *InsertUsersBatchResults {
+	batch := &pgx.Batch{}
+	for _, a := range arg {
+		vals := []interface{}{
+			a.UserID,
+			a.UserName,
+			a.IsActive,
+		}
+		batch.Queue(insertUsers, vals...)
+	}
+	br := q.db.SendBatch(ctx, batch)
+	return &InsertUsersBatchResults{br, len(arg), false}
+}
+
+func (b *InsertUsersBatchResults) Exec(f func(int, error)) {
+	defer b.br.Close()
+	for t := 0; t < b.tot; t++ {
+		if b.closed {
+			if f != nil {
+				f(t, ErrBatchAlreadyClosed)
+			}
+			continue
+		}
+		_, err := b.br.Exec()
+		if f != nil {
+			f(t, err)
+		}
+	}
+}
+
+func (b *InsertUsersBatchResults) Close() error {
+	b.closed = true
+	return b.br.Close()
+}
@@ -11,6 +11,49 @@ import (
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
+type ApiRole string
+
+const (
+	ApiRoleAdmin    ApiRole = "admin"
+	ApiRoleTeamLead ApiRole = "team_lead"
+	ApiRoleUser     ApiRole = "user"
+)
+
+func (e *ApiRole) Scan(src interface{}) error {
+	switch s := src.(type) {
+	case []byte:
+		*e = ApiRole(s)
+	case string:
+		*e = ApiRole(s)
+	default:
+		return fmt.Errorf("unsupported scan type for ApiRole: %T", src)
+	}
+	return nil
+}
+
+type NullApiRole struct {
+	ApiRole ApiRole
+	Valid   bool // Valid is true if ApiRole is not NULL
+}
+
+// Scan implements the Scanner interface.
+func (ns *NullApiRole) Scan(value interface{}) error {
+	if value == nil {
+		ns.ApiRole, ns.Valid = "", false
+		return nil
+	}
+	ns.Valid = true
+	return ns.ApiRole.Scan(value)
+}
+
+// Value implements the driver Valuer interface.
+func (ns NullApiRole) Value() (driver.Value, error) {
+	if !ns.Valid {
+		return nil, nil
+	}
+	return string(ns.ApiRole), nil
+}
+
 type Prstat string
 
 const (
@@ -53,13 +96,42 @@ func (ns NullPrstat) Value() (driver.Value, error) {
 	return string(ns.Prstat), nil
 }
 
+type ApiKey struct {
+	KeyID     string
+	KeyHash   []byte
+	UserID    pgtype.Text
+	Role      ApiRole
+	CreatedAt pgtype.Timestamp
+	RevokedAt pgtype.Timestamp
+}
+
+type IdempotencyKey struct {
+	IdemKey         string
+	Scope           string
+	RequestHash     []byte
+	StatusCode      pgtype.Int4
+	ResponseBody    []byte
+	CreatedAt       pgtype.Timestamp
+	ExpiresAt       pgtype.Timestamp
+	ResponseHeaders []byte
+}
+
 type PullRequest struct {
 	PullReqID     string
 	PullReqName   string
-	AuthorID      string
+	AuthorID      pgtype.Text
 	PullReqStatus Prstat
 	CreatedAt     pgtype.Timestamp
 	MergedAt      pgtype.Timestamp
+	Version       int64
+	TeamName      pgtype.Text
+}
+
+type RateLimitBucket struct {
+	BucketKey string
+	Tokens    float64
+	Allowed   bool
+	UpdatedAt pgtype.Timestamp
 }
 
 type ReviewersToPullRequest struct {
@@ -68,16 +140,20 @@ type ReviewersToPullRequest struct {
 }
 
 type Team struct {
-	TeamName string
+	TeamName   string
+	ParentTeam pgtype.Text
+	DeletedAt  pgtype.Timestamp
 }
 
 type User struct {
-	UserID   string
-	UserName string
-	IsActive bool
+	UserID    string
+	UserName  string
+	IsActive  bool
+	DeletedAt pgtype.Timestamp
 }
 
 type UsersToTeam struct {
-	UserID   string
-	TeamName string
+	UserID    string
+	TeamName  string
+	IsPrimary bool
 }
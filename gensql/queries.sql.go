@@ -29,6 +29,20 @@ func (q *Queries) AddReviewer(ctx context.Context, arg AddReviewerParams) (strin
 	return user_id, err
 }
 
+const bumpPRVersion = `-- name: BumpPRVersion :one
+update pull_requests
+set version = version + 1
+where pull_req_id = $1
+returning version
+`
+
+func (q *Queries) BumpPRVersion(ctx context.Context, pullReqID string) (int64, error) {
+	row := q.db.QueryRow(ctx, bumpPRVersion, pullReqID)
+	var version int64
+	err := row.Scan(&version)
+	return version, err
+}
+
 const checkPRExists = `-- name: CheckPRExists :one
 select exists(select 1 from pull_requests where pull_req_id = $1) as exists
 `
@@ -41,7 +55,7 @@ func (q *Queries) CheckPRExists(ctx context.Context, pullReqID string) (bool, er
 }
 
 const checkTeamExists = `-- name: CheckTeamExists :one
-select exists(select 1 from teams where team_name = $1) as exists
+select exists(select 1 from teams where team_name = $1 and deleted_at is null) as exists
 `
 
 func (q *Queries) CheckTeamExists(ctx context.Context, teamName string) (bool, error) {
@@ -52,7 +66,7 @@ func (q *Queries) CheckTeamExists(ctx context.Context, teamName string) (bool, e
 }
 
 const checkUserExists = `-- name: CheckUserExists :one
-select exists(select 1 from users where user_id = $1) as exists
+select exists(select 1 from users where user_id = $1 and deleted_at is null) as exists
 `
 
 func (q *Queries) CheckUserExists(ctx context.Context, userID string) (bool, error) {
@@ -62,6 +76,81 @@ func (q *Queries) CheckUserExists(ctx context.Context, userID string) (bool, err
 	return exists, err
 }
 
+const claimIdempotencyKey = `-- name: ClaimIdempotencyKey :execrows
+insert into idempotency_keys (idem_key, scope, request_hash, expires_at)
+values ($1, $2, $3, now() + make_interval(secs => $4::float8))
+on conflict (idem_key, scope) do nothing
+`
+
+type ClaimIdempotencyKeyParams struct {
+	IdemKey     string
+	Scope       string
+	RequestHash []byte
+	TtlSeconds  float64
+}
+
+func (q *Queries) ClaimIdempotencyKey(ctx context.Context, arg ClaimIdempotencyKeyParams) (int64, error) {
+	result, err := q.db.Exec(ctx, claimIdempotencyKey,
+		arg.IdemKey,
+		arg.Scope,
+		arg.RequestHash,
+		arg.TtlSeconds,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const clearPrimaryTeam = `-- name: ClearPrimaryTeam :exec
+update users_to_teams
+set is_primary = false
+where user_id = $1 and is_primary
+`
+
+func (q *Queries) ClearPrimaryTeam(ctx context.Context, userID string) error {
+	_, err := q.db.Exec(ctx, clearPrimaryTeam, userID)
+	return err
+}
+
+const completeIdempotencyKey = `-- name: CompleteIdempotencyKey :exec
+update idempotency_keys
+set status_code = $3, response_body = $4, response_headers = $5
+where idem_key = $1 and scope = $2
+`
+
+type CompleteIdempotencyKeyParams struct {
+	IdemKey         string
+	Scope           string
+	StatusCode      pgtype.Int4
+	ResponseBody    []byte
+	ResponseHeaders []byte
+}
+
+func (q *Queries) CompleteIdempotencyKey(ctx context.Context, arg CompleteIdempotencyKeyParams) error {
+	_, err := q.db.Exec(ctx, completeIdempotencyKey,
+		arg.IdemKey,
+		arg.Scope,
+		arg.StatusCode,
+		arg.ResponseBody,
+		arg.ResponseHeaders,
+	)
+	return err
+}
+
+const countOpenPRs = `-- name: CountOpenPRs :one
+select count(*) as open_count
+from pull_requests
+where pull_req_status = 'open'::prstat
+`
+
+func (q *Queries) CountOpenPRs(ctx context.Context) (int64, error) {
+	row := q.db.QueryRow(ctx, countOpenPRs)
+	var open_count int64
+	err := row.Scan(&open_count)
+	return open_count, err
+}
+
 const countReviewersForPR = `-- name: CountReviewersForPR :one
 select count(*) as reviewer_count
 from reviewers_to_pull_requests
@@ -75,20 +164,58 @@ func (q *Queries) CountReviewersForPR(ctx context.Context, pullReqID string) (in
 	return reviewer_count, err
 }
 
+const createAPIKey = `-- name: CreateAPIKey :one
+insert into api_keys (key_id, key_hash, user_id, role)
+values ($1, $2, $3, $4)
+returning key_id, key_hash, user_id, role, created_at, revoked_at
+`
+
+type CreateAPIKeyParams struct {
+	KeyID   string
+	KeyHash []byte
+	UserID  pgtype.Text
+	Role    ApiRole
+}
+
+func (q *Queries) CreateAPIKey(ctx context.Context, arg CreateAPIKeyParams) (ApiKey, error) {
+	row := q.db.QueryRow(ctx, createAPIKey,
+		arg.KeyID,
+		arg.KeyHash,
+		arg.UserID,
+		arg.Role,
+	)
+	var i ApiKey
+	err := row.Scan(
+		&i.KeyID,
+		&i.KeyHash,
+		&i.UserID,
+		&i.Role,
+		&i.CreatedAt,
+		&i.RevokedAt,
+	)
+	return i, err
+}
+
 const createPR = `-- name: CreatePR :one
-insert into pull_requests (pull_req_id, pull_req_name, author_id)
-values ($1, $2, $3)
-returning pull_req_id, pull_req_name, author_id, pull_req_status, created_at, merged_at
+insert into pull_requests (pull_req_id, pull_req_name, author_id, team_name)
+values ($1, $2, $3, $4)
+returning pull_req_id, pull_req_name, author_id, pull_req_status, created_at, merged_at, version, team_name
 `
 
 type CreatePRParams struct {
 	PullReqID   string
 	PullReqName string
-	AuthorID    string
+	AuthorID    pgtype.Text
+	TeamName    pgtype.Text
 }
 
 func (q *Queries) CreatePR(ctx context.Context, arg CreatePRParams) (PullRequest, error) {
-	row := q.db.QueryRow(ctx, createPR, arg.PullReqID, arg.PullReqName, arg.AuthorID)
+	row := q.db.QueryRow(ctx, createPR,
+		arg.PullReqID,
+		arg.PullReqName,
+		arg.AuthorID,
+		arg.TeamName,
+	)
 	var i PullRequest
 	err := row.Scan(
 		&i.PullReqID,
@@ -97,6 +224,8 @@ func (q *Queries) CreatePR(ctx context.Context, arg CreatePRParams) (PullRequest
 		&i.PullReqStatus,
 		&i.CreatedAt,
 		&i.MergedAt,
+		&i.Version,
+		&i.TeamName,
 	)
 	return i, err
 }
@@ -114,6 +243,103 @@ func (q *Queries) CreateTeam(ctx context.Context, teamName string) (string, erro
 	return team_name, err
 }
 
+const dropExpiredIdempotencyKey = `-- name: DropExpiredIdempotencyKey :exec
+delete from idempotency_keys
+where idem_key = $1 and scope = $2 and expires_at <= now()
+`
+
+type DropExpiredIdempotencyKeyParams struct {
+	IdemKey string
+	Scope   string
+}
+
+func (q *Queries) DropExpiredIdempotencyKey(ctx context.Context, arg DropExpiredIdempotencyKeyParams) error {
+	_, err := q.db.Exec(ctx, dropExpiredIdempotencyKey, arg.IdemKey, arg.Scope)
+	return err
+}
+
+const dropOpenReviews = `-- name: DropOpenReviews :many
+delete from reviewers_to_pull_requests rtp
+using pull_requests pr
+where pr.pull_req_id = rtp.pull_req_id
+  and rtp.user_id = $1
+  and pr.pull_req_status = 'open'::prstat
+returning rtp.pull_req_id
+`
+
+func (q *Queries) DropOpenReviews(ctx context.Context, userID string) ([]string, error) {
+	rows, err := q.db.Query(ctx, dropOpenReviews, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []string
+	for rows.Next() {
+		var pull_req_id string
+		if err := rows.Scan(&pull_req_id); err != nil {
+			return nil, err
+		}
+		items = append(items, pull_req_id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getAPIKey = `-- name: GetAPIKey :one
+select key_id, key_hash, user_id, role, created_at, revoked_at from api_keys
+where key_id = $1
+`
+
+func (q *Queries) GetAPIKey(ctx context.Context, keyID string) (ApiKey, error) {
+	row := q.db.QueryRow(ctx, getAPIKey, keyID)
+	var i ApiKey
+	err := row.Scan(
+		&i.KeyID,
+		&i.KeyHash,
+		&i.UserID,
+		&i.Role,
+		&i.CreatedAt,
+		&i.RevokedAt,
+	)
+	return i, err
+}
+
+const getActiveMembersOfTeams = `-- name: GetActiveMembersOfTeams :many
+select utt.team_name, utt.user_id
+from users_to_teams utt
+inner join users u on u.user_id = utt.user_id
+where utt.team_name = any($1::text[])
+  and u.is_active = true
+order by utt.team_name, utt.user_id
+`
+
+type GetActiveMembersOfTeamsRow struct {
+	TeamName string
+	UserID   string
+}
+
+func (q *Queries) GetActiveMembersOfTeams(ctx context.Context, dollar_1 []string) ([]GetActiveMembersOfTeamsRow, error) {
+	rows, err := q.db.Query(ctx, getActiveMembersOfTeams, dollar_1)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetActiveMembersOfTeamsRow
+	for rows.Next() {
+		var i GetActiveMembersOfTeamsRow
+		if err := rows.Scan(&i.TeamName, &i.UserID); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getActiveTeammates = `-- name: GetActiveTeammates :many
 select utt.user_id 
 from users_to_teams utt
@@ -148,8 +374,85 @@ func (q *Queries) GetActiveTeammates(ctx context.Context, arg GetActiveTeammates
 	return items, nil
 }
 
+const getDeletedUsers = `-- name: GetDeletedUsers :many
+select user_id from users
+where user_id = any($1::text[]) and deleted_at is not null
+order by user_id
+`
+
+func (q *Queries) GetDeletedUsers(ctx context.Context, dollar_1 []string) ([]string, error) {
+	rows, err := q.db.Query(ctx, getDeletedUsers, dollar_1)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []string
+	for rows.Next() {
+		var user_id string
+		if err := rows.Scan(&user_id); err != nil {
+			return nil, err
+		}
+		items = append(items, user_id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getExistingPRs = `-- name: GetExistingPRs :many
+select pull_req_id from pull_requests
+where pull_req_id = any($1::text[])
+`
+
+func (q *Queries) GetExistingPRs(ctx context.Context, dollar_1 []string) ([]string, error) {
+	rows, err := q.db.Query(ctx, getExistingPRs, dollar_1)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []string
+	for rows.Next() {
+		var pull_req_id string
+		if err := rows.Scan(&pull_req_id); err != nil {
+			return nil, err
+		}
+		items = append(items, pull_req_id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getIdempotencyKey = `-- name: GetIdempotencyKey :one
+select idem_key, scope, request_hash, status_code, response_body, created_at, expires_at, response_headers from idempotency_keys
+where idem_key = $1 and scope = $2
+`
+
+type GetIdempotencyKeyParams struct {
+	IdemKey string
+	Scope   string
+}
+
+func (q *Queries) GetIdempotencyKey(ctx context.Context, arg GetIdempotencyKeyParams) (IdempotencyKey, error) {
+	row := q.db.QueryRow(ctx, getIdempotencyKey, arg.IdemKey, arg.Scope)
+	var i IdempotencyKey
+	err := row.Scan(
+		&i.IdemKey,
+		&i.Scope,
+		&i.RequestHash,
+		&i.StatusCode,
+		&i.ResponseBody,
+		&i.CreatedAt,
+		&i.ExpiresAt,
+		&i.ResponseHeaders,
+	)
+	return i, err
+}
+
 const getPR = `-- name: GetPR :one
-select pull_req_id, pull_req_name, author_id, pull_req_status, created_at, merged_at from pull_requests
+select pull_req_id, pull_req_name, author_id, pull_req_status, created_at, merged_at, version, team_name from pull_requests
 where pull_req_id = $1
 `
 
@@ -163,6 +466,8 @@ func (q *Queries) GetPR(ctx context.Context, pullReqID string) (PullRequest, err
 		&i.PullReqStatus,
 		&i.CreatedAt,
 		&i.MergedAt,
+		&i.Version,
+		&i.TeamName,
 	)
 	return i, err
 }
@@ -177,7 +482,7 @@ where rtp.user_id = $1
 type GetPRsReviewedByUserRow struct {
 	PullReqID     string
 	PullReqName   string
-	AuthorID      string
+	AuthorID      pgtype.Text
 	PullReqStatus Prstat
 }
 
@@ -214,6 +519,8 @@ select
     pr.pull_req_status,
     pr.created_at,
     pr.merged_at,
+    pr.version,
+    pr.team_name,
     coalesce(
         array_agg(rtp.user_id) filter (where rtp.user_id is not null),
         array[]::text[]
@@ -221,16 +528,18 @@ select
 from pull_requests pr
 left join reviewers_to_pull_requests rtp on pr.pull_req_id = rtp.pull_req_id
 where pr.pull_req_id = $1
-group by pr.pull_req_id, pr.pull_req_name, pr.author_id, pr.pull_req_status, pr.created_at, pr.merged_at
+group by pr.pull_req_id, pr.pull_req_name, pr.author_id, pr.pull_req_status, pr.created_at, pr.merged_at, pr.version, pr.team_name
 `
 
 type GetPRwithReviewersRow struct {
 	PullReqID         string
 	PullReqName       string
-	AuthorID          string
+	AuthorID          pgtype.Text
 	PullReqStatus     Prstat
 	CreatedAt         pgtype.Timestamp
 	MergedAt          pgtype.Timestamp
+	Version           int64
+	TeamName          pgtype.Text
 	AssignedReviewers interface{}
 }
 
@@ -244,11 +553,46 @@ func (q *Queries) GetPRwithReviewers(ctx context.Context, pullReqID string) (Get
 		&i.PullReqStatus,
 		&i.CreatedAt,
 		&i.MergedAt,
+		&i.Version,
+		&i.TeamName,
 		&i.AssignedReviewers,
 	)
 	return i, err
 }
 
+const getReviewerLoad = `-- name: GetReviewerLoad :many
+select rtp.user_id, count(*) as open_reviews
+from reviewers_to_pull_requests rtp
+inner join pull_requests pr on pr.pull_req_id = rtp.pull_req_id
+where pr.pull_req_status = 'open'::prstat
+group by rtp.user_id
+`
+
+type GetReviewerLoadRow struct {
+	UserID      string
+	OpenReviews int64
+}
+
+func (q *Queries) GetReviewerLoad(ctx context.Context) ([]GetReviewerLoadRow, error) {
+	rows, err := q.db.Query(ctx, getReviewerLoad)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetReviewerLoadRow
+	for rows.Next() {
+		var i GetReviewerLoadRow
+		if err := rows.Scan(&i.UserID, &i.OpenReviews); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getReviewersForPR = `-- name: GetReviewersForPR :many
 select user_id
 from reviewers_to_pull_requests
@@ -275,16 +619,75 @@ func (q *Queries) GetReviewersForPR(ctx context.Context, pullReqID string) ([]st
 	return items, nil
 }
 
+const getSubTeams = `-- name: GetSubTeams :many
+select team_name from teams
+where parent_team = $1 and deleted_at is null
+order by team_name
+`
+
+func (q *Queries) GetSubTeams(ctx context.Context, parentTeam pgtype.Text) ([]string, error) {
+	rows, err := q.db.Query(ctx, getSubTeams, parentTeam)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []string
+	for rows.Next() {
+		var team_name string
+		if err := rows.Scan(&team_name); err != nil {
+			return nil, err
+		}
+		items = append(items, team_name)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getTeam = `-- name: GetTeam :one
-select team_name from teams 
-where team_name = $1
+select team_name, parent_team, deleted_at from teams 
+where team_name = $1 and deleted_at is null
 `
 
-func (q *Queries) GetTeam(ctx context.Context, teamName string) (string, error) {
+func (q *Queries) GetTeam(ctx context.Context, teamName string) (Team, error) {
 	row := q.db.QueryRow(ctx, getTeam, teamName)
-	var team_name string
-	err := row.Scan(&team_name)
-	return team_name, err
+	var i Team
+	err := row.Scan(&i.TeamName, &i.ParentTeam, &i.DeletedAt)
+	return i, err
+}
+
+const getTeamsOfUsers = `-- name: GetTeamsOfUsers :many
+select u.user_id, ut.team_name
+from users u
+left join users_to_teams ut using (user_id)
+where u.user_id = any($1::text[]) and u.deleted_at is null
+order by u.user_id, ut.is_primary desc, ut.team_name
+`
+
+type GetTeamsOfUsersRow struct {
+	UserID   string
+	TeamName pgtype.Text
+}
+
+func (q *Queries) GetTeamsOfUsers(ctx context.Context, dollar_1 []string) ([]GetTeamsOfUsersRow, error) {
+	rows, err := q.db.Query(ctx, getTeamsOfUsers, dollar_1)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetTeamsOfUsersRow
+	for rows.Next() {
+		var i GetTeamsOfUsersRow
+		if err := rows.Scan(&i.UserID, &i.TeamName); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
 }
 
 const getUserCoworkers = `-- name: GetUserCoworkers :many
@@ -315,24 +718,38 @@ func (q *Queries) GetUserCoworkers(ctx context.Context, userID string) ([]string
 	return items, nil
 }
 
-const getUserTeam = `-- name: GetUserTeam :one
+const getUserTeams = `-- name: GetUserTeams :many
 select team_name
 from users_to_teams
 where user_id = $1
+order by is_primary desc, team_name
 `
 
-func (q *Queries) GetUserTeam(ctx context.Context, userID string) (string, error) {
-	row := q.db.QueryRow(ctx, getUserTeam, userID)
-	var team_name string
-	err := row.Scan(&team_name)
-	return team_name, err
+func (q *Queries) GetUserTeams(ctx context.Context, userID string) ([]string, error) {
+	rows, err := q.db.Query(ctx, getUserTeams, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []string
+	for rows.Next() {
+		var team_name string
+		if err := rows.Scan(&team_name); err != nil {
+			return nil, err
+		}
+		items = append(items, team_name)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
 }
 
 const getUserWithTeam = `-- name: GetUserWithTeam :one
 select u.user_id, u.user_name, u.is_active, ut.team_name
 from users u
-left join users_to_teams ut using (user_id)
-where u.user_id = $1
+left join users_to_teams ut on ut.user_id = u.user_id and ut.is_primary
+where u.user_id = $1 and u.deleted_at is null
 `
 
 type GetUserWithTeamRow struct {
@@ -355,22 +772,35 @@ func (q *Queries) GetUserWithTeam(ctx context.Context, userID string) (GetUserWi
 }
 
 const getUsersForTeam = `-- name: GetUsersForTeam :many
-select u.user_id, u.user_name, u.is_active
+select u.user_id, u.user_name, u.is_active, ut.is_primary
 from users_to_teams ut
 inner join users u using (user_id)
 where ut.team_name = $1
+order by u.user_id
 `
 
-func (q *Queries) GetUsersForTeam(ctx context.Context, teamName string) ([]User, error) {
+type GetUsersForTeamRow struct {
+	UserID    string
+	UserName  string
+	IsActive  bool
+	IsPrimary bool
+}
+
+func (q *Queries) GetUsersForTeam(ctx context.Context, teamName string) ([]GetUsersForTeamRow, error) {
 	rows, err := q.db.Query(ctx, getUsersForTeam, teamName)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var items []User
+	var items []GetUsersForTeamRow
 	for rows.Next() {
-		var i User
-		if err := rows.Scan(&i.UserID, &i.UserName, &i.IsActive); err != nil {
+		var i GetUsersForTeamRow
+		if err := rows.Scan(
+			&i.UserID,
+			&i.UserName,
+			&i.IsActive,
+			&i.IsPrimary,
+		); err != nil {
 			return nil, err
 		}
 		items = append(items, i)
@@ -381,6 +811,36 @@ func (q *Queries) GetUsersForTeam(ctx context.Context, teamName string) ([]User,
 	return items, nil
 }
 
+const importPR = `-- name: ImportPR :exec
+insert into pull_requests (pull_req_id, pull_req_name, author_id, pull_req_status, created_at, merged_at, version, team_name)
+values ($1, $2, $3, $4, $5, $6, $7, $8)
+`
+
+type ImportPRParams struct {
+	PullReqID     string
+	PullReqName   string
+	AuthorID      pgtype.Text
+	PullReqStatus Prstat
+	CreatedAt     pgtype.Timestamp
+	MergedAt      pgtype.Timestamp
+	Version       int64
+	TeamName      pgtype.Text
+}
+
+func (q *Queries) ImportPR(ctx context.Context, arg ImportPRParams) error {
+	_, err := q.db.Exec(ctx, importPR,
+		arg.PullReqID,
+		arg.PullReqName,
+		arg.AuthorID,
+		arg.PullReqStatus,
+		arg.CreatedAt,
+		arg.MergedAt,
+		arg.Version,
+		arg.TeamName,
+	)
+	return err
+}
+
 const isReviewerAssigned = `-- name: IsReviewerAssigned :one
 select exists(
     select 1 from reviewers_to_pull_requests
@@ -400,11 +860,176 @@ func (q *Queries) IsReviewerAssigned(ctx context.Context, arg IsReviewerAssigned
 	return is_assigned, err
 }
 
+const listPRsWithReviewers = `-- name: ListPRsWithReviewers :many
+select
+    pr.pull_req_id,
+    pr.pull_req_name,
+    pr.author_id,
+    pr.pull_req_status,
+    pr.created_at,
+    pr.merged_at,
+    pr.version,
+    pr.team_name,
+    coalesce(
+        array_agg(rtp.user_id) filter (where rtp.user_id is not null),
+        array[]::text[]
+    ) as assigned_reviewers
+from pull_requests pr
+left join reviewers_to_pull_requests rtp on pr.pull_req_id = rtp.pull_req_id
+group by pr.pull_req_id, pr.pull_req_name, pr.author_id, pr.pull_req_status, pr.created_at, pr.merged_at, pr.version, pr.team_name
+order by pr.created_at, pr.pull_req_id
+`
+
+type ListPRsWithReviewersRow struct {
+	PullReqID         string
+	PullReqName       string
+	AuthorID          pgtype.Text
+	PullReqStatus     Prstat
+	CreatedAt         pgtype.Timestamp
+	MergedAt          pgtype.Timestamp
+	Version           int64
+	TeamName          pgtype.Text
+	AssignedReviewers interface{}
+}
+
+func (q *Queries) ListPRsWithReviewers(ctx context.Context) ([]ListPRsWithReviewersRow, error) {
+	rows, err := q.db.Query(ctx, listPRsWithReviewers)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListPRsWithReviewersRow
+	for rows.Next() {
+		var i ListPRsWithReviewersRow
+		if err := rows.Scan(
+			&i.PullReqID,
+			&i.PullReqName,
+			&i.AuthorID,
+			&i.PullReqStatus,
+			&i.CreatedAt,
+			&i.MergedAt,
+			&i.Version,
+			&i.TeamName,
+			&i.AssignedReviewers,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listTeamParents = `-- name: ListTeamParents :many
+select team_name, parent_team from teams
+where parent_team is not null
+`
+
+type ListTeamParentsRow struct {
+	TeamName   string
+	ParentTeam pgtype.Text
+}
+
+func (q *Queries) ListTeamParents(ctx context.Context) ([]ListTeamParentsRow, error) {
+	rows, err := q.db.Query(ctx, listTeamParents)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListTeamParentsRow
+	for rows.Next() {
+		var i ListTeamParentsRow
+		if err := rows.Scan(&i.TeamName, &i.ParentTeam); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listTeams = `-- name: ListTeams :many
+select team_name from teams
+where deleted_at is null
+order by team_name
+`
+
+func (q *Queries) ListTeams(ctx context.Context) ([]string, error) {
+	rows, err := q.db.Query(ctx, listTeams)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []string
+	for rows.Next() {
+		var team_name string
+		if err := rows.Scan(&team_name); err != nil {
+			return nil, err
+		}
+		items = append(items, team_name)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const lockPR = `-- name: LockPR :one
+select pull_req_status, version from pull_requests
+where pull_req_id = $1
+for update
+`
+
+type LockPRRow struct {
+	PullReqStatus Prstat
+	Version       int64
+}
+
+func (q *Queries) LockPR(ctx context.Context, pullReqID string) (LockPRRow, error) {
+	row := q.db.QueryRow(ctx, lockPR, pullReqID)
+	var i LockPRRow
+	err := row.Scan(&i.PullReqStatus, &i.Version)
+	return i, err
+}
+
+const lockTeamHierarchy = `-- name: LockTeamHierarchy :exec
+select pg_advisory_xact_lock(hashtext('teams.parent_team'))
+`
+
+func (q *Queries) LockTeamHierarchy(ctx context.Context) error {
+	_, err := q.db.Exec(ctx, lockTeamHierarchy)
+	return err
+}
+
+const markPrimaryTeam = `-- name: MarkPrimaryTeam :execrows
+update users_to_teams
+set is_primary = true
+where user_id = $1 and team_name = $2
+`
+
+type MarkPrimaryTeamParams struct {
+	UserID   string
+	TeamName string
+}
+
+func (q *Queries) MarkPrimaryTeam(ctx context.Context, arg MarkPrimaryTeamParams) (int64, error) {
+	result, err := q.db.Exec(ctx, markPrimaryTeam, arg.UserID, arg.TeamName)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
 const mergePR = `-- name: MergePR :one
 update pull_requests
-set pull_req_status = 'merged'::prstat
+set pull_req_status = 'merged'::prstat,
+    version         = version + (pull_req_status = 'open'::prstat)::int
 where pull_req_id = $1
-returning pull_req_id, pull_req_name, author_id, pull_req_status, created_at, merged_at
+returning pull_req_id, pull_req_name, author_id, pull_req_status, created_at, merged_at, version, team_name
 `
 
 func (q *Queries) MergePR(ctx context.Context, pullReqID string) (PullRequest, error) {
@@ -417,10 +1042,97 @@ func (q *Queries) MergePR(ctx context.Context, pullReqID string) (PullRequest, e
 		&i.PullReqStatus,
 		&i.CreatedAt,
 		&i.MergedAt,
+		&i.Version,
+		&i.TeamName,
 	)
 	return i, err
 }
 
+const promotePrimaryTeams = `-- name: PromotePrimaryTeams :exec
+update users_to_teams ut
+set is_primary = true
+where ut.user_id = any($1::text[])
+  and ut.team_name = (select min(o.team_name) from users_to_teams o where o.user_id = ut.user_id)
+  and not exists(select 1 from users_to_teams p where p.user_id = ut.user_id and p.is_primary)
+`
+
+func (q *Queries) PromotePrimaryTeams(ctx context.Context, dollar_1 []string) error {
+	_, err := q.db.Exec(ctx, promotePrimaryTeams, dollar_1)
+	return err
+}
+
+const purgeIdempotencyKeys = `-- name: PurgeIdempotencyKeys :execrows
+delete from idempotency_keys
+where expires_at <= now()
+`
+
+func (q *Queries) PurgeIdempotencyKeys(ctx context.Context) (int64, error) {
+	result, err := q.db.Exec(ctx, purgeIdempotencyKeys)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const purgeRateLimitBuckets = `-- name: PurgeRateLimitBuckets :execrows
+delete from rate_limit_buckets
+where updated_at < now() - make_interval(secs => $1::float8)
+`
+
+func (q *Queries) PurgeRateLimitBuckets(ctx context.Context, idleSeconds float64) (int64, error) {
+	result, err := q.db.Exec(ctx, purgeRateLimitBuckets, idleSeconds)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const purgeTeam = `-- name: PurgeTeam :one
+delete from teams
+where team_name = $1
+returning team_name, parent_team, deleted_at
+`
+
+func (q *Queries) PurgeTeam(ctx context.Context, teamName string) (Team, error) {
+	row := q.db.QueryRow(ctx, purgeTeam, teamName)
+	var i Team
+	err := row.Scan(&i.TeamName, &i.ParentTeam, &i.DeletedAt)
+	return i, err
+}
+
+const purgeUser = `-- name: PurgeUser :one
+delete from users
+where user_id = $1
+returning user_id, user_name, is_active, deleted_at
+`
+
+func (q *Queries) PurgeUser(ctx context.Context, userID string) (User, error) {
+	row := q.db.QueryRow(ctx, purgeUser, userID)
+	var i User
+	err := row.Scan(
+		&i.UserID,
+		&i.UserName,
+		&i.IsActive,
+		&i.DeletedAt,
+	)
+	return i, err
+}
+
+const releaseIdempotencyKey = `-- name: ReleaseIdempotencyKey :exec
+delete from idempotency_keys
+where idem_key = $1 and scope = $2
+`
+
+type ReleaseIdempotencyKeyParams struct {
+	IdemKey string
+	Scope   string
+}
+
+func (q *Queries) ReleaseIdempotencyKey(ctx context.Context, arg ReleaseIdempotencyKeyParams) error {
+	_, err := q.db.Exec(ctx, releaseIdempotencyKey, arg.IdemKey, arg.Scope)
+	return err
+}
+
 const removeReviewer = `-- name: RemoveReviewer :exec
 delete from reviewers_to_pull_requests
 where pull_req_id = $1 and user_id = $2
@@ -436,11 +1148,193 @@ func (q *Queries) RemoveReviewer(ctx context.Context, arg RemoveReviewerParams)
 	return err
 }
 
+const removeTeamMemberships = `-- name: RemoveTeamMemberships :many
+delete from users_to_teams
+where team_name = $1
+returning user_id
+`
+
+func (q *Queries) RemoveTeamMemberships(ctx context.Context, teamName string) ([]string, error) {
+	rows, err := q.db.Query(ctx, removeTeamMemberships, teamName)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []string
+	for rows.Next() {
+		var user_id string
+		if err := rows.Scan(&user_id); err != nil {
+			return nil, err
+		}
+		items = append(items, user_id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const removeUserFromTeam = `-- name: RemoveUserFromTeam :execrows
+delete from users_to_teams
+where user_id = $1 and team_name = $2
+`
+
+type RemoveUserFromTeamParams struct {
+	UserID   string
+	TeamName string
+}
+
+func (q *Queries) RemoveUserFromTeam(ctx context.Context, arg RemoveUserFromTeamParams) (int64, error) {
+	result, err := q.db.Exec(ctx, removeUserFromTeam, arg.UserID, arg.TeamName)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const removeUserMemberships = `-- name: RemoveUserMemberships :exec
+delete from users_to_teams
+where user_id = $1
+`
+
+func (q *Queries) RemoveUserMemberships(ctx context.Context, userID string) error {
+	_, err := q.db.Exec(ctx, removeUserMemberships, userID)
+	return err
+}
+
+const reparentSubTeams = `-- name: ReparentSubTeams :exec
+update teams
+set parent_team = $1
+where parent_team = $2
+`
+
+type ReparentSubTeamsParams struct {
+	NewParent pgtype.Text
+	OldParent pgtype.Text
+}
+
+func (q *Queries) ReparentSubTeams(ctx context.Context, arg ReparentSubTeamsParams) error {
+	_, err := q.db.Exec(ctx, reparentSubTeams, arg.NewParent, arg.OldParent)
+	return err
+}
+
+const revokeAPIKey = `-- name: RevokeAPIKey :one
+update api_keys
+set revoked_at = now()
+where key_id = $1 and revoked_at is null
+returning key_id, key_hash, user_id, role, created_at, revoked_at
+`
+
+func (q *Queries) RevokeAPIKey(ctx context.Context, keyID string) (ApiKey, error) {
+	row := q.db.QueryRow(ctx, revokeAPIKey, keyID)
+	var i ApiKey
+	err := row.Scan(
+		&i.KeyID,
+		&i.KeyHash,
+		&i.UserID,
+		&i.Role,
+		&i.CreatedAt,
+		&i.RevokedAt,
+	)
+	return i, err
+}
+
+const revokeUserAPIKeys = `-- name: RevokeUserAPIKeys :exec
+update api_keys
+set revoked_at = now()
+where user_id = $1 and revoked_at is null
+`
+
+func (q *Queries) RevokeUserAPIKeys(ctx context.Context, userID pgtype.Text) error {
+	_, err := q.db.Exec(ctx, revokeUserAPIKeys, userID)
+	return err
+}
+
+const setTeamParent = `-- name: SetTeamParent :execrows
+update teams
+set parent_team = $2
+where team_name = $1
+`
+
+type SetTeamParentParams struct {
+	TeamName   string
+	ParentTeam pgtype.Text
+}
+
+func (q *Queries) SetTeamParent(ctx context.Context, arg SetTeamParentParams) (int64, error) {
+	result, err := q.db.Exec(ctx, setTeamParent, arg.TeamName, arg.ParentTeam)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const softDeleteTeam = `-- name: SoftDeleteTeam :execrows
+update teams
+set deleted_at = now()
+where team_name = $1 and deleted_at is null
+`
+
+func (q *Queries) SoftDeleteTeam(ctx context.Context, teamName string) (int64, error) {
+	result, err := q.db.Exec(ctx, softDeleteTeam, teamName)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const softDeleteUser = `-- name: SoftDeleteUser :execrows
+update users
+set deleted_at = now(),
+    is_active  = false
+where user_id = $1 and deleted_at is null
+`
+
+func (q *Queries) SoftDeleteUser(ctx context.Context, userID string) (int64, error) {
+	result, err := q.db.Exec(ctx, softDeleteUser, userID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const takeRateLimitToken = `-- name: TakeRateLimitToken :one
+insert into rate_limit_buckets as b (bucket_key, tokens, allowed, updated_at)
+values ($1, $2::float8 - 1, true, now())
+on conflict (bucket_key) do update
+set tokens     = case
+                     when least($2::float8, b.tokens + extract(epoch from now() - b.updated_at) * $3::float8) >= 1
+                         then least($2::float8, b.tokens + extract(epoch from now() - b.updated_at) * $3::float8) - 1
+                     else least($2::float8, b.tokens + extract(epoch from now() - b.updated_at) * $3::float8)
+                 end,
+    allowed    = least($2::float8, b.tokens + extract(epoch from now() - b.updated_at) * $3::float8) >= 1,
+    updated_at = now()
+returning tokens, allowed
+`
+
+type TakeRateLimitTokenParams struct {
+	BucketKey string
+	Burst     float64
+	Rate      float64
+}
+
+type TakeRateLimitTokenRow struct {
+	Tokens  float64
+	Allowed bool
+}
+
+func (q *Queries) TakeRateLimitToken(ctx context.Context, arg TakeRateLimitTokenParams) (TakeRateLimitTokenRow, error) {
+	row := q.db.QueryRow(ctx, takeRateLimitToken, arg.BucketKey, arg.Burst, arg.Rate)
+	var i TakeRateLimitTokenRow
+	err := row.Scan(&i.Tokens, &i.Allowed)
+	return i, err
+}
+
 const userSetIsActive = `-- name: UserSetIsActive :one
 update users
 set is_active = $2
-where user_id = $1
-returning user_id, user_name, is_active
+where user_id = $1 and deleted_at is null
+returning user_id, user_name, is_active, deleted_at
 `
 
 type UserSetIsActiveParams struct {
@@ -451,6 +1345,11 @@ type UserSetIsActiveParams struct {
 func (q *Queries) UserSetIsActive(ctx context.Context, arg UserSetIsActiveParams) (User, error) {
 	row := q.db.QueryRow(ctx, userSetIsActive, arg.UserID, arg.IsActive)
 	var i User
-	err := row.Scan(&i.UserID, &i.UserName, &i.IsActive)
+	err := row.Scan(
+		&i.UserID,
+		&i.UserName,
+		&i.IsActive,
+		&i.DeletedAt,
+	)
 	return i, err
 }
@@ -0,0 +1,66 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"plassstic.tech/trainee/avito/internal/schema"
+)
+
+const (
+	keyIDBytes  = 8
+	secretBytes = 24
+)
+
+type Identity struct {
+	KeyID  string      `json:"key_id,omitempty"`
+	UserID string      `json:"user_id,omitempty"`
+	Role   schema.Role `json:"role"`
+}
+
+type ctxKey struct{}
+
+func WithIdentity(ctx context.Context, id Identity) context.Context {
+	return context.WithValue(ctx, ctxKey{}, id)
+}
+
+func FromContext(ctx context.Context) (Identity, bool) {
+	id, ok := ctx.Value(ctxKey{}).(Identity)
+	return id, ok
+}
+
+func GenerateKey() (keyID, secret string, err error) {
+	buf := make([]byte, keyIDBytes+secretBytes)
+	if _, err = rand.Read(buf); err != nil {
+		return
+	}
+	keyID = hex.EncodeToString(buf[:keyIDBytes])
+	secret = hex.EncodeToString(buf[keyIDBytes:])
+	return
+}
+
+func FormatKey(keyID, secret string) string {
+	return keyID + "." + secret
+}
+
+func ParseKey(key string) (keyID, secret string, err error) {
+	var ok bool
+	if keyID, secret, ok = strings.Cut(key, "."); !ok || keyID == "" || secret == "" {
+		err = fmt.Errorf("malformed api key")
+	}
+	return
+}
+
+func Hash(secret string) []byte {
+	h := sha256.Sum256([]byte(secret))
+	return h[:]
+}
+
+func Verify(secret string, hash []byte) bool {
+	return subtle.ConstantTimeCompare(Hash(secret), hash) == 1
+}
@@ -0,0 +1,66 @@
+package auth
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"net/http"
+	"strings"
+)
+
+const APIKeyHeader = "X-API-Key"
+
+var ErrNoCredentials = errors.New("no credentials provided")
+
+type Authenticator interface {
+	Authenticate(ctx context.Context, r *http.Request) (*Identity, error)
+}
+
+type AuthenticatorFunc func(ctx context.Context, r *http.Request) (*Identity, error)
+
+func (f AuthenticatorFunc) Authenticate(ctx context.Context, r *http.Request) (*Identity, error) {
+	return f(ctx, r)
+}
+
+// Chain asks every authenticator in order and stops at the first one that
+// either accepts or rejects the request; ErrNoCredentials passes it on.
+type Chain []Authenticator
+
+func (ch Chain) Authenticate(ctx context.Context, r *http.Request) (*Identity, error) {
+	for _, a := range ch {
+		id, err := a.Authenticate(ctx, r)
+		if errors.Is(err, ErrNoCredentials) {
+			continue
+		}
+		return id, err
+	}
+	return nil, ErrNoCredentials
+}
+
+func StaticKey(key string, id Identity) Authenticator {
+	return AuthenticatorFunc(func(_ context.Context, r *http.Request) (*Identity, error) {
+		got := r.Header.Get(APIKeyHeader)
+		if got == "" || subtle.ConstantTimeCompare([]byte(got), []byte(key)) != 1 {
+			return nil, ErrNoCredentials
+		}
+		return &id, nil
+	})
+}
+
+func APIKeys(lookup func(ctx context.Context, key string) (*Identity, error)) Authenticator {
+	return AuthenticatorFunc(func(ctx context.Context, r *http.Request) (*Identity, error) {
+		key := r.Header.Get(APIKeyHeader)
+		if key == "" {
+			return nil, ErrNoCredentials
+		}
+		return lookup(ctx, key)
+	})
+}
+
+func BearerToken(r *http.Request) string {
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return ""
+	}
+	return strings.TrimSpace(token)
+}
@@ -0,0 +1,229 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+type KeySet interface {
+	Key(ctx context.Context, kid string) (crypto.PublicKey, error)
+}
+
+type staticKeys map[string]crypto.PublicKey
+
+func StaticKeys(keys map[string]crypto.PublicKey) KeySet {
+	return staticKeys(keys)
+}
+
+// LoadPEMKeys reads public keys from PEM files, the file name without
+// extension becomes the key id.
+func LoadPEMKeys(paths []string) (KeySet, error) {
+	keys := make(staticKeys, len(paths))
+	for _, p := range paths {
+		raw, err := os.ReadFile(p)
+		if err != nil {
+			return nil, err
+		}
+
+		block, _ := pem.Decode(raw)
+		if block == nil {
+			return nil, fmt.Errorf("%s: no PEM block found", p)
+		}
+
+		key, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", p, err)
+		}
+
+		keys[strings.TrimSuffix(filepath.Base(p), filepath.Ext(p))] = key
+	}
+	return keys, nil
+}
+
+func (s staticKeys) Key(_ context.Context, kid string) (crypto.PublicKey, error) {
+	if key, ok := s[kid]; ok {
+		return key, nil
+	}
+	if kid == "" && len(s) == 1 {
+		for _, key := range s {
+			return key, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown key id %q", kid)
+}
+
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use,omitempty"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+}
+
+type jwkSet struct {
+	Keys []jwk `json:"keys"`
+}
+
+type remoteKeys struct {
+	client  *http.Client
+	url     string
+	refresh time.Duration
+
+	mu      sync.Mutex
+	keys    staticKeys
+	fetched time.Time
+}
+
+func NewJWKS(client *http.Client, url string, refresh time.Duration) KeySet {
+	if client == nil {
+		client = &http.Client{Timeout: 5 * time.Second}
+	}
+	return &remoteKeys{client: client, url: url, refresh: refresh}
+}
+
+func (r *remoteKeys) Key(ctx context.Context, kid string) (crypto.PublicKey, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stale := time.Since(r.fetched) > r.refresh
+	if r.keys != nil && !stale {
+		if key, err := r.keys.Key(ctx, kid); err == nil {
+			return key, nil
+		}
+		// an unknown kid usually means the issuer rotated keys, but do not
+		// let forged tokens hammer the JWKS endpoint
+		if time.Since(r.fetched) < time.Minute {
+			return nil, fmt.Errorf("unknown key id %q", kid)
+		}
+	}
+
+	if err := r.fetch(ctx); err != nil {
+		return nil, err
+	}
+	return r.keys.Key(ctx, kid)
+}
+
+func (r *remoteKeys) fetch(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, r.url, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("fetch jwks: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("fetch jwks: %s", resp.Status)
+	}
+
+	keys, err := parseJWKS(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	r.keys = keys
+	r.fetched = time.Now()
+	return nil
+}
+
+func ParseJWKS(body io.Reader) (KeySet, error) {
+	return parseJWKS(body)
+}
+
+func parseJWKS(body io.Reader) (staticKeys, error) {
+	var set jwkSet
+	if err := json.NewDecoder(body).Decode(&set); err != nil {
+		return nil, fmt.Errorf("decode jwks: %w", err)
+	}
+
+	keys := make(staticKeys, len(set.Keys))
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		key, err := k.publicKey()
+		if err != nil {
+			return nil, fmt.Errorf("jwk %q: %w", k.Kid, err)
+		}
+		keys[k.Kid] = key
+	}
+	return keys, nil
+}
+
+func (k jwk) publicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := b64int(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := b64int(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := b64int(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := b64int(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	case "OKP":
+		if k.Crv != "Ed25519" {
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil {
+			return nil, err
+		}
+		return ed25519.PublicKey(x), nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+	}
+}
+
+func b64int(s string) (*big.Int, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(raw), nil
+}
@@ -0,0 +1,166 @@
+package auth
+
+import (
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"plassstic.tech/trainee/avito/internal/schema"
+)
+
+func rsaJWK(kid string, key *rsa.PublicKey) jwk {
+	return jwk{
+		Kty: "RSA",
+		Kid: kid,
+		Use: "sig",
+		N:   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+		E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+	}
+}
+
+func ecJWK(kid string, key *ecdsa.PublicKey) jwk {
+	raw, _ := key.Bytes()
+	size := (len(raw) - 1) / 2
+	return jwk{
+		Kty: "EC",
+		Kid: kid,
+		Crv: key.Curve.Params().Name,
+		X:   base64.RawURLEncoding.EncodeToString(raw[1 : 1+size]),
+		Y:   base64.RawURLEncoding.EncodeToString(raw[1+size:]),
+	}
+}
+
+// jwksServer serves whatever set is current and counts the fetches.
+type jwksServer struct {
+	*httptest.Server
+
+	mu      sync.Mutex
+	set     jwkSet
+	status  int
+	fetches atomic.Int32
+}
+
+func newJWKSServer(t *testing.T, keys ...jwk) *jwksServer {
+	s := &jwksServer{set: jwkSet{Keys: keys}, status: http.StatusOK}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		s.fetches.Add(1)
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		w.WriteHeader(s.status)
+		_ = json.NewEncoder(w).Encode(s.set)
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *jwksServer) rotate(status int, keys ...jwk) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.status, s.set = status, jwkSet{Keys: keys}
+}
+
+func TestJWKS(t *testing.T) {
+	rsaPriv, ecPriv := rsaKey(t), ecKey(t)
+	srv := newJWKSServer(t, rsaJWK("rsa-1", &rsaPriv.PublicKey), ecJWK("ec-1", &ecPriv.PublicKey))
+
+	a := JWT(JWTConfig{
+		Keys:        NewJWKS(srv.Client(), srv.URL, time.Hour),
+		UserClaim:   "sub",
+		DefaultRole: schema.RoleUser,
+	})
+
+	for _, token := range []string{
+		sign(t, jwt.SigningMethodRS256, rsaPriv, "rsa-1", claims(nil)),
+		sign(t, jwt.SigningMethodES256, ecPriv, "ec-1", claims(nil)),
+	} {
+		if _, err := authenticate(a, token); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := srv.fetches.Load(); n != 1 {
+		t.Fatalf("expected the set to be fetched once, got %d", n)
+	}
+
+	// an unknown kid right after a fetch is refused without asking again
+	if _, err := authenticate(a, sign(t, jwt.SigningMethodRS256, rsaPriv, "rsa-2", claims(nil))); err == nil {
+		t.Fatal("expected unknown kid to fail")
+	}
+	if n := srv.fetches.Load(); n != 1 {
+		t.Fatalf("unknown kid refetched the set, %d fetches", n)
+	}
+}
+
+func TestJWKSRotation(t *testing.T) {
+	oldPriv, newPriv := rsaKey(t), rsaKey(t)
+	srv := newJWKSServer(t, rsaJWK("k1", &oldPriv.PublicKey))
+
+	refresh := 50 * time.Millisecond
+	a := JWT(JWTConfig{
+		Keys:        NewJWKS(srv.Client(), srv.URL, refresh),
+		UserClaim:   "sub",
+		DefaultRole: schema.RoleUser,
+	})
+	oldToken := sign(t, jwt.SigningMethodRS256, oldPriv, "k1", claims(nil))
+	newToken := sign(t, jwt.SigningMethodRS256, newPriv, "k2", claims(nil))
+
+	if _, err := authenticate(a, oldToken); err != nil {
+		t.Fatal(err)
+	}
+
+	srv.rotate(http.StatusOK, rsaJWK("k2", &newPriv.PublicKey))
+	time.Sleep(2 * refresh)
+
+	if _, err := authenticate(a, newToken); err != nil {
+		t.Fatalf("rotated key was not picked up: %v", err)
+	}
+	if _, err := authenticate(a, oldToken); err == nil {
+		t.Fatal("retired key is still accepted")
+	}
+	if n := srv.fetches.Load(); n != 2 {
+		t.Fatalf("expected 2 fetches, got %d", n)
+	}
+
+	// a failing endpoint is an error rather than an empty key set
+	srv.rotate(http.StatusInternalServerError)
+	time.Sleep(2 * refresh)
+	if _, err := authenticate(a, newToken); err == nil || !strings.Contains(err.Error(), "fetch jwks") {
+		t.Fatalf("expected a fetch error, got %v", err)
+	}
+}
+
+func TestParseJWKS(t *testing.T) {
+	priv := rsaKey(t)
+	set := jwkSet{Keys: []jwk{
+		rsaJWK("sig", &priv.PublicKey),
+		{Kty: "RSA", Kid: "enc", Use: "enc"},
+	}}
+	body, err := json.Marshal(set)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keys, err := ParseJWKS(strings.NewReader(string(body)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := keys.Key(t.Context(), "sig"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := keys.Key(t.Context(), "enc"); err == nil {
+		t.Fatal("encryption key was accepted for signatures")
+	}
+
+	if _, err := ParseJWKS(strings.NewReader(`{"keys":[{"kty":"EC","kid":"x","crv":"P-192"}]}`)); err == nil {
+		t.Fatal("expected unsupported curve to fail")
+	}
+}
@@ -0,0 +1,98 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"plassstic.tech/trainee/avito/internal/schema"
+)
+
+var signingMethods = []string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512", "EdDSA"}
+
+type JWTConfig struct {
+	Keys        KeySet
+	Issuer      string
+	Audience    string
+	UserClaim   string
+	RoleClaim   string
+	DefaultRole schema.Role
+	RoleMap     map[string]schema.Role
+	Leeway      time.Duration
+}
+
+type jwtAuthenticator struct {
+	cfg    JWTConfig
+	parser *jwt.Parser
+}
+
+func JWT(cfg JWTConfig) Authenticator {
+	opts := []jwt.ParserOption{
+		jwt.WithValidMethods(signingMethods),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(cfg.Leeway),
+	}
+	if cfg.Issuer != "" {
+		opts = append(opts, jwt.WithIssuer(cfg.Issuer))
+	}
+	if cfg.Audience != "" {
+		opts = append(opts, jwt.WithAudience(cfg.Audience))
+	}
+
+	return &jwtAuthenticator{cfg: cfg, parser: jwt.NewParser(opts...)}
+}
+
+func (a *jwtAuthenticator) Authenticate(ctx context.Context, r *http.Request) (*Identity, error) {
+	raw := BearerToken(r)
+	if raw == "" {
+		return nil, ErrNoCredentials
+	}
+
+	claims := jwt.MapClaims{}
+	_, err := a.parser.ParseWithClaims(raw, claims, func(t *jwt.Token) (any, error) {
+		kid, _ := t.Header["kid"].(string)
+		return a.cfg.Keys.Key(ctx, kid)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	userID, _ := claims[a.cfg.UserClaim].(string)
+	if userID == "" {
+		return nil, fmt.Errorf("token has no %q claim", a.cfg.UserClaim)
+	}
+
+	return &Identity{
+		UserID: userID,
+		Role:   a.role(claims[a.cfg.RoleClaim]),
+	}, nil
+}
+
+// role picks the highest known role out of a string or a list claim.
+func (a *jwtAuthenticator) role(claim any) schema.Role {
+	var values []string
+	switch v := claim.(type) {
+	case string:
+		values = []string{v}
+	case []any:
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				values = append(values, s)
+			}
+		}
+	}
+
+	best := a.cfg.DefaultRole
+	for _, v := range values {
+		role := schema.Role(v)
+		if mapped, ok := a.cfg.RoleMap[v]; ok {
+			role = mapped
+		}
+		if role.Valid() && role.AtLeast(best) {
+			best = role
+		}
+	}
+	return best
+}
@@ -0,0 +1,191 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"errors"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"plassstic.tech/trainee/avito/internal/schema"
+)
+
+func rsaKey(t *testing.T) *rsa.PrivateKey {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func ecKey(t *testing.T) *ecdsa.PrivateKey {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func sign(t *testing.T, method jwt.SigningMethod, key crypto.Signer, kid string, claims jwt.MapClaims) string {
+	t.Helper()
+	token := jwt.NewWithClaims(method, claims)
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+	raw, err := token.SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return raw
+}
+
+func claims(extra jwt.MapClaims) jwt.MapClaims {
+	c := jwt.MapClaims{
+		"sub": "u1",
+		"iss": "https://issuer.example",
+		"aud": "avito",
+		"exp": time.Now().Add(time.Hour).Unix(),
+	}
+	for k, v := range extra {
+		if v == nil {
+			delete(c, k)
+			continue
+		}
+		c[k] = v
+	}
+	return c
+}
+
+func authenticate(a Authenticator, token string) (*Identity, error) {
+	r := httptest.NewRequest("GET", "/", nil)
+	if token != "" {
+		r.Header.Set("Authorization", "Bearer "+token)
+	}
+	return a.Authenticate(r.Context(), r)
+}
+
+func TestJWT(t *testing.T) {
+	rsaPriv, ecPriv, other := rsaKey(t), ecKey(t), rsaKey(t)
+
+	a := JWT(JWTConfig{
+		Keys:        StaticKeys(map[string]crypto.PublicKey{"rsa": rsaPriv.Public(), "ec": ecPriv.Public()}),
+		Issuer:      "https://issuer.example",
+		Audience:    "avito",
+		UserClaim:   "sub",
+		RoleClaim:   "roles",
+		DefaultRole: schema.RoleUser,
+		RoleMap:     map[string]schema.Role{"maintainer": schema.RoleTeamLead},
+		Leeway:      30 * time.Second,
+	})
+
+	tests := []struct {
+		name  string
+		token string
+		role  schema.Role
+		fail  bool
+	}{
+		{name: "rsa", token: sign(t, jwt.SigningMethodRS256, rsaPriv, "rsa", claims(nil)), role: schema.RoleUser},
+		{name: "ec", token: sign(t, jwt.SigningMethodES256, ecPriv, "ec", claims(nil)), role: schema.RoleUser},
+		{
+			name:  "role string",
+			token: sign(t, jwt.SigningMethodRS256, rsaPriv, "rsa", claims(jwt.MapClaims{"roles": "admin"})),
+			role:  schema.RoleAdmin,
+		},
+		{
+			name:  "mapped role list",
+			token: sign(t, jwt.SigningMethodRS256, rsaPriv, "rsa", claims(jwt.MapClaims{"roles": []string{"viewer", "maintainer"}})),
+			role:  schema.RoleTeamLead,
+		},
+		{
+			name:  "highest role wins",
+			token: sign(t, jwt.SigningMethodRS256, rsaPriv, "rsa", claims(jwt.MapClaims{"roles": []string{"admin", "maintainer"}})),
+			role:  schema.RoleAdmin,
+		},
+		{
+			name:  "expired within leeway",
+			token: sign(t, jwt.SigningMethodRS256, rsaPriv, "rsa", claims(jwt.MapClaims{"exp": time.Now().Add(-10 * time.Second).Unix()})),
+			role:  schema.RoleUser,
+		},
+		{
+			name:  "expired",
+			token: sign(t, jwt.SigningMethodRS256, rsaPriv, "rsa", claims(jwt.MapClaims{"exp": time.Now().Add(-time.Minute).Unix()})),
+			fail:  true,
+		},
+		{name: "no expiry", token: sign(t, jwt.SigningMethodRS256, rsaPriv, "rsa", claims(jwt.MapClaims{"exp": nil})), fail: true},
+		{
+			name:  "not yet valid",
+			token: sign(t, jwt.SigningMethodRS256, rsaPriv, "rsa", claims(jwt.MapClaims{"nbf": time.Now().Add(time.Minute).Unix()})),
+			fail:  true,
+		},
+		{
+			name:  "wrong issuer",
+			token: sign(t, jwt.SigningMethodRS256, rsaPriv, "rsa", claims(jwt.MapClaims{"iss": "https://evil.example"})),
+			fail:  true,
+		},
+		{
+			name:  "wrong audience",
+			token: sign(t, jwt.SigningMethodRS256, rsaPriv, "rsa", claims(jwt.MapClaims{"aud": "other"})),
+			fail:  true,
+		},
+		{name: "unknown kid", token: sign(t, jwt.SigningMethodRS256, rsaPriv, "gone", claims(nil)), fail: true},
+		{name: "wrong key", token: sign(t, jwt.SigningMethodRS256, other, "rsa", claims(nil)), fail: true},
+		{name: "key of another type", token: sign(t, jwt.SigningMethodES256, ecPriv, "rsa", claims(nil)), fail: true},
+		{name: "no subject", token: sign(t, jwt.SigningMethodRS256, rsaPriv, "rsa", claims(jwt.MapClaims{"sub": nil})), fail: true},
+		{name: "hmac", token: hmacToken(t), fail: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			id, err := authenticate(a, tc.token)
+			if tc.fail {
+				if err == nil {
+					t.Fatalf("expected an error, got %+v", id)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if id.UserID != "u1" || id.Role != tc.role {
+				t.Fatalf("unexpected identity %+v", id)
+			}
+		})
+	}
+}
+
+// hmacToken is signed with a shared secret, which must never be accepted
+// whatever the key set holds.
+func hmacToken(t *testing.T) string {
+	t.Helper()
+	raw, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims(nil)).SignedString([]byte("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return raw
+}
+
+func TestJWTNoToken(t *testing.T) {
+	a := JWT(JWTConfig{Keys: StaticKeys(nil), UserClaim: "sub"})
+	if _, err := authenticate(a, ""); !errors.Is(err, ErrNoCredentials) {
+		t.Fatalf("expected ErrNoCredentials, got %v", err)
+	}
+}
+
+func TestJWTSingleKeyWithoutKid(t *testing.T) {
+	priv := rsaKey(t)
+	a := JWT(JWTConfig{
+		Keys:        StaticKeys(map[string]crypto.PublicKey{"only": priv.Public()}),
+		UserClaim:   "sub",
+		DefaultRole: schema.RoleUser,
+	})
+	if _, err := authenticate(a, sign(t, jwt.SigningMethodRS256, priv, "", claims(nil))); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,67 @@
+package events
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"time"
+
+	"plassstic.tech/trainee/avito/internal/schema"
+)
+
+const (
+	SpecVersion     = "1.0"
+	DataContentType = "application/json"
+
+	typePrefix = "tech.plassstic."
+)
+
+type Type string
+
+const (
+	PRCreated    Type = typePrefix + "pr.created"
+	PRMerged     Type = typePrefix + "pr.merged"
+	PRReassigned Type = typePrefix + "pr.reassigned"
+)
+
+var Types = []Type{PRCreated, PRMerged, PRReassigned}
+
+// Event is a CloudEvents 1.0 envelope, see https://github.com/cloudevents/spec/blob/v1.0.2/cloudevents/spec.md
+type Event struct {
+	SpecVersion     string          `json:"specversion"`
+	ID              string          `json:"id"`
+	Source          string          `json:"source"`
+	Type            Type            `json:"type"`
+	Subject         string          `json:"subject,omitempty"`
+	Time            time.Time       `json:"time"`
+	DataContentType string          `json:"datacontenttype,omitempty"`
+	DataSchema      string          `json:"dataschema,omitempty"`
+	Data            json.RawMessage `json:"data,omitempty"`
+}
+
+func New(source string, typ Type, subject string, data any) (Event, error) {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return Event{}, err
+	}
+
+	return Event{
+		SpecVersion:     SpecVersion,
+		ID:              rand.Text(),
+		Source:          source,
+		Type:            typ,
+		Subject:         subject,
+		Time:            time.Now().UTC(),
+		DataContentType: DataContentType,
+		DataSchema:      SchemaURI(typ),
+		Data:            raw,
+	}, nil
+}
+
+func ForPR(source string, typ Type, pr schema.PullRequest) (Event, error) {
+	return New(source, typ, pr.PRId, pr)
+}
+
+func (e Event) PullRequest() (pr schema.PullRequest, err error) {
+	err = json.Unmarshal(e.Data, &pr)
+	return
+}
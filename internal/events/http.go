@@ -0,0 +1,111 @@
+package events
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+)
+
+type Mode string
+
+const (
+	Structured Mode = "structured"
+	Binary     Mode = "binary"
+
+	StructuredContentType = "application/cloudevents+json"
+
+	headerPrefix = "Ce-"
+)
+
+func NewRequest(ctx context.Context, url string, mode Mode, e Event) (*http.Request, error) {
+	switch mode {
+	case Structured:
+		body, err := json.Marshal(e)
+		if err != nil {
+			return nil, err
+		}
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", StructuredContentType+"; charset=utf-8")
+		return req, nil
+	case Binary:
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(e.Data))
+		if err != nil {
+			return nil, err
+		}
+		WriteBinaryHeaders(req.Header, e)
+		return req, nil
+	default:
+		return nil, fmt.Errorf("unknown cloudevents mode %q", mode)
+	}
+}
+
+func WriteBinaryHeaders(h http.Header, e Event) {
+	h.Set(headerPrefix+"Specversion", e.SpecVersion)
+	h.Set(headerPrefix+"Id", e.ID)
+	h.Set(headerPrefix+"Source", e.Source)
+	h.Set(headerPrefix+"Type", string(e.Type))
+	h.Set(headerPrefix+"Time", e.Time.Format(time.RFC3339Nano))
+	if e.Subject != "" {
+		h.Set(headerPrefix+"Subject", e.Subject)
+	}
+	if e.DataSchema != "" {
+		h.Set(headerPrefix+"Dataschema", e.DataSchema)
+	}
+	if e.DataContentType != "" {
+		h.Set("Content-Type", e.DataContentType)
+	}
+}
+
+func FromRequest(r *http.Request) (e Event, err error) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return
+	}
+
+	mt, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mt == StructuredContentType {
+		err = json.Unmarshal(body, &e)
+		return
+	}
+
+	if r.Header.Get(headerPrefix+"Specversion") == "" {
+		err = fmt.Errorf("request is not a cloudevent")
+		return
+	}
+
+	e = Event{
+		SpecVersion:     r.Header.Get(headerPrefix + "Specversion"),
+		ID:              r.Header.Get(headerPrefix + "Id"),
+		Source:          r.Header.Get(headerPrefix + "Source"),
+		Type:            Type(r.Header.Get(headerPrefix + "Type")),
+		Subject:         r.Header.Get(headerPrefix + "Subject"),
+		DataSchema:      r.Header.Get(headerPrefix + "Dataschema"),
+		DataContentType: r.Header.Get("Content-Type"),
+		Data:            body,
+	}
+	if t := r.Header.Get(headerPrefix + "Time"); t != "" {
+		e.Time, err = time.Parse(time.RFC3339Nano, t)
+	}
+	return
+}
+
+func (m Mode) Valid() bool {
+	return m == Structured || m == Binary
+}
+
+func ParseMode(s string) (Mode, error) {
+	m := Mode(strings.ToLower(s))
+	if !m.Valid() {
+		return "", fmt.Errorf("unknown cloudevents mode %q", s)
+	}
+	return m, nil
+}
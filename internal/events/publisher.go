@@ -0,0 +1,82 @@
+package events
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/rs/zerolog"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+	"plassstic.tech/trainee/avito/internal/tracing"
+)
+
+type Publisher interface {
+	Publish(ctx context.Context, e Event) error
+}
+
+type nop struct{}
+
+func (nop) Publish(context.Context, Event) error {
+	return nil
+}
+
+func Nop() Publisher {
+	return nop{}
+}
+
+type webhook struct {
+	client *http.Client
+	url    string
+	mode   Mode
+}
+
+func NewWebhook(client *http.Client, url string, mode Mode) Publisher {
+	if client == nil {
+		client = &http.Client{Timeout: 5 * time.Second}
+	}
+	return &webhook{client: client, url: url, mode: mode}
+}
+
+func (w *webhook) Publish(ctx context.Context, e Event) (err error) {
+	ctx, span := tracing.Start(ctx, "webhook "+string(e.Type),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("event.id", e.ID),
+			attribute.String("url.full", w.url),
+		),
+	)
+	defer func() {
+		if err != nil {
+			tracing.Fail(span, err)
+		}
+		span.End()
+	}()
+
+	req, err := NewRequest(ctx, w.url, w.mode, e)
+	if err != nil {
+		return err
+	}
+	tracing.Inject(ctx, propagation.HeaderCarrier(req.Header))
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return err
+	}
+	_ = resp.Body.Close()
+	span.SetAttributes(attribute.Int("http.response.status_code", resp.StatusCode))
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook %s responded with %s", w.url, resp.Status)
+	}
+
+	zerolog.Ctx(ctx).Debug().
+		Str("id", e.ID).
+		Str("type", string(e.Type)).
+		Str("url", w.url).
+		Msg("event delivered")
+
+	return nil
+}
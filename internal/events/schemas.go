@@ -0,0 +1,17 @@
+package events
+
+import (
+	"embed"
+	"fmt"
+)
+
+//go:embed schemas/*.json
+var schemas embed.FS
+
+func SchemaURI(typ Type) string {
+	return fmt.Sprintf("urn:plassstic:schema:%s", typ)
+}
+
+func Schema(typ Type) ([]byte, error) {
+	return schemas.ReadFile(fmt.Sprintf("schemas/%s.json", typ))
+}
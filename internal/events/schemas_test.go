@@ -0,0 +1,66 @@
+package events
+
+import (
+	"encoding/json"
+	"maps"
+	"slices"
+	"testing"
+
+	"plassstic.tech/trainee/avito/gensql"
+	"plassstic.tech/trainee/avito/internal/schema"
+)
+
+// TestSchemasMatchPayload catches a PullRequest field that was added or
+// renamed without updating the published event schemas.
+func TestSchemasMatchPayload(t *testing.T) {
+	pr := schema.PullRequest{
+		PullRequestShort:  schema.PullRequestShort{PRId: "pr-1", Name: "Add search", AuthorId: "u1", Status: gensql.PrstatOpen},
+		AssignedReviewers: []string{"u2"},
+		TeamName:          "backend",
+		Version:           1,
+	}
+
+	for _, typ := range Types {
+		t.Run(string(typ), func(t *testing.T) {
+			e, err := ForPR("/test", typ, pr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var data map[string]any
+			if err = json.Unmarshal(e.Data, &data); err != nil {
+				t.Fatal(err)
+			}
+
+			raw, err := Schema(typ)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var doc struct {
+				ID   string `json:"$id"`
+				Defs struct {
+					PullRequest struct {
+						Required   []string       `json:"required"`
+						Properties map[string]any `json:"properties"`
+					} `json:"PullRequest"`
+				} `json:"$defs"`
+			}
+			if err = json.Unmarshal(raw, &doc); err != nil {
+				t.Fatal(err)
+			}
+
+			if doc.ID != SchemaURI(typ) {
+				t.Fatalf("schema $id %q, expected %q", doc.ID, SchemaURI(typ))
+			}
+			fields := slices.Sorted(maps.Keys(data))
+			declared := slices.Sorted(maps.Keys(doc.Defs.PullRequest.Properties))
+			if !slices.Equal(fields, declared) {
+				t.Fatalf("payload has %v, schema declares %v", fields, declared)
+			}
+			for _, name := range doc.Defs.PullRequest.Required {
+				if _, ok := data[name]; !ok {
+					t.Fatalf("required %q is missing from the payload", name)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,155 @@
+package health
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const (
+	StatusOK       = "ok"
+	StatusFailing  = "failing"
+	StatusDraining = "draining"
+
+	checkTimeout = 2 * time.Second
+)
+
+type Check func(ctx context.Context) error
+
+type CheckResult struct {
+	Status   string     `json:"status"`
+	Latency  string     `json:"latency,omitempty"`
+	LastRun  *time.Time `json:"last_run,omitempty"`
+	Error    string     `json:"error,omitempty"`
+	Critical bool       `json:"critical"`
+}
+
+type Report struct {
+	Status string                 `json:"status"`
+	Checks map[string]CheckResult `json:"checks"`
+}
+
+func (r Report) Ready() bool {
+	return r.Status == StatusOK
+}
+
+type check struct {
+	run      Check
+	critical bool
+}
+
+type Checker struct {
+	mu       sync.RWMutex
+	checks   map[string]check
+	workers  map[string]*Worker
+	draining atomic.Bool
+}
+
+func New() *Checker {
+	return &Checker{
+		checks:  map[string]check{},
+		workers: map[string]*Worker{},
+	}
+}
+
+// Add registers a check; a failing critical check makes the service not ready.
+func (h *Checker) Add(name string, critical bool, run Check) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.checks[name] = check{run: run, critical: critical}
+}
+
+// Worker registers a background worker whose state is reported alongside checks.
+func (h *Checker) Worker(name string) *Worker {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	w := &Worker{}
+	h.workers[name] = w
+	return w
+}
+
+// Drain marks the service as shutting down so load balancers stop routing to it.
+func (h *Checker) Drain() {
+	h.draining.Store(true)
+}
+
+func (h *Checker) Draining() bool {
+	return h.draining.Load()
+}
+
+func (h *Checker) Run(ctx context.Context) Report {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	report := Report{Status: StatusOK, Checks: make(map[string]CheckResult, len(h.checks)+len(h.workers))}
+
+	var (
+		mu sync.Mutex
+		wg sync.WaitGroup
+	)
+	for name, c := range h.checks {
+		wg.Go(func() {
+			res := runCheck(ctx, c)
+			mu.Lock()
+			defer mu.Unlock()
+			report.Checks[name] = res
+			if res.Status != StatusOK && c.critical {
+				report.Status = StatusFailing
+			}
+		})
+	}
+	wg.Wait()
+
+	for name, w := range h.workers {
+		report.Checks["worker:"+name] = w.result()
+	}
+
+	if h.Draining() {
+		report.Status = StatusDraining
+	}
+	return report
+}
+
+func runCheck(ctx context.Context, c check) CheckResult {
+	ctx, cancel := context.WithTimeout(ctx, checkTimeout)
+	defer cancel()
+
+	start := time.Now()
+	err := c.run(ctx)
+	res := CheckResult{Status: StatusOK, Latency: time.Since(start).String(), Critical: c.critical}
+	if err != nil {
+		res.Status = StatusFailing
+		res.Error = err.Error()
+	}
+	return res
+}
+
+func Ping(pool *pgxpool.Pool) Check {
+	return func(ctx context.Context) error {
+		return pool.Ping(ctx)
+	}
+}
+
+func PingSQL(db *sql.DB) Check {
+	return db.PingContext
+}
+
+// MigrationVersion fails unless the database is migrated to at least expected.
+func MigrationVersion(pool *pgxpool.Pool, expected int64) Check {
+	return func(ctx context.Context) error {
+		var current int64
+		err := pool.QueryRow(ctx, "select coalesce(max(version_id), 0) from goose_db_version").Scan(&current)
+		if err != nil {
+			return err
+		}
+		if current < expected {
+			return fmt.Errorf("database is at version %d, expected %d", current, expected)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,53 @@
+package health
+
+import (
+	"sync"
+	"time"
+)
+
+// Worker tracks the state of a background loop for the readiness report.
+type Worker struct {
+	mu      sync.Mutex
+	running bool
+	lastRun time.Time
+	lastErr error
+}
+
+func (w *Worker) Start() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.running = true
+}
+
+func (w *Worker) Stop() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.running = false
+}
+
+// Done records the outcome of one iteration.
+func (w *Worker) Done(err error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.lastRun = time.Now()
+	w.lastErr = err
+}
+
+func (w *Worker) result() CheckResult {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	res := CheckResult{Status: StatusOK}
+	if !w.lastRun.IsZero() {
+		last := w.lastRun
+		res.LastRun = &last
+	}
+	switch {
+	case !w.running:
+		res.Status = "stopped"
+	case w.lastErr != nil:
+		res.Status = StatusFailing
+		res.Error = w.lastErr.Error()
+	}
+	return res
+}
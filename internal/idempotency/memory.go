@@ -0,0 +1,81 @@
+package idempotency
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+)
+
+var _ Store = (*memory)(nil)
+
+type memKey struct {
+	key, scope string
+}
+
+type memRecord struct {
+	Record
+	expires time.Time
+}
+
+type memory struct {
+	mu      sync.Mutex
+	records map[memKey]*memRecord
+}
+
+// NewMemory keeps keys in process, for a single replica or demo mode.
+func NewMemory() Store {
+	return &memory{records: make(map[memKey]*memRecord)}
+}
+
+func (m *memory) Claim(_ context.Context, key, scope string, hash []byte, ttl time.Duration) (bool, *Record, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	k := memKey{key, scope}
+	if r, ok := m.records[k]; ok && time.Now().Before(r.expires) {
+		rec := r.Record
+		return false, &rec, nil
+	}
+
+	m.records[k] = &memRecord{
+		Record:  Record{RequestHash: hash},
+		expires: time.Now().Add(ttl),
+	}
+	return true, nil, nil
+}
+
+func (m *memory) Complete(_ context.Context, key, scope string, status int, body []byte, headers http.Header) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if r, ok := m.records[memKey{key, scope}]; ok {
+		r.StatusCode = status
+		r.Body = body
+		r.Headers = headers
+		r.Done = true
+	}
+	return nil
+}
+
+func (m *memory) Release(_ context.Context, key, scope string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.records, memKey{key, scope})
+	return nil
+}
+
+func (m *memory) Purge(_ context.Context) (n int64, _ error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	for k, r := range m.records {
+		if !now.Before(r.expires) {
+			delete(m.records, k)
+			n++
+		}
+	}
+	return n, nil
+}
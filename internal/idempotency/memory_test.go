@@ -0,0 +1,60 @@
+package idempotency
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestMemory(t *testing.T) {
+	ctx := t.Context()
+	store := NewMemory()
+	hash := []byte("hash")
+
+	claimed, _, err := store.Claim(ctx, "k", "scope", hash, time.Hour)
+	if err != nil || !claimed {
+		t.Fatalf("expected the first claim to win, got %v %v", claimed, err)
+	}
+
+	claimed, rec, err := store.Claim(ctx, "k", "scope", hash, time.Hour)
+	if err != nil || claimed || rec.Done {
+		t.Fatalf("expected a pending record, got %v %+v %v", claimed, rec, err)
+	}
+
+	if claimed, _, _ = store.Claim(ctx, "k", "other scope", hash, time.Hour); !claimed {
+		t.Fatal("expected keys to be separate per scope")
+	}
+
+	headers := http.Header{"Etag": {`"1"`}}
+	if err = store.Complete(ctx, "k", "scope", http.StatusCreated, []byte("body"), headers); err != nil {
+		t.Fatal(err)
+	}
+	_, rec, _ = store.Claim(ctx, "k", "scope", hash, time.Hour)
+	if !rec.Done || rec.StatusCode != http.StatusCreated || string(rec.Body) != "body" || rec.Headers.Get("ETag") != `"1"` {
+		t.Fatalf("expected the stored response, got %+v", rec)
+	}
+
+	if err = store.Release(ctx, "k", "scope"); err != nil {
+		t.Fatal(err)
+	}
+	if claimed, _, _ = store.Claim(ctx, "k", "scope", hash, time.Hour); !claimed {
+		t.Fatal("expected a released key to be claimable")
+	}
+}
+
+func TestMemoryExpiry(t *testing.T) {
+	ctx := t.Context()
+	store := NewMemory()
+
+	if claimed, _, _ := store.Claim(ctx, "k", "scope", nil, -time.Second); !claimed {
+		t.Fatal("expected the first claim to win")
+	}
+	if claimed, _, _ := store.Claim(ctx, "k", "scope", nil, -time.Second); !claimed {
+		t.Fatal("expected an expired key to be claimable")
+	}
+
+	n, err := store.Purge(ctx)
+	if err != nil || n != 1 {
+		t.Fatalf("expected one expired key purged, got %d %v", n, err)
+	}
+}
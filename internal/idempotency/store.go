@@ -0,0 +1,129 @@
+package idempotency
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/rs/zerolog/log"
+	"plassstic.tech/trainee/avito/gensql"
+	"plassstic.tech/trainee/avito/internal/health"
+)
+
+const Header = "Idempotency-Key"
+
+type Record struct {
+	RequestHash []byte
+	StatusCode  int
+	Body        []byte
+	Headers     http.Header
+	Done        bool
+}
+
+type Store interface {
+	// Claim reserves key for the caller, if somebody already holds it the
+	// stored record is returned instead.
+	Claim(ctx context.Context, key, scope string, hash []byte, ttl time.Duration) (claimed bool, rec *Record, err error)
+	Complete(ctx context.Context, key, scope string, status int, body []byte, headers http.Header) error
+	Release(ctx context.Context, key, scope string) error
+	Purge(ctx context.Context) (int64, error)
+}
+
+var _ Store = pgStore{}
+
+type pgStore struct {
+	qs *gensql.Queries
+}
+
+func NewPgStore(pool *pgxpool.Pool) Store {
+	return pgStore{qs: gensql.New(pool)}
+}
+
+func (s pgStore) Claim(ctx context.Context, key, scope string, hash []byte, ttl time.Duration) (claimed bool, rec *Record, err error) {
+	if err = s.qs.DropExpiredIdempotencyKey(ctx, gensql.DropExpiredIdempotencyKeyParams{
+		IdemKey: key,
+		Scope:   scope,
+	}); err != nil {
+		return
+	}
+
+	var n int64
+	if n, err = s.qs.ClaimIdempotencyKey(ctx, gensql.ClaimIdempotencyKeyParams{
+		IdemKey:     key,
+		Scope:       scope,
+		RequestHash: hash,
+		TtlSeconds:  ttl.Seconds(),
+	}); err != nil || n == 1 {
+		claimed = n == 1
+		return
+	}
+
+	row, err := s.qs.GetIdempotencyKey(ctx, gensql.GetIdempotencyKeyParams{
+		IdemKey: key,
+		Scope:   scope,
+	})
+	if err != nil {
+		return
+	}
+
+	rec = &Record{
+		RequestHash: row.RequestHash,
+		StatusCode:  int(row.StatusCode.Int32),
+		Body:        row.ResponseBody,
+		Done:        row.StatusCode.Valid,
+	}
+	if row.ResponseHeaders != nil {
+		err = json.Unmarshal(row.ResponseHeaders, &rec.Headers)
+	}
+	return
+}
+
+func (s pgStore) Complete(ctx context.Context, key, scope string, status int, body []byte, headers http.Header) error {
+	raw, err := json.Marshal(headers)
+	if err != nil {
+		return err
+	}
+	return s.qs.CompleteIdempotencyKey(ctx, gensql.CompleteIdempotencyKeyParams{
+		IdemKey:         key,
+		Scope:           scope,
+		StatusCode:      pgtype.Int4{Int32: int32(status), Valid: true},
+		ResponseBody:    body,
+		ResponseHeaders: raw,
+	})
+}
+
+func (s pgStore) Release(ctx context.Context, key, scope string) error {
+	return s.qs.ReleaseIdempotencyKey(ctx, gensql.ReleaseIdempotencyKeyParams{
+		IdemKey: key,
+		Scope:   scope,
+	})
+}
+
+func (s pgStore) Purge(ctx context.Context) (int64, error) {
+	return s.qs.PurgeIdempotencyKeys(ctx)
+}
+
+func RunPurger(ctx context.Context, store Store, interval time.Duration, w *health.Worker) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	w.Start()
+	defer w.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			n, err := store.Purge(ctx)
+			w.Done(err)
+			if err != nil {
+				log.Error().Err(err).Msg("failed to purge idempotency keys")
+				continue
+			}
+			log.Debug().Int64("purged", n).Msg("idempotency keys purged")
+		}
+	}
+}
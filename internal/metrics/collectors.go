@@ -0,0 +1,123 @@
+package metrics
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/rs/zerolog/log"
+	"plassstic.tech/trainee/avito/internal/schema"
+)
+
+type poolCollector struct {
+	pool *pgxpool.Pool
+
+	acquired, idle, total, max  *prometheus.Desc
+	acquires, emptyAcquires     *prometheus.Desc
+	canceledAcquires, waitTime  *prometheus.Desc
+	newConns, destroyedLifetime *prometheus.Desc
+	destroyedIdle, constructing *prometheus.Desc
+}
+
+func poolDesc(name, help string) *prometheus.Desc {
+	return prometheus.NewDesc(prometheus.BuildFQName(namespace, "pgxpool", name), help, nil, nil)
+}
+
+func NewPoolCollector(pool *pgxpool.Pool) prometheus.Collector {
+	return &poolCollector{
+		pool:              pool,
+		acquired:          poolDesc("acquired_conns", "Connections currently in use."),
+		idle:              poolDesc("idle_conns", "Idle connections."),
+		total:             poolDesc("total_conns", "All open connections."),
+		max:               poolDesc("max_conns", "Configured pool size."),
+		constructing:      poolDesc("constructing_conns", "Connections being established."),
+		acquires:          poolDesc("acquires_total", "Successful connection acquires."),
+		emptyAcquires:     poolDesc("empty_acquires_total", "Acquires that had to wait for a connection."),
+		canceledAcquires:  poolDesc("canceled_acquires_total", "Acquires canceled by context."),
+		waitTime:          poolDesc("acquire_wait_seconds_total", "Time spent waiting for connections."),
+		newConns:          poolDesc("new_conns_total", "Connections opened."),
+		destroyedLifetime: poolDesc("max_lifetime_destroyed_total", "Connections closed for exceeding max lifetime."),
+		destroyedIdle:     poolDesc("max_idle_destroyed_total", "Connections closed for exceeding max idle time."),
+	}
+}
+
+func (c *poolCollector) Describe(ch chan<- *prometheus.Desc) {
+	for _, d := range []*prometheus.Desc{
+		c.acquired, c.idle, c.total, c.max, c.constructing,
+		c.acquires, c.emptyAcquires, c.canceledAcquires, c.waitTime,
+		c.newConns, c.destroyedLifetime, c.destroyedIdle,
+	} {
+		ch <- d
+	}
+}
+
+func (c *poolCollector) Collect(ch chan<- prometheus.Metric) {
+	s := c.pool.Stat()
+	gauge := func(d *prometheus.Desc, v float64) {
+		ch <- prometheus.MustNewConstMetric(d, prometheus.GaugeValue, v)
+	}
+	counter := func(d *prometheus.Desc, v float64) {
+		ch <- prometheus.MustNewConstMetric(d, prometheus.CounterValue, v)
+	}
+
+	gauge(c.acquired, float64(s.AcquiredConns()))
+	gauge(c.idle, float64(s.IdleConns()))
+	gauge(c.total, float64(s.TotalConns()))
+	gauge(c.max, float64(s.MaxConns()))
+	gauge(c.constructing, float64(s.ConstructingConns()))
+	counter(c.acquires, float64(s.AcquireCount()))
+	counter(c.emptyAcquires, float64(s.EmptyAcquireCount()))
+	counter(c.canceledAcquires, float64(s.CanceledAcquireCount()))
+	counter(c.waitTime, s.AcquireDuration().Seconds())
+	counter(c.newConns, float64(s.NewConnsCount()))
+	counter(c.destroyedLifetime, float64(s.MaxLifetimeDestroyCount()))
+	counter(c.destroyedIdle, float64(s.MaxIdleDestroyCount()))
+}
+
+type StatsSource interface {
+	Stats(ctx context.Context) (*schema.Stats, *schema.Err)
+}
+
+type domainCollector struct {
+	source StatsSource
+
+	openPRs, reviewerLoad *prometheus.Desc
+}
+
+// NewDomainCollector reads open PR and reviewer load gauges from storage on
+// every scrape.
+func NewDomainCollector(source StatsSource) prometheus.Collector {
+	return &domainCollector{
+		source: source,
+		openPRs: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "reviews", "open_prs"),
+			"Pull requests in OPEN status.", nil, nil,
+		),
+		reviewerLoad: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "reviews", "reviewer_load"),
+			"Open pull requests each user is assigned to review.", []string{"user_id"}, nil,
+		),
+	}
+}
+
+func (c *domainCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.openPRs
+	ch <- c.reviewerLoad
+}
+
+func (c *domainCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	stats, err := c.source.Stats(ctx)
+	if err != nil {
+		log.Error().Err(err).Msg("failed to collect domain metrics")
+		return
+	}
+
+	ch <- prometheus.MustNewConstMetric(c.openPRs, prometheus.GaugeValue, float64(stats.OpenPRs))
+	for userID, load := range stats.ReviewerLoad {
+		ch <- prometheus.MustNewConstMetric(c.reviewerLoad, prometheus.GaugeValue, float64(load), userID)
+	}
+}
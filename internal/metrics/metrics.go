@@ -0,0 +1,84 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+)
+
+const namespace = "avito"
+
+var Registry = prometheus.NewRegistry()
+
+var (
+	HTTPRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "requests_total",
+		Help:      "HTTP requests by route, method and status.",
+	}, []string{"route", "method", "status"})
+
+	HTTPDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "request_duration_seconds",
+		Help:      "HTTP request latency by route, method and status.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"route", "method", "status"})
+
+	Transactions = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "db",
+		Name:      "transactions_total",
+		Help:      "Finished transactions by outcome.",
+	}, []string{"outcome"})
+
+	Assignments = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "reviews",
+		Name:      "assignments_total",
+		Help:      "Reviewers assigned to newly created pull requests.",
+	})
+
+	Reassignments = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "reviews",
+		Name:      "reassignments_total",
+		Help:      "Reviewers replaced on open pull requests.",
+	})
+
+	NoCandidate = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "reviews",
+		Name:      "no_candidate_total",
+		Help:      "Reassignments that failed with NO_CANDIDATE.",
+	})
+
+	RateLimitErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "ratelimit",
+		Name:      "errors_total",
+		Help:      "Requests let through unlimited because the rate limiter failed, by group.",
+	}, []string{"group"})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		HTTPRequests,
+		HTTPDuration,
+		Transactions,
+		Assignments,
+		Reassignments,
+		NoCandidate,
+		RateLimitErrors,
+	)
+}
+
+func Commit() {
+	Transactions.WithLabelValues("commit").Inc()
+}
+
+func Rollback() {
+	Transactions.WithLabelValues("rollback").Inc()
+}
@@ -0,0 +1,137 @@
+package migrate
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"plassstic.tech/trainee/avito/sqlc"
+)
+
+const (
+	migratedSchema = "schemacheck_migrations"
+	sqlcSchema     = "schemacheck_sqlc"
+)
+
+var catalogQueries = []string{
+	`select 'type ' || t.typname || ' (' || string_agg(e.enumlabel, ', ' order by e.enumsortorder) || ')'
+	 from pg_type t
+	          join pg_enum e on e.enumtypid = t.oid
+	          join pg_namespace n on n.oid = t.typnamespace
+	 where n.nspname = $1
+	 group by t.typname`,
+	`select 'column ' || c.table_name || '.' || c.column_name || ' ' || c.udt_name
+	           || case when c.is_nullable = 'NO' then ' not null' else '' end
+	           || coalesce(' default ' || c.column_default, '')
+	 from information_schema.columns c
+	 where c.table_schema = $1
+	   and c.table_name <> 'goose_db_version'`,
+	`select 'constraint ' || cl.relname || '.' || co.conname || ' ' || pg_get_constraintdef(co.oid)
+	 from pg_constraint co
+	          join pg_class cl on cl.oid = co.conrelid
+	          join pg_namespace n on n.oid = cl.relnamespace
+	 where n.nspname = $1
+	   and cl.relname <> 'goose_db_version'`,
+	`select 'index ' || indexname || ' ' || indexdef
+	 from pg_indexes
+	 where schemaname = $1
+	   and tablename <> 'goose_db_version'`,
+	`select 'function ' || p.proname || ' ' || md5(regexp_replace(p.prosrc, '\s+', ' ', 'g'))
+	 from pg_proc p
+	          join pg_namespace n on n.oid = p.pronamespace
+	 where n.nspname = $1`,
+	`select 'trigger ' || c.relname || '.' || t.tgname || ' ' || pg_get_triggerdef(t.oid)
+	 from pg_trigger t
+	          join pg_class c on c.oid = t.tgrelid
+	          join pg_namespace n on n.oid = c.relnamespace
+	 where n.nspname = $1
+	   and not t.tgisinternal`,
+}
+
+// Check applies the embedded migrations and sqlc/schema.sql into two scratch
+// schemas and returns every object that differs between them.
+func Check(ctx context.Context, pool *pgxpool.Pool) ([]string, error) {
+	conn, err := pool.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Release()
+
+	defer func() {
+		ctx := context.WithoutCancel(ctx)
+		_, _ = conn.Exec(ctx, "drop schema if exists "+migratedSchema+" cascade")
+		_, _ = conn.Exec(ctx, "drop schema if exists "+sqlcSchema+" cascade")
+		_, _ = conn.Exec(ctx, "reset search_path")
+	}()
+
+	for _, schema := range []string{migratedSchema, sqlcSchema} {
+		if _, err = conn.Exec(ctx, fmt.Sprintf("drop schema if exists %[1]s cascade; create schema %[1]s", schema)); err != nil {
+			return nil, err
+		}
+	}
+
+	if err = migrateInto(ctx, pool, migratedSchema); err != nil {
+		return nil, fmt.Errorf("apply migrations: %w", err)
+	}
+	if _, err = conn.Exec(ctx, "set search_path to "+sqlcSchema+"; "+sqlc.Schema+"; reset search_path"); err != nil {
+		return nil, fmt.Errorf("apply sqlc schema: %w", err)
+	}
+
+	migrated, err := describe(ctx, conn.Conn(), migratedSchema)
+	if err != nil {
+		return nil, err
+	}
+	generated, err := describe(ctx, conn.Conn(), sqlcSchema)
+	if err != nil {
+		return nil, err
+	}
+
+	var diff []string
+	for _, obj := range migrated {
+		if !slices.Contains(generated, obj) {
+			diff = append(diff, "only in migrations: "+obj)
+		}
+	}
+	for _, obj := range generated {
+		if !slices.Contains(migrated, obj) {
+			diff = append(diff, "only in sqlc schema: "+obj)
+		}
+	}
+	return diff, nil
+}
+
+func migrateInto(ctx context.Context, pool *pgxpool.Pool, schema string) error {
+	cfg := pool.Config().ConnConfig.Copy()
+	cfg.RuntimeParams["search_path"] = schema
+
+	m, err := FromConfig(cfg)
+	if err != nil {
+		return err
+	}
+	defer m.Close()
+
+	_, err = m.provider.Up(ctx)
+	return err
+}
+
+func describe(ctx context.Context, conn *pgx.Conn, schema string) ([]string, error) {
+	var objects []string
+	for _, q := range catalogQueries {
+		rows, err := conn.Query(ctx, q, schema)
+		if err != nil {
+			return nil, err
+		}
+		found, err := pgx.CollectRows(rows, pgx.RowTo[string])
+		if err != nil {
+			return nil, err
+		}
+		for _, obj := range found {
+			objects = append(objects, strings.ReplaceAll(obj, schema+".", ""))
+		}
+	}
+	slices.Sort(objects)
+	return objects, nil
+}
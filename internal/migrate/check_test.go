@@ -0,0 +1,74 @@
+package migrate
+
+import (
+	"io/fs"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"plassstic.tech/trainee/avito/migrations"
+)
+
+// TestCheck compares the embedded migrations with sqlc/schema.sql on the
+// database in REPOTEST_POSTGRES_URL, the one the repo conformance suite uses.
+func TestCheck(t *testing.T) {
+	url := os.Getenv("REPOTEST_POSTGRES_URL")
+	if url == "" {
+		t.Skip("REPOTEST_POSTGRES_URL is not set")
+	}
+
+	pool, err := pgxpool.New(t.Context(), url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pool.Close()
+
+	diff, err := Check(t.Context(), pool)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(diff) > 0 {
+		t.Fatalf("sqlc schema drifted from the migrations:\n%s", strings.Join(diff, "\n"))
+	}
+}
+
+func versions(t *testing.T, fsys fs.FS) []string {
+	t.Helper()
+	files, err := fs.Glob(fsys, "*.sql")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var vs []string
+	for _, f := range files {
+		raw, err := fs.ReadFile(fsys, f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(string(raw), "-- +goose Up") || !strings.Contains(string(raw), "-- +goose Down") {
+			t.Errorf("%s has no goose Up and Down sections", f)
+		}
+		v, _, _ := strings.Cut(f, "_")
+		vs = append(vs, v)
+	}
+	return vs
+}
+
+func TestEmbeddedMigrations(t *testing.T) {
+	pg := versions(t, migrations.FS)
+	if len(pg) == 0 {
+		t.Fatal("no migrations embedded")
+	}
+	if want, _ := strconv.ParseInt(slices.Max(pg), 10, 64); migrations.Latest() != want {
+		t.Fatalf("expected Latest to be %d, got %d", want, migrations.Latest())
+	}
+
+	for _, v := range versions(t, migrations.SQLite()) {
+		if !slices.Contains(pg, v) {
+			t.Errorf("sqlite migration %s has no Postgres counterpart", v)
+		}
+	}
+}
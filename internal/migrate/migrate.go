@@ -0,0 +1,80 @@
+package migrate
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jackc/pgx/v5/stdlib"
+	"github.com/pressly/goose/v3"
+	"github.com/pressly/goose/v3/lock"
+	"github.com/rs/zerolog/log"
+	"plassstic.tech/trainee/avito/migrations"
+)
+
+type Migrator struct {
+	db       *sql.DB
+	provider *goose.Provider
+}
+
+// New runs migrations over db; the session lock keeps replicas that
+// auto-migrate on startup from racing each other.
+func New(db *sql.DB) (*Migrator, error) {
+	locker, err := lock.NewPostgresSessionLocker()
+	if err != nil {
+		return nil, err
+	}
+
+	provider, err := goose.NewProvider(goose.DialectPostgres, db, migrations.FS, goose.WithSessionLocker(locker))
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{db: db, provider: provider}, nil
+}
+
+// NewSQLite migrates a sqlite database, which only ever has one writer so it
+// needs no session lock.
+func NewSQLite(db *sql.DB) (*Migrator, error) {
+	provider, err := goose.NewProvider(goose.DialectSQLite3, db, migrations.SQLite())
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{db: db, provider: provider}, nil
+}
+
+func FromPool(pool *pgxpool.Pool) (*Migrator, error) {
+	return New(stdlib.OpenDBFromPool(pool))
+}
+
+func FromConfig(cfg *pgx.ConnConfig) (*Migrator, error) {
+	return New(stdlib.OpenDB(*cfg))
+}
+
+func (m *Migrator) Close() error {
+	return m.db.Close()
+}
+
+func (m *Migrator) Up(ctx context.Context) error {
+	results, err := m.provider.Up(ctx)
+	for _, r := range results {
+		log.Info().Str("migration", r.String()).Msg("applied")
+	}
+	return err
+}
+
+func (m *Migrator) Down(ctx context.Context) error {
+	r, err := m.provider.Down(ctx)
+	if r != nil {
+		log.Info().Str("migration", r.String()).Msg("rolled back")
+	}
+	return err
+}
+
+func (m *Migrator) Status(ctx context.Context) ([]*goose.MigrationStatus, error) {
+	return m.provider.Status(ctx)
+}
+
+func (m *Migrator) Version(ctx context.Context) (int64, error) {
+	return m.provider.GetDBVersion(ctx)
+}
@@ -0,0 +1,46 @@
+//go:build cgo
+
+package migrate
+
+import (
+	"database/sql"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// TestSQLiteUpDown applies the embedded sqlite migrations, rolls all of them
+// back and applies them again.
+func TestSQLiteUpDown(t *testing.T) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.SetMaxOpenConns(1)
+
+	m, err := NewSQLite(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.Close()
+	ctx := t.Context()
+
+	if err = m.Up(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = m.provider.DownTo(ctx, 0); err != nil {
+		t.Fatal(err)
+	}
+	if err = m.Up(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	v, err := m.Version(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sources := m.provider.ListSources()
+	if want := sources[len(sources)-1].Version; v != want {
+		t.Fatalf("expected version %d, got %d", want, v)
+	}
+}
@@ -0,0 +1,256 @@
+package policy
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+
+	"plassstic.tech/trainee/avito/internal/auth"
+	"plassstic.tech/trainee/avito/internal/repo"
+	"plassstic.tech/trainee/avito/internal/schema"
+	"plassstic.tech/trainee/avito/internal/service"
+)
+
+var _ service.Service = policy{}
+
+// policy guards the calls that act on somebody else's data, everything else
+// is passed through to the wrapped service as is. Rules that read state run
+// as a service.Check, inside the transaction of the call they guard.
+type policy struct {
+	service.Service
+}
+
+func New(s service.Service) service.Service {
+	return policy{Service: s}
+}
+
+// WithCheck keeps the policy in front of the checked service.
+func (p policy) WithCheck(check service.Check) service.Service {
+	return policy{Service: p.Service.WithCheck(check)}
+}
+
+func forbidden(format string, args ...any) *schema.Err {
+	return schema.Err{}.Wrap(schema.Forbidden, fmt.Errorf(format, args...))
+}
+
+func caller(ctx context.Context) (id auth.Identity, err *schema.Err) {
+	var ok bool
+	if id, ok = auth.FromContext(ctx); !ok {
+		err = schema.Err{}.Wrap(schema.Unauthorized, fmt.Errorf("caller is not authenticated"))
+	}
+	return
+}
+
+// sameTeam reports whether the calling lead shares a team with userID.
+func sameTeam(ctx context.Context, tx repo.Tx, id auth.Identity, userID string) (ok bool, err *schema.Err) {
+	if id.UserID == "" {
+		return
+	}
+
+	var lead, user *schema.User
+	if lead, err = tx.GetUser(ctx, id.UserID); err != nil {
+		return false, forbidden("caller %s is not a known user", id.UserID)
+	}
+	if user, err = tx.GetUser(ctx, userID); err != nil {
+		return
+	}
+
+	ok = slices.ContainsFunc(lead.Teams, func(team string) bool { return slices.Contains(user.Teams, team) })
+	return
+}
+
+// teammate checks that the caller shares a team with userID, whom the error
+// calls what.
+func teammate(id auth.Identity, userID, what string) service.Check {
+	return func(ctx context.Context, tx repo.Tx) *schema.Err {
+		ok, err := sameTeam(ctx, tx, id, userID)
+		if err != nil {
+			return err
+		} else if !ok {
+			return forbidden("%s %s is not in your team", what, userID)
+		}
+		return nil
+	}
+}
+
+func (p policy) SetUserActive(ctx context.Context, userID string, isActive bool) (*schema.User, *schema.Err) {
+	id, err := caller(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	s := p.Service
+	switch {
+	case id.Role == schema.RoleAdmin, id.UserID == userID:
+	case id.Role == schema.RoleTeamLead:
+		s = s.WithCheck(teammate(id, userID, "user"))
+	default:
+		return nil, forbidden("only %s can change their activity", userID)
+	}
+
+	return s.SetUserActive(ctx, userID, isActive)
+}
+
+func (p policy) MergePR(ctx context.Context, prID string, ifMatch *schema.Precondition) (*schema.PullRequest, *schema.Err) {
+	id, err := caller(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	s := p.Service
+	if id.Role != schema.RoleAdmin {
+		s = s.WithCheck(func(ctx context.Context, tx repo.Tx) *schema.Err {
+			pr, err := tx.GetPR(ctx, prID)
+			if err != nil {
+				return err
+			}
+			if pr.AuthorId != id.UserID {
+				return forbidden("only the author of PR %s can merge it", prID)
+			}
+			return nil
+		})
+	}
+	return s.MergePR(ctx, prID, ifMatch)
+}
+
+func (p policy) ReassignReviewer(ctx context.Context, prID, oldUserID string, ifMatch *schema.Precondition) (string, *schema.PullRequest, *schema.Err) {
+	id, err := caller(ctx)
+	if err != nil {
+		return "", nil, err
+	}
+
+	s := p.Service
+	switch {
+	case id.Role == schema.RoleAdmin, id.UserID == oldUserID:
+	case id.Role == schema.RoleTeamLead:
+		s = s.WithCheck(teammate(id, oldUserID, "reviewer"))
+	default:
+		return "", nil, forbidden("reviewers can only reassign themselves")
+	}
+
+	return s.ReassignReviewer(ctx, prID, oldUserID, ifMatch)
+}
+
+// CreatePR lets callers open PRs only in their own name, admins excepted.
+func (p policy) CreatePR(ctx context.Context, req schema.CreatePRRequest) (*schema.PullRequest, *schema.Err) {
+	if err := authors(ctx, req.AuthorID); err != nil {
+		return nil, err
+	}
+	return p.Service.CreatePR(ctx, req)
+}
+
+func (p policy) CreatePRBatch(ctx context.Context, req schema.CreatePRBatchRequest) (*schema.CreatePRBatchResponse, *schema.Err) {
+	for _, pr := range req.PullRequests {
+		if err := authors(ctx, pr.AuthorID); err != nil {
+			return nil, err
+		}
+	}
+	return p.Service.CreatePRBatch(ctx, req)
+}
+
+// authors reports an error unless the caller is an admin or authorID.
+func authors(ctx context.Context, authorID string) *schema.Err {
+	id, err := caller(ctx)
+	if err != nil {
+		return err
+	}
+	if id.Role != schema.RoleAdmin && id.UserID != authorID {
+		return forbidden("PRs can only be opened in your own name, not as %s", authorID)
+	}
+	return nil
+}
+
+// leads reports an error unless the caller is an admin or a lead of teamName.
+func leads(ctx context.Context, tx repo.Tx, teamName string) *schema.Err {
+	id, err := caller(ctx)
+	if err != nil {
+		return err
+	}
+	if id.Role == schema.RoleAdmin {
+		return nil
+	}
+
+	if id.Role == schema.RoleTeamLead && id.UserID != "" {
+		if lead, err := tx.GetUser(ctx, id.UserID); err == nil && slices.Contains(lead.Teams, teamName) {
+			return nil
+		}
+	}
+	return forbidden("only a lead of team %s can change its members", teamName)
+}
+
+// leading checks that the caller leads every one of teams.
+func leading(teams ...string) service.Check {
+	return func(ctx context.Context, tx repo.Tx) *schema.Err {
+		for _, team := range teams {
+			if err := leads(ctx, tx, team); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+func (p policy) AddTeamMembers(ctx context.Context, req schema.AddTeamMembersRequest) (*schema.Team, *schema.Err) {
+	return p.Service.WithCheck(leading(req.TeamName)).AddTeamMembers(ctx, req)
+}
+
+func (p policy) RemoveTeamMembers(ctx context.Context, req schema.RemoveTeamMembersRequest) (*schema.Team, *schema.Err) {
+	return p.Service.WithCheck(leading(req.TeamName)).RemoveTeamMembers(ctx, req)
+}
+
+// MoveTeamMember needs a lead of the team the user joins and of the one they
+// leave, their primary one unless from_team says otherwise.
+func (p policy) MoveTeamMember(ctx context.Context, req schema.MoveTeamMemberRequest) (*schema.MoveTeamMemberResponse, *schema.Err) {
+	return p.Service.WithCheck(func(ctx context.Context, tx repo.Tx) *schema.Err {
+		user, err := tx.GetUser(ctx, req.UserID)
+		if err != nil {
+			return err
+		}
+
+		if err = leads(ctx, tx, req.TeamName); err != nil {
+			return err
+		}
+		if from := cmp.Or(req.FromTeam, user.TeamName); from != "" && from != req.TeamName {
+			return leads(ctx, tx, from)
+		}
+		return nil
+	}).MoveTeamMember(ctx, req)
+}
+
+// AddTeam under a parent is up to a lead of the parent.
+func (p policy) AddTeam(ctx context.Context, team schema.Team) (*schema.Team, *schema.Err) {
+	s := p.Service
+	if team.ParentTeam != "" {
+		s = s.WithCheck(leading(team.ParentTeam))
+	}
+	return s.AddTeam(ctx, team)
+}
+
+// SetTeamParent needs a lead of the team and, unless the team is made
+// top-level, of the new parent too.
+func (p policy) SetTeamParent(ctx context.Context, req schema.SetTeamParentRequest) (*schema.Team, *schema.Err) {
+	teams := []string{req.TeamName}
+	if req.ParentTeam != "" {
+		teams = append(teams, req.ParentTeam)
+	}
+	return p.Service.WithCheck(leading(teams...)).SetTeamParent(ctx, req)
+}
+
+func (p policy) DeleteTeam(ctx context.Context, teamName string) (*schema.Team, *schema.Err) {
+	return p.Service.WithCheck(leading(teamName)).DeleteTeam(ctx, teamName)
+}
+
+// DeleteUser is up to an admin or a lead who shares a team with the user.
+func (p policy) DeleteUser(ctx context.Context, userID string) (*schema.DeleteUserResponse, *schema.Err) {
+	id, err := caller(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	s := p.Service
+	if id.Role != schema.RoleAdmin {
+		s = s.WithCheck(teammate(id, userID, "user"))
+	}
+	return s.DeleteUser(ctx, userID)
+}
@@ -0,0 +1,208 @@
+package policy_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"plassstic.tech/trainee/avito/internal/auth"
+	"plassstic.tech/trainee/avito/internal/events"
+	"plassstic.tech/trainee/avito/internal/policy"
+	"plassstic.tech/trainee/avito/internal/repo"
+	"plassstic.tech/trainee/avito/internal/router"
+	"plassstic.tech/trainee/avito/internal/schema"
+	"plassstic.tech/trainee/avito/internal/service"
+	"plassstic.tech/trainee/avito/internal/utils"
+)
+
+const adminKey = "test-admin-key"
+
+// callers are the demo users the cases act as: u1 leads backend, u5 leads
+// frontend, u2 and u3 are backend users.
+var callers = map[string]schema.IssueAPIKeyRequest{
+	"lead u1": {UserID: "u1", Role: schema.RoleTeamLead},
+	"lead u5": {UserID: "u5", Role: schema.RoleTeamLead},
+	"user u1": {UserID: "u1", Role: schema.RoleUser},
+	"user u2": {UserID: "u2", Role: schema.RoleUser},
+	"user u3": {UserID: "u3", Role: schema.RoleUser},
+}
+
+// newServer serves the real router over the in-memory demo store and
+// returns it with an api key per caller.
+func newServer(t *testing.T) (*httptest.Server, map[string]string) {
+	t.Helper()
+	cfg, err := utils.LoadConfig(map[string]string{
+		"SERVER_DEMO":       "true",
+		"AUTH_ENABLED":      "true",
+		"AUTH_ADMIN_KEY":    adminKey,
+		"RATELIMIT_ENABLED": "false",
+		"METRICS_PORT":      "0",
+		"METRICS_TOKEN":     "",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(router.New(utils.SetupBox(t.Context(), cfg)))
+	t.Cleanup(srv.Close)
+
+	keys := map[string]string{"admin": adminKey}
+	for name, req := range callers {
+		var res schema.APIKeyResponse
+		if status := post(t, srv, adminKey, "/auth/issueKey", req, &res); status != http.StatusCreated {
+			t.Fatalf("issue key for %s: status %d", name, status)
+		}
+		keys[name] = res.Key.Key
+	}
+	return srv, keys
+}
+
+func post(t *testing.T, srv *httptest.Server, key, path string, body, out any) int {
+	t.Helper()
+	raw, err := json.Marshal(body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req, err := http.NewRequestWithContext(t.Context(), http.MethodPost, srv.URL+path, bytes.NewReader(raw))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(auth.APIKeyHeader, key)
+
+	res, err := srv.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	if out != nil {
+		if err = json.NewDecoder(res.Body).Decode(out); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return res.StatusCode
+}
+
+func TestPolicy(t *testing.T) {
+	pr := func(author string) map[string]any {
+		return map[string]any{"pull_request_id": "pr-new", "pull_request_name": "New", "author_id": author}
+	}
+	gina := []any{map[string]any{"user_id": "u7", "username": "Gina", "is_active": true}}
+
+	tests := []struct {
+		name   string
+		caller string
+		path   string
+		body   any
+		// deny is the code the call is refused with, empty when it goes
+		// through to the service
+		deny schema.ErrorCode
+	}{
+		{name: "author merges", caller: "user u1", path: "/pullRequest/merge", body: map[string]any{"pull_request_id": "pr-1001"}},
+		{name: "admin merges", caller: "admin", path: "/pullRequest/merge", body: map[string]any{"pull_request_id": "pr-1001"}},
+		{name: "reviewer merges", caller: "user u2", path: "/pullRequest/merge", body: map[string]any{"pull_request_id": "pr-1001"}, deny: schema.Forbidden},
+		{name: "other lead merges", caller: "lead u5", path: "/pullRequest/merge", body: map[string]any{"pull_request_id": "pr-1001"}, deny: schema.Forbidden},
+
+		{name: "reviewer reassigns self", caller: "user u2", path: "/pullRequest/reassign", body: map[string]any{"pull_request_id": "pr-1001", "old_reviewer_id": "u2"}},
+		{name: "reviewer reassigns other", caller: "user u2", path: "/pullRequest/reassign", body: map[string]any{"pull_request_id": "pr-1001", "old_reviewer_id": "u3"}, deny: schema.Forbidden},
+		{name: "lead reassigns teammate", caller: "lead u1", path: "/pullRequest/reassign", body: map[string]any{"pull_request_id": "pr-1001", "old_reviewer_id": "u3"}},
+		{name: "lead reassigns outsider", caller: "lead u5", path: "/pullRequest/reassign", body: map[string]any{"pull_request_id": "pr-1001", "old_reviewer_id": "u3"}, deny: schema.Forbidden},
+
+		{name: "user toggles self", caller: "user u3", path: "/users/setIsActive", body: map[string]any{"user_id": "u3", "is_active": false}},
+		{name: "user toggles other", caller: "user u2", path: "/users/setIsActive", body: map[string]any{"user_id": "u3", "is_active": false}, deny: schema.Forbidden},
+		{name: "lead toggles teammate", caller: "lead u1", path: "/users/setIsActive", body: map[string]any{"user_id": "u3", "is_active": false}},
+		{name: "lead toggles outsider", caller: "lead u5", path: "/users/setIsActive", body: map[string]any{"user_id": "u3", "is_active": false}, deny: schema.Forbidden},
+		{name: "admin toggles anyone", caller: "admin", path: "/users/setIsActive", body: map[string]any{"user_id": "u3", "is_active": false}},
+
+		{name: "user opens own pr", caller: "user u2", path: "/pullRequest/create", body: pr("u2")},
+		{name: "user opens pr as other", caller: "user u2", path: "/pullRequest/create", body: pr("u1"), deny: schema.Forbidden},
+		{name: "lead opens pr as teammate", caller: "lead u1", path: "/pullRequest/create", body: pr("u2"), deny: schema.Forbidden},
+		{name: "admin opens pr as anyone", caller: "admin", path: "/pullRequest/create", body: pr("u2")},
+		{
+			name:   "user batch with other author",
+			caller: "user u2",
+			path:   "/pullRequest/createBatch",
+			body:   map[string]any{"pull_requests": []any{pr("u2"), pr("u1")}},
+			deny:   schema.Forbidden,
+		},
+
+		{name: "lead adds to own team", caller: "lead u1", path: "/team/addMembers", body: map[string]any{"team_name": "backend", "members": gina}},
+		{name: "lead adds to other team", caller: "lead u5", path: "/team/addMembers", body: map[string]any{"team_name": "backend", "members": gina}, deny: schema.Forbidden},
+		{name: "user adds members", caller: "user u2", path: "/team/addMembers", body: map[string]any{"team_name": "backend", "members": gina}, deny: schema.InsufficientRole},
+		{name: "lead moves out of own team", caller: "lead u1", path: "/team/moveMember", body: map[string]any{"user_id": "u2", "team_name": "frontend"}, deny: schema.Forbidden},
+		{name: "admin moves anyone", caller: "admin", path: "/team/moveMember", body: map[string]any{"user_id": "u2", "team_name": "frontend"}},
+		{name: "lead deletes teammate", caller: "lead u1", path: "/users/delete", body: map[string]any{"user_id": "u3"}},
+		{name: "lead deletes outsider", caller: "lead u5", path: "/users/delete", body: map[string]any{"user_id": "u3"}, deny: schema.Forbidden},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			srv, keys := newServer(t)
+
+			var res schema.ErrorResponse
+			status := post(t, srv, keys[tc.caller], tc.path, tc.body, &res)
+			if tc.deny == "" && (status == http.StatusForbidden || status == http.StatusBadRequest) {
+				t.Fatalf("expected the call through, got %d %+v", status, res.Err)
+			}
+			if tc.deny != "" && (status != http.StatusForbidden || res.Code != tc.deny) {
+				t.Fatalf("expected %s, got %d %+v", tc.deny, status, res.Err)
+			}
+		})
+	}
+}
+
+// countingStore counts the transactions the service opens.
+type countingStore struct {
+	repo.Store
+	begins int
+}
+
+func (s *countingStore) Begin(ctx context.Context) (repo.Tx, error) {
+	s.begins++
+	return s.Store.Begin(ctx)
+}
+
+// TestCheckSharesTransaction makes sure a rule reads the state inside the
+// transaction that then changes it, not in one of its own.
+func TestCheckSharesTransaction(t *testing.T) {
+	store := &countingStore{Store: repo.NewMemory()}
+	svc := service.NewWithStore(store, events.Nop(), "test")
+	ctx := t.Context()
+
+	if _, err := svc.AddTeam(ctx, schema.Team{TeamName: "backend", Members: []schema.TeamMember{
+		{UserID: "u1", UserName: "Alice", IsActive: true},
+		{UserID: "u2", UserName: "Bob", IsActive: true},
+	}}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.CreatePR(ctx, schema.CreatePRRequest{PRId: "pr-1", Name: "Change", AuthorID: "u1"}); err != nil {
+		t.Fatal(err)
+	}
+
+	guarded := policy.New(svc)
+	for _, tc := range []struct {
+		name string
+		call func(ctx context.Context) *schema.Err
+	}{
+		{"merge", func(ctx context.Context) *schema.Err {
+			_, err := guarded.MergePR(ctx, "pr-1", nil)
+			return err
+		}},
+		{"set active", func(ctx context.Context) *schema.Err {
+			_, err := guarded.SetUserActive(ctx, "u2", false)
+			return err
+		}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			store.begins = 0
+			if err := tc.call(auth.WithIdentity(ctx, auth.Identity{UserID: "u1", Role: schema.RoleTeamLead})); err != nil {
+				t.Fatal(err)
+			}
+			if store.begins != 1 {
+				t.Fatalf("expected one transaction, got %d", store.begins)
+			}
+		})
+	}
+}
@@ -0,0 +1,148 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/rs/zerolog/log"
+	"plassstic.tech/trainee/avito/gensql"
+	"plassstic.tech/trainee/avito/internal/health"
+)
+
+// Limit is a token bucket refilled with Rate tokens per second up to Burst.
+type Limit struct {
+	Rate  float64
+	Burst int
+}
+
+// ParseLimit reads a limit written as "rate:burst", e.g. "0.5:10".
+func ParseLimit(s string) (l Limit, err error) {
+	rate, burst, ok := strings.Cut(s, ":")
+	if !ok {
+		err = fmt.Errorf("limit %q is not in rate:burst form", s)
+		return
+	}
+	if l.Rate, err = strconv.ParseFloat(rate, 64); err != nil {
+		return
+	}
+	if l.Burst, err = strconv.Atoi(burst); err != nil {
+		return
+	}
+	if l.Rate <= 0 || l.Burst < 1 {
+		err = fmt.Errorf("limit %q must have a positive rate and burst", s)
+	}
+	return
+}
+
+func (l Limit) retryAfter(tokens float64) time.Duration {
+	return time.Duration(math.Ceil((1-tokens)/l.Rate*1000)) * time.Millisecond
+}
+
+type Limiter interface {
+	Allow(ctx context.Context, key string, limit Limit) (ok bool, retryAfter time.Duration, err error)
+	Cleanup(ctx context.Context, idle time.Duration) error
+}
+
+type bucket struct {
+	tokens  float64
+	updated time.Time
+}
+
+type memory struct {
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	// now is the clock buckets are refilled by, replaced in tests
+	now func() time.Time
+}
+
+func NewMemory() Limiter {
+	return &memory{buckets: make(map[string]*bucket), now: time.Now}
+}
+
+func (m *memory) Allow(_ context.Context, key string, limit Limit) (bool, time.Duration, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := m.now()
+	b, ok := m.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(limit.Burst), updated: now}
+		m.buckets[key] = b
+	}
+
+	b.tokens = min(float64(limit.Burst), b.tokens+now.Sub(b.updated).Seconds()*limit.Rate)
+	b.updated = now
+
+	if b.tokens < 1 {
+		return false, limit.retryAfter(b.tokens), nil
+	}
+	b.tokens--
+	return true, 0, nil
+}
+
+func (m *memory) Cleanup(_ context.Context, idle time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for key, b := range m.buckets {
+		if m.now().Sub(b.updated) > idle {
+			delete(m.buckets, key)
+		}
+	}
+	return nil
+}
+
+type postgres struct {
+	qs *gensql.Queries
+}
+
+func NewPostgres(pool *pgxpool.Pool) Limiter {
+	return postgres{qs: gensql.New(pool)}
+}
+
+func (p postgres) Allow(ctx context.Context, key string, limit Limit) (bool, time.Duration, error) {
+	row, err := p.qs.TakeRateLimitToken(ctx, gensql.TakeRateLimitTokenParams{
+		BucketKey: key,
+		Burst:     float64(limit.Burst),
+		Rate:      limit.Rate,
+	})
+	if err != nil {
+		return false, 0, err
+	}
+	if !row.Allowed {
+		return false, limit.retryAfter(row.Tokens), nil
+	}
+	return true, 0, nil
+}
+
+func (p postgres) Cleanup(ctx context.Context, idle time.Duration) error {
+	n, err := p.qs.PurgeRateLimitBuckets(ctx, idle.Seconds())
+	log.Debug().Int64("purged", n).AnErr("err", err).Msg("rate limit buckets purged")
+	return err
+}
+
+func RunCleanup(ctx context.Context, l Limiter, idle time.Duration, w *health.Worker) {
+	t := time.NewTicker(idle)
+	defer t.Stop()
+	w.Start()
+	defer w.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			err := l.Cleanup(ctx, idle)
+			w.Done(err)
+			if err != nil {
+				log.Error().Err(err).Msg("failed to clean up rate limit buckets")
+			}
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseLimit(t *testing.T) {
+	for _, tc := range []struct {
+		raw  string
+		want Limit
+		ok   bool
+	}{
+		{"10:20", Limit{Rate: 10, Burst: 20}, true},
+		{"0.5:1", Limit{Rate: 0.5, Burst: 1}, true},
+		{"10", Limit{}, false},
+		{"x:20", Limit{}, false},
+		{"10:x", Limit{}, false},
+		{"0:20", Limit{}, false},
+		{"10:0", Limit{}, false},
+	} {
+		got, err := ParseLimit(tc.raw)
+		if (err == nil) != tc.ok || (tc.ok && got != tc.want) {
+			t.Errorf("ParseLimit(%q) = %+v, %v", tc.raw, got, err)
+		}
+	}
+}
+
+// clock is a manual clock for the memory limiter.
+type clock struct{ now time.Time }
+
+func (c *clock) advance(d time.Duration) { c.now = c.now.Add(d) }
+
+func newTestMemory() (*memory, *clock) {
+	c := &clock{now: time.Unix(0, 0)}
+	m := NewMemory().(*memory)
+	m.now = func() time.Time { return c.now }
+	return m, c
+}
+
+func TestMemoryBurstAndRefill(t *testing.T) {
+	ctx := t.Context()
+	m, c := newTestMemory()
+	limit := Limit{Rate: 2, Burst: 3}
+
+	for i := range limit.Burst {
+		if ok, _, _ := m.Allow(ctx, "k", limit); !ok {
+			t.Fatalf("expected request %d within the burst", i+1)
+		}
+	}
+	ok, retryAfter, _ := m.Allow(ctx, "k", limit)
+	if ok || retryAfter != 500*time.Millisecond {
+		t.Fatalf("expected the burst exhausted with a retry in 500ms, got %v %s", ok, retryAfter)
+	}
+	if ok, _, _ = m.Allow(ctx, "other", limit); !ok {
+		t.Fatal("expected keys to have their own buckets")
+	}
+
+	c.advance(250 * time.Millisecond)
+	if ok, retryAfter, _ = m.Allow(ctx, "k", limit); ok || retryAfter != 250*time.Millisecond {
+		t.Fatalf("expected half a token and a retry in 250ms, got %v %s", ok, retryAfter)
+	}
+	c.advance(250 * time.Millisecond)
+	if ok, _, _ = m.Allow(ctx, "k", limit); !ok {
+		t.Fatal("expected a token refilled after 500ms")
+	}
+
+	c.advance(time.Hour)
+	for i := range limit.Burst {
+		if ok, _, _ = m.Allow(ctx, "k", limit); !ok {
+			t.Fatalf("expected request %d within the refilled burst", i+1)
+		}
+	}
+	if ok, _, _ = m.Allow(ctx, "k", limit); ok {
+		t.Fatal("expected the refill capped at the burst")
+	}
+}
+
+func TestMemoryCleanup(t *testing.T) {
+	ctx := t.Context()
+	m, c := newTestMemory()
+	limit := Limit{Rate: 1, Burst: 1}
+
+	_, _, _ = m.Allow(ctx, "idle", limit)
+	c.advance(time.Minute)
+	_, _, _ = m.Allow(ctx, "busy", limit)
+	c.advance(30 * time.Second)
+
+	if err := m.Cleanup(ctx, 45*time.Second); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := m.buckets["idle"]; ok {
+		t.Fatal("expected the idle bucket dropped")
+	}
+	if _, ok := m.buckets["busy"]; !ok {
+		t.Fatal("expected the busy bucket kept")
+	}
+}
@@ -0,0 +1,140 @@
+package repo
+
+import (
+	"cmp"
+	"fmt"
+	"math/rand"
+	"slices"
+
+	"plassstic.tech/trainee/avito/gensql"
+	"plassstic.tech/trainee/avito/internal/schema"
+)
+
+// batchPlan is what a backend knows about the store before creating a batch
+// of PRs: which ids are taken, the authors' teams, who is free to review and
+// how many open reviews everyone already has.
+type batchPlan struct {
+	existing map[string]bool
+	// teams maps every known author to their teams, primary first
+	teams map[string][]string
+	// parents maps sub-teams to their parent team
+	parents map[string]string
+	members map[string][]string
+	load    map[string]int64
+}
+
+func newBatchPlan() batchPlan {
+	return batchPlan{
+		existing: map[string]bool{},
+		teams:    map[string][]string{},
+		parents:  map[string]string{},
+		members:  map[string][]string{},
+		load:     map[string]int64{},
+	}
+}
+
+// batchKeys lists the PR ids and authors of a batch, each once.
+func batchKeys(prs []schema.PullReqCreate) (prIDs, authors []string) {
+	for _, pr := range prs {
+		prIDs = append(prIDs, pr.PRId)
+		authors = append(authors, pr.AuthorID)
+	}
+	slices.Sort(prIDs)
+	slices.Sort(authors)
+	return slices.Compact(prIDs), slices.Compact(authors)
+}
+
+func (p batchPlan) teamNames() []string {
+	var names []string
+	for _, teams := range p.teams {
+		for _, team := range teams {
+			names = append(names, teamChain(p.parents, team)...)
+		}
+	}
+	slices.Sort(names)
+	return slices.Compact(names)
+}
+
+// run checks every PR and picks reviewers for the ones that pass. Reviewers
+// go to the least loaded active teammates, counting the reviews handed out
+// earlier in the same batch, so a large import does not pile up on whoever
+// sorts first. A team short of reviewers borrows from its parents. Items
+// that pass come back as BatchCreated with the PR the backend still has to
+// store.
+func (p batchPlan) run(prs []schema.PullReqCreate) []schema.PRBatchItem {
+	items := make([]schema.PRBatchItem, len(prs))
+	seen := make(map[string]bool, len(prs))
+
+	for i, prc := range prs {
+		items[i] = schema.PRBatchItem{PRId: prc.PRId, Status: schema.BatchFailed}
+
+		teams, known := p.teams[prc.AuthorID]
+		if p.existing[prc.PRId] || seen[prc.PRId] {
+			items[i].Error = schema.Err{}.Wrap(schema.PRExists, fmt.Errorf("PR %s already exists", prc.PRId)).With("pull_request_id", prc.PRId)
+			continue
+		}
+		if !known {
+			items[i].Error = userNotFound(prc.AuthorID)
+			continue
+		}
+		team, err := prTeam(prc, teams)
+		if err == nil && team == "" {
+			err = noTeam(prc.AuthorID)
+		}
+		if err != nil {
+			items[i].Error = err
+			continue
+		}
+		seen[prc.PRId] = true
+
+		items[i].Status = schema.BatchCreated
+		items[i].PR = &schema.PullRequest{
+			PullRequestShort: schema.PullRequestShort{
+				PRId:     prc.PRId,
+				Name:     prc.Name,
+				AuthorId: prc.AuthorID,
+				Status:   gensql.PrstatOpen,
+			},
+			AssignedReviewers: p.pick(team, prc.AuthorID),
+			TeamName:          team,
+		}
+	}
+	return items
+}
+
+// failItem marks a planned item failed by an error the backend only ran into
+// while storing it.
+func failItem(item *schema.PRBatchItem, err *schema.Err) {
+	item.Status = schema.BatchFailed
+	item.Error = err
+	item.PR = nil
+}
+
+func (p batchPlan) pick(team, authorID string) []string {
+	picked, _ := fillCandidates(teamChain(p.parents, team), []string{authorID}, maxReviewers, func(team string, exclude []string) ([]string, *schema.Err) {
+		candidates := slices.DeleteFunc(slices.Clone(p.members[team]), func(id string) bool { return slices.Contains(exclude, id) })
+		rand.Shuffle(len(candidates), func(i, j int) { candidates[i], candidates[j] = candidates[j], candidates[i] })
+		slices.SortStableFunc(candidates, func(a, b string) int { return cmp.Compare(p.load[a], p.load[b]) })
+		return candidates, nil
+	})
+	for _, id := range picked {
+		p.load[id]++
+	}
+	return picked
+}
+
+// prTeam picks the team a new PR belongs to out of the author's teams, primary
+// first: the requested one if the author is in it, else the primary one. It is
+// "" for authors without a team.
+func prTeam(prc schema.PullReqCreate, teams []string) (string, *schema.Err) {
+	switch {
+	case prc.TeamName == "":
+		if len(teams) == 0 {
+			return "", nil
+		}
+		return teams[0], nil
+	case !slices.Contains(teams, prc.TeamName):
+		return "", notMember(prc.AuthorID, prc.TeamName)
+	}
+	return prc.TeamName, nil
+}
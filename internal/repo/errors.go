@@ -0,0 +1,88 @@
+package repo
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/jackc/pgerrcode"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"plassstic.tech/trainee/avito/internal/schema"
+)
+
+var uniqueCodes = map[string]schema.ErrorCode{
+	"teams_pkey":         schema.TeamExists,
+	"pull_requests_pkey": schema.PRExists,
+}
+
+// dbErr classifies a driver error into a domain code, anything it does not
+// recognise stays UNKNOWN and is hidden from clients.
+func dbErr(err error) *schema.Err {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return schema.Err{}.Wrap(schema.Unknown, err)
+	}
+
+	switch pgErr.Code {
+	case pgerrcode.UniqueViolation:
+		code, ok := uniqueCodes[pgErr.ConstraintName]
+		if !ok {
+			code = schema.Conflict
+		}
+		return schema.Err{}.Wrap(code, fmt.Errorf("%s", pgErr.Detail)).
+			With("constraint", pgErr.ConstraintName)
+	case pgerrcode.ForeignKeyViolation:
+		return schema.Err{}.Wrap(schema.NotFound, fmt.Errorf("%s", pgErr.Detail)).
+			With("constraint", pgErr.ConstraintName)
+	case pgerrcode.SerializationFailure, pgerrcode.DeadlockDetected:
+		return schema.Err{}.Wrap(schema.Conflict, fmt.Errorf("concurrent update, retry the request")).
+			With("retryable", true)
+	case pgerrcode.RaiseException:
+		switch {
+		case strings.HasPrefix(pgErr.Message, "reviewers count for pull request"):
+			return schema.Err{}.Wrap(schema.TooManyReviewers, fmt.Errorf("%s", pgErr.Message))
+		case strings.HasSuffix(pgErr.Message, "already merged"):
+			return schema.Err{}.Wrap(schema.PRMerged, fmt.Errorf("%s", pgErr.Message))
+		}
+	}
+
+	return schema.Err{}.Wrap(schema.Unknown, err)
+}
+
+// orNotFound reports nf when the row is missing and classifies anything else.
+func orNotFound(err error, nf *schema.Err) *schema.Err {
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nf
+	}
+	return dbErr(err)
+}
+
+func userNotFound(userID string) *schema.Err {
+	return schema.Err{}.Wrap(schema.NotFound, fmt.Errorf("user %s not found", userID)).With("user_id", userID)
+}
+
+// userDeleted refuses to reuse the id of a soft-deleted user, which would
+// bring back their history; a purge frees the id.
+func userDeleted(userID string) *schema.Err {
+	return schema.Err{}.Wrap(schema.Conflict, fmt.Errorf("user %s was deleted, purge them to reuse the id", userID)).With("user_id", userID)
+}
+
+// noTeam reports an author who is in no team, so nobody can review their PR.
+func noTeam(authorID string) *schema.Err {
+	return schema.Err{}.Wrap(schema.NoCandidate, fmt.Errorf("author %s is in no team to pick reviewers from", authorID)).With("user_id", authorID)
+}
+
+func teamNotFound(teamName string) *schema.Err {
+	return schema.Err{}.Wrap(schema.NotFound, fmt.Errorf("team %s does not exist", teamName)).With("team_name", teamName)
+}
+
+func notMember(userID, teamName string) *schema.Err {
+	return schema.Err{}.Wrap(schema.NotFound, fmt.Errorf("user %s is not a member of team %s", userID, teamName)).
+		With("user_id", userID).
+		With("team_name", teamName)
+}
+
+func prNotFound(prID string) *schema.Err {
+	return schema.Err{}.Wrap(schema.NotFound, fmt.Errorf("PR %s not found", prID)).With("pull_request_id", prID)
+}
@@ -0,0 +1,127 @@
+package repo
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/jackc/pgerrcode"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"plassstic.tech/trainee/avito/internal/schema"
+)
+
+func TestDBErr(t *testing.T) {
+	tests := []struct {
+		name    string
+		err     error
+		code    schema.ErrorCode
+		details map[string]any
+	}{
+		{
+			name:    "team exists",
+			err:     &pgconn.PgError{Code: pgerrcode.UniqueViolation, ConstraintName: "teams_pkey", Detail: "Key (team_name)=(backend) already exists."},
+			code:    schema.TeamExists,
+			details: map[string]any{"constraint": "teams_pkey"},
+		},
+		{
+			name:    "pr exists",
+			err:     &pgconn.PgError{Code: pgerrcode.UniqueViolation, ConstraintName: "pull_requests_pkey", Detail: "Key (pull_req_id)=(pr-1) already exists."},
+			code:    schema.PRExists,
+			details: map[string]any{"constraint": "pull_requests_pkey"},
+		},
+		{
+			name:    "other unique",
+			err:     &pgconn.PgError{Code: pgerrcode.UniqueViolation, ConstraintName: "api_keys_pkey"},
+			code:    schema.Conflict,
+			details: map[string]any{"constraint": "api_keys_pkey"},
+		},
+		{
+			name:    "foreign key",
+			err:     &pgconn.PgError{Code: pgerrcode.ForeignKeyViolation, ConstraintName: "pull_requests_author_id_fkey"},
+			code:    schema.NotFound,
+			details: map[string]any{"constraint": "pull_requests_author_id_fkey"},
+		},
+		{
+			name:    "serialization failure",
+			err:     &pgconn.PgError{Code: pgerrcode.SerializationFailure},
+			code:    schema.Conflict,
+			details: map[string]any{"retryable": true},
+		},
+		{
+			name:    "deadlock",
+			err:     &pgconn.PgError{Code: pgerrcode.DeadlockDetected},
+			code:    schema.Conflict,
+			details: map[string]any{"retryable": true},
+		},
+		{
+			name: "too many reviewers",
+			err:  &pgconn.PgError{Code: pgerrcode.RaiseException, Message: "reviewers count for pull request pr-1 already eq to 2"},
+			code: schema.TooManyReviewers,
+		},
+		{
+			name: "merged",
+			err:  &pgconn.PgError{Code: pgerrcode.RaiseException, Message: "pr pr-1 already merged"},
+			code: schema.PRMerged,
+		},
+		{
+			name: "other exception",
+			err:  &pgconn.PgError{Code: pgerrcode.RaiseException, Message: "something else"},
+			code: schema.Unknown,
+		},
+		{
+			name: "other pg error",
+			err:  &pgconn.PgError{Code: pgerrcode.CheckViolation},
+			code: schema.Unknown,
+		},
+		{
+			name:    "wrapped",
+			err:     fmt.Errorf("insert: %w", &pgconn.PgError{Code: pgerrcode.UniqueViolation, ConstraintName: "teams_pkey"}),
+			code:    schema.TeamExists,
+			details: map[string]any{"constraint": "teams_pkey"},
+		},
+		{
+			name: "not a pg error",
+			err:  errors.New("connection reset"),
+			code: schema.Unknown,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got := dbErr(tc.err)
+			if got.Code != tc.code {
+				t.Fatalf("expected %s, got %s", tc.code, got.Code)
+			}
+			if len(got.Details) != len(tc.details) {
+				t.Fatalf("expected details %v, got %v", tc.details, got.Details)
+			}
+			for k, v := range tc.details {
+				if got.Details[k] != v {
+					t.Fatalf("expected details %v, got %v", tc.details, got.Details)
+				}
+			}
+			if got.Code == schema.Unknown && !errors.Is(got, tc.err) {
+				t.Fatal("expected unknown errors to keep the driver error as the cause")
+			}
+		})
+	}
+}
+
+func TestUniqueCodes(t *testing.T) {
+	for constraint, code := range uniqueCodes {
+		if got := dbErr(&pgconn.PgError{Code: pgerrcode.UniqueViolation, ConstraintName: constraint}); got.Code != code {
+			t.Errorf("%s: expected %s, got %s", constraint, code, got.Code)
+		}
+	}
+}
+
+func TestOrNotFound(t *testing.T) {
+	nf := prNotFound("pr-1")
+	if got := orNotFound(pgx.ErrNoRows, nf); got != nf {
+		t.Fatalf("expected the not found error, got %+v", got)
+	}
+	if got := orNotFound(&pgconn.PgError{Code: pgerrcode.DeadlockDetected}, nf); got.Code != schema.Conflict {
+		t.Fatalf("expected other errors classified, got %+v", got)
+	}
+}
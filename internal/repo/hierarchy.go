@@ -0,0 +1,58 @@
+package repo
+
+import (
+	"fmt"
+	"slices"
+
+	"plassstic.tech/trainee/avito/internal/schema"
+)
+
+// teamChain lists teamName followed by its ancestors, nearest first.
+func teamChain(parents map[string]string, teamName string) []string {
+	chain := []string{teamName}
+	for parent := parents[teamName]; parent != "" && !slices.Contains(chain, parent); parent = parents[parent] {
+		chain = append(chain, parent)
+	}
+	return chain
+}
+
+// checkParent rejects putting teamName under parentTeam when parentTeam is
+// teamName itself or one of its descendants.
+func checkParent(parents map[string]string, teamName, parentTeam string) *schema.Err {
+	if parentTeam == "" || !slices.Contains(teamChain(parents, parentTeam), teamName) {
+		return nil
+	}
+	return schema.Err{}.Wrap(schema.TeamCycle, fmt.Errorf("team %s cannot be put under %s, that would make a cycle", teamName, parentTeam)).
+		With("team_name", teamName).
+		With("parent_team", parentTeam)
+}
+
+type candidatesFunc func(teamName string, exclude []string) ([]string, *schema.Err)
+
+// fillCandidates collects up to n candidates from the first team of chain and
+// walks up to its parents while that falls short.
+func fillCandidates(chain, exclude []string, n int, candidates candidatesFunc) (picked []string, err *schema.Err) {
+	for _, team := range chain {
+		if len(picked) >= n {
+			break
+		}
+		var found []string
+		if found, err = candidates(team, append(slices.Clone(exclude), picked...)); err != nil {
+			return nil, err
+		}
+		picked = append(picked, found[:min(len(found), n-len(picked))]...)
+	}
+	return
+}
+
+// nearestCandidates returns the candidates of the first team in chain that
+// has any.
+func nearestCandidates(chain, exclude []string, candidates candidatesFunc) ([]string, *schema.Err) {
+	for _, team := range chain {
+		found, err := candidates(team, exclude)
+		if err != nil || len(found) > 0 {
+			return found, err
+		}
+	}
+	return nil, nil
+}
@@ -0,0 +1,59 @@
+package repo
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"plassstic.tech/trainee/avito/gensql"
+	"plassstic.tech/trainee/avito/internal/schema"
+)
+
+func (r repository) CreateAPIKey(ctx context.Context, keyID string, hash []byte, userID string, role schema.Role) (key *schema.APIKey, err *schema.Err) {
+	if userID != "" {
+		b, lerr := r.qs.CheckUserExists(ctx, userID)
+		if lerr != nil {
+			err = dbErr(lerr)
+			return
+		} else if !b {
+			err = userNotFound(userID)
+			return
+		}
+	}
+
+	k, lerr := r.qs.CreateAPIKey(ctx, gensql.CreateAPIKeyParams{
+		KeyID:   keyID,
+		KeyHash: hash,
+		UserID:  pgtype.Text{String: userID, Valid: userID != ""},
+		Role:    gensql.ApiRole(role),
+	})
+	if lerr != nil {
+		err = dbErr(lerr)
+		return
+	}
+
+	key = schema.APIKey{}.FromDDL(k)
+	return
+}
+
+func (r repository) GetAPIKey(ctx context.Context, keyID string) (key *schema.APIKey, err *schema.Err) {
+	k, lerr := r.qs.GetAPIKey(ctx, keyID)
+	if lerr != nil {
+		err = orNotFound(lerr, schema.Err{}.Wrap(schema.NotFound, fmt.Errorf("api key %s not found", keyID)).With("key_id", keyID))
+		return
+	}
+
+	key = schema.APIKey{}.FromDDL(k)
+	return
+}
+
+func (r repository) RevokeAPIKey(ctx context.Context, keyID string) (key *schema.APIKey, err *schema.Err) {
+	k, lerr := r.qs.RevokeAPIKey(ctx, keyID)
+	if lerr != nil {
+		err = orNotFound(lerr, schema.Err{}.Wrap(schema.NotFound, fmt.Errorf("active api key %s not found", keyID)).With("key_id", keyID))
+		return
+	}
+
+	key = schema.APIKey{}.FromDDL(k)
+	return
+}
@@ -0,0 +1,791 @@
+package repo
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"math/rand"
+	"slices"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"plassstic.tech/trainee/avito/gensql"
+	"plassstic.tech/trainee/avito/internal/schema"
+)
+
+const maxReviewers = 2
+
+var errTxDone = errors.New("transaction already committed or rolled back")
+
+type memUser struct {
+	gensql.User
+	// teams is ordered the way GetUserTeams orders them, primary first. It is
+	// shared between transaction copies, so it is replaced, never modified.
+	teams []string
+}
+
+func (u memUser) primary() string {
+	if len(u.teams) == 0 {
+		return ""
+	}
+	return u.teams[0]
+}
+
+func (u memUser) user() *schema.User {
+	user := schema.User{}.FromDDL(u.User)
+	user.TeamName = u.primary()
+	user.Teams = append([]string{}, u.teams...)
+	return &user
+}
+
+// memTeams orders teams primary first and the rest by name. Without a
+// primary the first by name takes its place, like PromotePrimaryTeams does.
+func memTeams(primary string, teams []string) []string {
+	rest := slices.DeleteFunc(slices.Clone(teams), func(team string) bool { return team == primary })
+	slices.Sort(rest)
+	rest = slices.Compact(rest)
+	if primary == "" {
+		return rest
+	}
+	return append([]string{primary}, rest...)
+}
+
+type memPR struct {
+	gensql.PullRequest
+	reviewers []string
+}
+
+type memState struct {
+	// teams maps every team to its parent, empty for top-level teams
+	teams map[string]string
+	// deletedTeams does the same for deleted teams, which PRs may still name
+	deletedTeams map[string]string
+	users        map[string]memUser
+	prs          map[string]memPR
+	keys         map[string]gensql.ApiKey
+}
+
+func (s memState) clone() memState {
+	prs := make(map[string]memPR, len(s.prs))
+	for id, pr := range s.prs {
+		pr.reviewers = slices.Clone(pr.reviewers)
+		prs[id] = pr
+	}
+	return memState{
+		teams:        maps.Clone(s.teams),
+		deletedTeams: maps.Clone(s.deletedTeams),
+		users:        maps.Clone(s.users),
+		prs:          prs,
+		keys:         maps.Clone(s.keys),
+	}
+}
+
+// memStore keeps everything in process. Transactions are serialized: Begin
+// takes the store for the duration of the transaction and works on a copy
+// that Commit publishes and Rollback drops.
+type memStore struct {
+	sem   chan struct{}
+	state memState
+}
+
+type memTx struct {
+	store *memStore
+	st    memState
+	done  bool
+}
+
+var (
+	_ Store      = (*memStore)(nil)
+	_ Repository = (*memTx)(nil)
+)
+
+func NewMemory() Store {
+	return &memStore{
+		sem: make(chan struct{}, 1),
+		state: memState{
+			teams:        map[string]string{},
+			deletedTeams: map[string]string{},
+			users:        map[string]memUser{},
+			prs:          map[string]memPR{},
+			keys:         map[string]gensql.ApiKey{},
+		},
+	}
+}
+
+func (s *memStore) Begin(ctx context.Context) (Tx, error) {
+	select {
+	case s.sem <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	return &memTx{store: s, st: s.state.clone()}, nil
+}
+
+func (t *memTx) Commit(context.Context) error {
+	if t.done {
+		return errTxDone
+	}
+	t.done = true
+	t.store.state = t.st
+	<-t.store.sem
+	return nil
+}
+
+func (t *memTx) Rollback(context.Context) error {
+	if t.done {
+		return errTxDone
+	}
+	t.done = true
+	<-t.store.sem
+	return nil
+}
+
+func now() pgtype.Timestamp {
+	return pgtype.Timestamp{Time: time.Now().UTC().Truncate(time.Microsecond), Valid: true}
+}
+
+func (t *memTx) members(teamName string) []schema.TeamMember {
+	members := []schema.TeamMember{}
+	for _, id := range slices.Sorted(maps.Keys(t.st.users)) {
+		if u := t.st.users[id]; slices.Contains(u.teams, teamName) {
+			m := schema.TeamMember{}.FromDDL(u.User)
+			m.IsPrimary = u.primary() == teamName
+			members = append(members, m)
+		}
+	}
+	return members
+}
+
+// user looks up a user that has not been deleted.
+func (t *memTx) user(userID string) (memUser, bool) {
+	u, ok := t.st.users[userID]
+	return u, ok && !u.DeletedAt.Valid
+}
+
+// parents includes deleted teams, so PRs of a deleted team still find
+// reviewers above it.
+func (t *memTx) parents() map[string]string {
+	parents := maps.Clone(t.st.teams)
+	maps.Copy(parents, t.st.deletedTeams)
+	return parents
+}
+
+func (t *memTx) candidates(teamName string, exclude []string) ([]string, *schema.Err) {
+	return t.activeTeammates(teamName, exclude), nil
+}
+
+func (t *memTx) activeTeammates(teamName string, exclude []string) []string {
+	var candidates []string
+	for _, id := range slices.Sorted(maps.Keys(t.st.users)) {
+		u := t.st.users[id]
+		if slices.Contains(u.teams, teamName) && u.IsActive && !slices.Contains(exclude, id) {
+			candidates = append(candidates, id)
+		}
+	}
+	return candidates
+}
+
+// addReviewer mirrors the reviewers_to_pull_requests keys and the
+// reviewersconstr trigger.
+func (t *memTx) addReviewer(prID, userID string) *schema.Err {
+	pr, ok := t.st.prs[prID]
+	if !ok {
+		return prNotFound(prID).With("constraint", "reviewers_to_pull_requests_pull_req_id_fkey")
+	}
+	if _, ok = t.st.users[userID]; !ok {
+		return userNotFound(userID).With("constraint", "reviewers_to_pull_requests_user_id_fkey")
+	}
+	if slices.Contains(pr.reviewers, userID) {
+		return schema.Err{}.Wrap(schema.Conflict, fmt.Errorf("user %s already reviews PR %s", userID, prID)).
+			With("constraint", "reviewers_to_pull_requests_pkey")
+	}
+	if len(pr.reviewers) >= maxReviewers {
+		return schema.Err{}.Wrap(schema.TooManyReviewers, fmt.Errorf("reviewers count for pull request %s already eq to %d", prID, maxReviewers))
+	}
+
+	pr.reviewers = append(pr.reviewers, userID)
+	t.st.prs[prID] = pr
+	return nil
+}
+
+func (t *memTx) withReviewers(pr memPR) *schema.PullRequest {
+	return schema.PullRequest{}.FromDDL(pr.PullRequest, slices.Clone(pr.reviewers))
+}
+
+func (t *memTx) AddTeamWithMembers(ctx context.Context, team schema.Team) (*schema.Team, *schema.Err) {
+	if _, ok := t.st.teams[team.TeamName]; ok {
+		return nil, schema.Err{}.Wrap(schema.TeamExists, fmt.Errorf("team %s already exists", team.TeamName)).With("team_name", team.TeamName)
+	}
+	// the row of a deleted team is still there until it is purged
+	if _, ok := t.st.deletedTeams[team.TeamName]; ok {
+		return nil, schema.Err{}.Wrap(schema.TeamExists, fmt.Errorf("team %s already exists", team.TeamName)).With("constraint", "teams_pkey")
+	}
+
+	if _, ok := t.st.teams[team.ParentTeam]; team.ParentTeam != "" && !ok {
+		return nil, teamNotFound(team.ParentTeam)
+	}
+	if err := t.checkNotDeleted(team.Members); err != nil {
+		return nil, err
+	}
+
+	t.st.teams[team.TeamName] = team.ParentTeam
+	t.join(team.TeamName, team.Members, nil)
+	return t.GetTeamWithMembers(ctx, team.TeamName)
+}
+
+// checkNotDeleted rejects members that were soft-deleted.
+func (t *memTx) checkNotDeleted(members []schema.TeamMember) *schema.Err {
+	for _, m := range members {
+		if u, ok := t.st.users[m.UserID]; ok && u.DeletedAt.Valid {
+			return userDeleted(m.UserID)
+		}
+	}
+	return nil
+}
+
+// join upserts members and adds them to teamName, which becomes their primary
+// team when they ask for it or have none yet. Users listed in joining are only
+// created, an existing one keeps their name and activity.
+func (t *memTx) join(teamName string, members []schema.TeamMember, joining map[string]bool) {
+	for _, m := range members {
+		u, ok := t.st.users[m.UserID]
+		if !ok || !joining[m.UserID] {
+			u.User = gensql.User{UserID: m.UserID, UserName: m.UserName, IsActive: m.IsActive}
+		}
+
+		primary := u.primary()
+		if m.IsPrimary || primary == "" {
+			primary = teamName
+		}
+		u.teams = memTeams(primary, append(slices.Clone(u.teams), teamName))
+		t.st.users[m.UserID] = u
+	}
+}
+
+// leave drops the user's membership in teamName, promoting another team if
+// it was the primary one.
+func (t *memTx) leave(u memUser, teamName string) memUser {
+	primary := u.primary()
+	if primary == teamName {
+		primary = ""
+	}
+	u.teams = memTeams(primary, slices.DeleteFunc(slices.Clone(u.teams), func(team string) bool { return team == teamName }))
+	return u
+}
+
+func (t *memTx) GetTeamWithMembers(_ context.Context, teamName string) (*schema.Team, *schema.Err) {
+	parent, ok := t.st.teams[teamName]
+	if !ok {
+		return nil, teamNotFound(teamName)
+	}
+	return &schema.Team{TeamName: teamName, ParentTeam: parent, Members: t.members(teamName)}, nil
+}
+
+func (t *memTx) SetTeamParent(ctx context.Context, teamName, parentTeam string) (*schema.Team, *schema.Err) {
+	if _, ok := t.st.teams[teamName]; !ok {
+		return nil, teamNotFound(teamName)
+	}
+	if _, ok := t.st.teams[parentTeam]; parentTeam != "" && !ok {
+		return nil, teamNotFound(parentTeam)
+	}
+	if err := checkParent(t.st.teams, teamName, parentTeam); err != nil {
+		return nil, err
+	}
+
+	t.st.teams[teamName] = parentTeam
+	return t.GetTeamWithMembers(ctx, teamName)
+}
+
+func (t *memTx) ListSubTeams(_ context.Context, teamName string) ([]string, *schema.Err) {
+	teams := []string{}
+	for _, name := range slices.Sorted(maps.Keys(t.st.teams)) {
+		if t.st.teams[name] == teamName {
+			teams = append(teams, name)
+		}
+	}
+	return teams, nil
+}
+
+func (t *memTx) AddTeamMembers(ctx context.Context, teamName string, members []schema.TeamMember) (*schema.Team, *schema.Err) {
+	if _, ok := t.st.teams[teamName]; !ok {
+		return nil, teamNotFound(teamName)
+	}
+	if err := t.checkNotDeleted(members); err != nil {
+		return nil, err
+	}
+
+	// users from other teams join as they are, only the team's own members
+	// are updated
+	joining := map[string]bool{}
+	for _, m := range members {
+		joining[m.UserID] = !slices.Contains(t.st.users[m.UserID].teams, teamName)
+	}
+	t.join(teamName, members, joining)
+	return t.GetTeamWithMembers(ctx, teamName)
+}
+
+func (t *memTx) RemoveTeamMembers(ctx context.Context, teamName string, userIDs []string) (*schema.Team, *schema.Err) {
+	if _, ok := t.st.teams[teamName]; !ok {
+		return nil, teamNotFound(teamName)
+	}
+	for _, id := range userIDs {
+		u, ok := t.st.users[id]
+		if !ok || !slices.Contains(u.teams, teamName) {
+			return nil, notMember(id, teamName)
+		}
+		t.st.users[id] = t.leave(u, teamName)
+	}
+	return t.GetTeamWithMembers(ctx, teamName)
+}
+
+func (t *memTx) MoveTeamMember(_ context.Context, userID, fromTeam, teamName string) (*schema.User, *schema.Err) {
+	if _, ok := t.st.teams[teamName]; !ok {
+		return nil, teamNotFound(teamName)
+	}
+	u, ok := t.user(userID)
+	if !ok {
+		return nil, userNotFound(userID)
+	}
+
+	primary := u.primary()
+	if fromTeam == "" {
+		fromTeam = primary
+	} else if !slices.Contains(u.teams, fromTeam) {
+		return nil, notMember(userID, fromTeam)
+	}
+
+	if fromTeam != "" && fromTeam != teamName {
+		u = t.leave(u, fromTeam)
+		t.st.users[userID] = u
+	}
+	t.join(teamName, []schema.TeamMember{{
+		UserID:    u.UserID,
+		UserName:  u.UserName,
+		IsActive:  u.IsActive,
+		IsPrimary: fromTeam == primary,
+	}}, nil)
+	return t.st.users[userID].user(), nil
+}
+
+func (t *memTx) DeleteTeam(ctx context.Context, teamName string) (*schema.Team, *schema.Err) {
+	team, err := t.GetTeamWithMembers(ctx, teamName)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, parents := range []map[string]string{t.st.teams, t.st.deletedTeams} {
+		for name, parent := range parents {
+			if parent == teamName {
+				parents[name] = team.ParentTeam
+			}
+		}
+	}
+	for _, m := range team.Members {
+		t.st.users[m.UserID] = t.leave(t.st.users[m.UserID], teamName)
+	}
+
+	delete(t.st.teams, teamName)
+	t.st.deletedTeams[teamName] = team.ParentTeam
+	return team, nil
+}
+
+func (t *memTx) PurgeTeam(ctx context.Context, teamName string) (*schema.Team, *schema.Err) {
+	team, err := t.DeleteTeam(ctx, teamName)
+	if err != nil && err.Code != schema.NotFound {
+		return nil, err
+	}
+
+	parent, ok := t.st.deletedTeams[teamName]
+	if !ok {
+		return nil, teamNotFound(teamName)
+	}
+	if team == nil {
+		team = &schema.Team{TeamName: teamName, ParentTeam: parent, Members: []schema.TeamMember{}}
+	}
+
+	delete(t.st.deletedTeams, teamName)
+	for _, parents := range []map[string]string{t.st.teams, t.st.deletedTeams} {
+		for name, p := range parents {
+			if p == teamName {
+				parents[name] = ""
+			}
+		}
+	}
+	for id, pr := range t.st.prs {
+		if pr.TeamName.String == teamName {
+			pr.TeamName = pgtype.Text{}
+			t.st.prs[id] = pr
+		}
+	}
+	return team, nil
+}
+
+func (t *memTx) DeleteUser(_ context.Context, userID string) (*schema.User, *schema.Err) {
+	u, ok := t.user(userID)
+	if !ok {
+		return nil, userNotFound(userID)
+	}
+	user := u.user()
+
+	for id, pr := range t.st.prs {
+		if pr.PullReqStatus == gensql.PrstatOpen && slices.Contains(pr.reviewers, userID) {
+			pr.reviewers = slices.DeleteFunc(pr.reviewers, func(r string) bool { return r == userID })
+			pr.Version++
+			t.st.prs[id] = pr
+		}
+	}
+	for id, key := range t.st.keys {
+		if key.UserID.String == userID && !key.RevokedAt.Valid {
+			key.RevokedAt = pgtype.Timestamp{Time: time.Now().UTC(), Valid: true}
+			t.st.keys[id] = key
+		}
+	}
+
+	u.teams = nil
+	u.IsActive = false
+	u.DeletedAt = pgtype.Timestamp{Time: time.Now().UTC(), Valid: true}
+	t.st.users[userID] = u
+	return user, nil
+}
+
+func (t *memTx) PurgeUser(ctx context.Context, userID string) (*schema.User, *schema.Err) {
+	user, err := t.DeleteUser(ctx, userID)
+	if err != nil && err.Code != schema.NotFound {
+		return nil, err
+	}
+
+	u, ok := t.st.users[userID]
+	if !ok {
+		return nil, userNotFound(userID)
+	}
+	if user == nil {
+		user = u.user()
+	}
+
+	delete(t.st.users, userID)
+	for id, pr := range t.st.prs {
+		if pr.AuthorID.String == userID {
+			pr.AuthorID = pgtype.Text{}
+		}
+		pr.reviewers = slices.DeleteFunc(pr.reviewers, func(r string) bool { return r == userID })
+		t.st.prs[id] = pr
+	}
+	for id, key := range t.st.keys {
+		if key.UserID.String == userID {
+			delete(t.st.keys, id)
+		}
+	}
+	return user, nil
+}
+
+func (t *memTx) SetUserActive(_ context.Context, userID string, isActive bool) (*schema.User, *schema.Err) {
+	u, ok := t.user(userID)
+	if !ok {
+		return nil, userNotFound(userID)
+	}
+
+	u.IsActive = isActive
+	t.st.users[userID] = u
+	return u.user(), nil
+}
+
+func (t *memTx) CreatePR(_ context.Context, prc schema.PullReqCreate) (*schema.PullRequest, *schema.Err) {
+	if _, ok := t.st.prs[prc.PRId]; ok {
+		return nil, schema.Err{}.Wrap(schema.PRExists, fmt.Errorf("PR %s already exists", prc.PRId)).With("pull_request_id", prc.PRId)
+	}
+	author, ok := t.user(prc.AuthorID)
+	if !ok {
+		return nil, userNotFound(prc.AuthorID)
+	}
+	team, err := prTeam(prc, author.teams)
+	if err != nil {
+		return nil, err
+	}
+
+	pr := memPR{PullRequest: gensql.PullRequest{
+		PullReqID:     prc.PRId,
+		PullReqName:   prc.Name,
+		AuthorID:      pgtype.Text{String: prc.AuthorID, Valid: true},
+		PullReqStatus: gensql.PrstatOpen,
+		CreatedAt:     now(),
+		Version:       1,
+		TeamName:      pgtype.Text{String: team, Valid: team != ""},
+	}}
+	t.st.prs[prc.PRId] = pr
+	return schema.PullRequest{}.FromDDL(pr.PullRequest, nil), nil
+}
+
+func (t *memTx) CreatePRBatch(ctx context.Context, prs []schema.PullReqCreate) ([]schema.PRBatchItem, *schema.Err) {
+	plan := newBatchPlan()
+	for _, prc := range prs {
+		if _, ok := t.st.prs[prc.PRId]; ok {
+			plan.existing[prc.PRId] = true
+		}
+		if u, ok := t.user(prc.AuthorID); ok {
+			plan.teams[prc.AuthorID] = u.teams
+		}
+	}
+	plan.parents = t.parents()
+	for _, team := range plan.teamNames() {
+		plan.members[team] = t.activeTeammates(team, nil)
+	}
+	stats, _ := t.GetStats(ctx)
+	plan.load = stats.ReviewerLoad
+
+	items := plan.run(prs)
+	for i, item := range items {
+		if item.Status != schema.BatchCreated {
+			continue
+		}
+		pr, err := t.CreatePR(ctx, schema.PullReqCreate{PRId: item.PRId, Name: item.PR.Name, AuthorID: item.PR.AuthorId, TeamName: item.PR.TeamName})
+		if err != nil {
+			failItem(&items[i], err)
+			continue
+		}
+		for _, id := range item.PR.AssignedReviewers {
+			if err = t.addReviewer(item.PRId, id); err != nil {
+				break
+			}
+		}
+		if err != nil {
+			delete(t.st.prs, item.PRId)
+			failItem(&items[i], err)
+			continue
+		}
+		pr.AssignedReviewers = item.PR.AssignedReviewers
+		items[i].PR = pr
+	}
+	return items, nil
+}
+
+func (t *memTx) MergePR(_ context.Context, prID string, ifMatch *schema.Precondition) (*schema.PullRequest, *schema.Err) {
+	pr, ok := t.st.prs[prID]
+	if !ok {
+		return nil, prNotFound(prID)
+	}
+	if err := ifMatch.Check(prID, pr.Version); err != nil {
+		return nil, err
+	}
+
+	if pr.PullReqStatus == gensql.PrstatOpen {
+		pr.PullReqStatus = gensql.PrstatMerged
+		pr.MergedAt = now()
+		pr.Version++
+		t.st.prs[prID] = pr
+	}
+	return t.withReviewers(pr), nil
+}
+
+func (t *memTx) ReassignReviewer(_ context.Context, prID, oldUserID string, ifMatch *schema.Precondition) (string, *schema.PullRequest, *schema.Err) {
+	pr, ok := t.st.prs[prID]
+	if !ok {
+		return "", nil, prNotFound(prID)
+	}
+	if err := ifMatch.Check(prID, pr.Version); err != nil {
+		return "", nil, err
+	}
+	if !slices.Contains(pr.reviewers, oldUserID) {
+		return "", nil, schema.Err{}.Wrap(schema.NotAssigned, fmt.Errorf("user %s is not assigned to PR %s", oldUserID, prID)).
+			With("pull_request_id", prID).
+			With("user_id", oldUserID)
+	}
+
+	if pr.PullReqStatus == gensql.PrstatMerged {
+		return "", nil, schema.Err{}.Wrap(schema.PRMerged, fmt.Errorf("cannot reassign on merged PR")).With("pull_request_id", prID)
+	}
+
+	team := pr.TeamName.String
+	if !pr.TeamName.Valid {
+		old, ok := t.st.users[oldUserID]
+		if !ok || len(old.teams) == 0 {
+			return "", nil, userNotFound(oldUserID)
+		}
+		team = old.primary()
+	}
+
+	candidates, _ := nearestCandidates(teamChain(t.parents(), team), append([]string{pr.AuthorID.String}, pr.reviewers...), t.candidates)
+	if len(candidates) == 0 {
+		return "", nil, schema.Err{}.Wrap(schema.NoCandidate, fmt.Errorf("no active replacement candidate in team")).
+			With("pull_request_id", prID).
+			With("team_name", team)
+	}
+
+	newUserID := candidates[rand.Intn(len(candidates))]
+	pr.reviewers = slices.DeleteFunc(pr.reviewers, func(id string) bool { return id == oldUserID })
+	pr.Version++
+	t.st.prs[prID] = pr
+	if err := t.addReviewer(prID, newUserID); err != nil {
+		return "", nil, err
+	}
+
+	return newUserID, t.withReviewers(t.st.prs[prID]), nil
+}
+
+func (t *memTx) GetUserReviews(_ context.Context, userID string) ([]schema.PullRequestShort, *schema.Err) {
+	if _, ok := t.user(userID); !ok {
+		return nil, userNotFound(userID)
+	}
+
+	prs := []schema.PullRequestShort{}
+	for _, id := range slices.Sorted(maps.Keys(t.st.prs)) {
+		pr := t.st.prs[id]
+		if slices.Contains(pr.reviewers, userID) {
+			prs = append(prs, t.withReviewers(pr).PullRequestShort)
+		}
+	}
+	return prs, nil
+}
+
+func (t *memTx) GetPR(_ context.Context, prID string) (*schema.PullRequest, *schema.Err) {
+	pr, ok := t.st.prs[prID]
+	if !ok {
+		return nil, prNotFound(prID)
+	}
+	return t.withReviewers(pr), nil
+}
+
+func (t *memTx) GetUser(_ context.Context, userID string) (*schema.User, *schema.Err) {
+	u, ok := t.user(userID)
+	if !ok {
+		return nil, userNotFound(userID)
+	}
+	return u.user(), nil
+}
+
+func (t *memTx) GetStats(context.Context) (*schema.Stats, *schema.Err) {
+	stats := &schema.Stats{ReviewerLoad: map[string]int64{}}
+	for _, pr := range t.st.prs {
+		if pr.PullReqStatus != gensql.PrstatOpen {
+			continue
+		}
+		stats.OpenPRs++
+		for _, id := range pr.reviewers {
+			stats.ReviewerLoad[id]++
+		}
+	}
+	return stats, nil
+}
+
+func (t *memTx) GetReviewersForPR(_ context.Context, prID string) ([]string, *schema.Err) {
+	return slices.Clone(t.st.prs[prID].reviewers), nil
+}
+
+func (t *memTx) AssignReviewersToPR(_ context.Context, prID, authorID string) ([]string, *schema.Err) {
+	pr, ok := t.st.prs[prID]
+	if !ok {
+		return nil, prNotFound(prID)
+	} else if !pr.TeamName.Valid {
+		return nil, noTeam(authorID)
+	}
+
+	reviewers, _ := fillCandidates(teamChain(t.parents(), pr.TeamName.String), []string{authorID}, maxReviewers, t.candidates)
+	for _, id := range reviewers {
+		if err := t.addReviewer(prID, id); err != nil {
+			return nil, err
+		}
+	}
+	return reviewers, nil
+}
+
+func (t *memTx) CreateAPIKey(_ context.Context, keyID string, hash []byte, userID string, role schema.Role) (*schema.APIKey, *schema.Err) {
+	if userID != "" {
+		if _, ok := t.user(userID); !ok {
+			return nil, userNotFound(userID)
+		}
+	}
+	if _, ok := t.st.keys[keyID]; ok {
+		return nil, schema.Err{}.Wrap(schema.Conflict, fmt.Errorf("Key (key_id)=(%s) already exists.", keyID)).
+			With("constraint", "api_keys_pkey")
+	}
+
+	k := gensql.ApiKey{
+		KeyID:     keyID,
+		KeyHash:   slices.Clone(hash),
+		UserID:    pgtype.Text{String: userID, Valid: userID != ""},
+		Role:      gensql.ApiRole(role),
+		CreatedAt: now(),
+	}
+	t.st.keys[keyID] = k
+	return schema.APIKey{}.FromDDL(k), nil
+}
+
+func (t *memTx) GetAPIKey(_ context.Context, keyID string) (*schema.APIKey, *schema.Err) {
+	k, ok := t.st.keys[keyID]
+	if !ok {
+		return nil, schema.Err{}.Wrap(schema.NotFound, fmt.Errorf("api key %s not found", keyID)).With("key_id", keyID)
+	}
+	return schema.APIKey{}.FromDDL(k), nil
+}
+
+func (t *memTx) RevokeAPIKey(_ context.Context, keyID string) (*schema.APIKey, *schema.Err) {
+	k, ok := t.st.keys[keyID]
+	if !ok || k.RevokedAt.Valid {
+		return nil, schema.Err{}.Wrap(schema.NotFound, fmt.Errorf("active api key %s not found", keyID)).With("key_id", keyID)
+	}
+
+	k.RevokedAt = now()
+	t.st.keys[keyID] = k
+	return schema.APIKey{}.FromDDL(k), nil
+}
+
+func (t *memTx) ListTeams(ctx context.Context) ([]schema.Team, *schema.Err) {
+	teams := make([]schema.Team, 0, len(t.st.teams))
+	for _, name := range slices.Sorted(maps.Keys(t.st.teams)) {
+		team, err := t.GetTeamWithMembers(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		teams = append(teams, *team)
+	}
+	return teams, nil
+}
+
+func (t *memTx) ListPRs(context.Context) ([]schema.PullRequest, *schema.Err) {
+	prs := slices.SortedFunc(maps.Values(t.st.prs), func(a, b memPR) int {
+		if c := a.CreatedAt.Time.Compare(b.CreatedAt.Time); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.PullReqID, b.PullReqID)
+	})
+
+	res := make([]schema.PullRequest, 0, len(prs))
+	for _, pr := range prs {
+		res = append(res, *t.withReviewers(pr))
+	}
+	return res, nil
+}
+
+func (t *memTx) ImportPR(_ context.Context, pr schema.PullRequest) *schema.Err {
+	params, lerr := pr.ImportSchema()
+	if lerr != nil {
+		return schema.Err{}.Wrap(schema.ValidationFailed, lerr).With("pull_request_id", pr.PRId)
+	}
+	if _, ok := t.st.prs[pr.PRId]; ok {
+		return schema.Err{}.Wrap(schema.PRExists, fmt.Errorf("PR %s already exists", pr.PRId)).With("pull_request_id", pr.PRId)
+	}
+	if _, ok := t.st.users[pr.AuthorId]; pr.AuthorId != "" && !ok {
+		return userNotFound(pr.AuthorId).With("constraint", "pull_requests_author_id_fkey")
+	}
+	if _, ok := t.st.teams[pr.TeamName]; pr.TeamName != "" && !ok {
+		return teamNotFound(pr.TeamName).With("constraint", "pull_requests_team_name_fkey")
+	}
+
+	t.st.prs[pr.PRId] = memPR{PullRequest: gensql.PullRequest{
+		PullReqID:     params.PullReqID,
+		PullReqName:   params.PullReqName,
+		AuthorID:      params.AuthorID,
+		PullReqStatus: params.PullReqStatus,
+		CreatedAt:     params.CreatedAt,
+		MergedAt:      params.MergedAt,
+		Version:       params.Version,
+		TeamName:      params.TeamName,
+	}}
+	for _, id := range pr.AssignedReviewers {
+		if err := t.addReviewer(pr.PRId, id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
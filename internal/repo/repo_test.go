@@ -0,0 +1,19 @@
+package repo_test
+
+import (
+	"testing"
+
+	"plassstic.tech/trainee/avito/internal/repo/repotest"
+)
+
+// TestStores runs the conformance suite against every backend, Postgres only
+// when REPOTEST_POSTGRES_URL is set.
+func TestStores(t *testing.T) {
+	repotest.RunAll(t)
+}
+
+// TestReassignConcurrently is most useful under go test -race, which also
+// covers the row locks of the Postgres store when it is configured.
+func TestReassignConcurrently(t *testing.T) {
+	repotest.StressAll(t)
+}
@@ -1,12 +1,16 @@
 package repo
 
 import (
+	"cmp"
 	"context"
+	"errors"
 	"fmt"
 	"math/rand"
+	"slices"
 
 	"github.com/jackc/pgx/v5"
-	"github.com/rs/zerolog/log"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/rs/zerolog"
 	"github.com/samber/lo"
 	"plassstic.tech/trainee/avito/gensql"
 	"plassstic.tech/trainee/avito/internal/schema"
@@ -16,31 +20,60 @@ var _ Repository = repository{}
 
 type repository struct {
 	qs *gensql.Queries
+	tx pgx.Tx
 }
 
 type Repository interface {
 	AddTeamWithMembers(ctx context.Context, team schema.Team) (*schema.Team, *schema.Err)
 	GetTeamWithMembers(ctx context.Context, teamName string) (*schema.Team, *schema.Err)
+	AddTeamMembers(ctx context.Context, teamName string, members []schema.TeamMember) (*schema.Team, *schema.Err)
+	RemoveTeamMembers(ctx context.Context, teamName string, userIDs []string) (*schema.Team, *schema.Err)
+	MoveTeamMember(ctx context.Context, userID, fromTeam, teamName string) (*schema.User, *schema.Err)
+	SetTeamParent(ctx context.Context, teamName, parentTeam string) (*schema.Team, *schema.Err)
+	ListSubTeams(ctx context.Context, teamName string) ([]string, *schema.Err)
+	DeleteTeam(ctx context.Context, teamName string) (*schema.Team, *schema.Err)
+	PurgeTeam(ctx context.Context, teamName string) (*schema.Team, *schema.Err)
+	DeleteUser(ctx context.Context, userID string) (*schema.User, *schema.Err)
+	PurgeUser(ctx context.Context, userID string) (*schema.User, *schema.Err)
 	SetUserActive(ctx context.Context, userID string, isActive bool) (*schema.User, *schema.Err)
 	CreatePR(ctx context.Context, pr schema.PullReqCreate) (*schema.PullRequest, *schema.Err)
-	MergePR(ctx context.Context, prID string) (*schema.PullRequest, *schema.Err)
-	ReassignReviewer(ctx context.Context, prID, oldUserID string) (string, *schema.PullRequest, *schema.Err)
+	CreatePRBatch(ctx context.Context, prs []schema.PullReqCreate) ([]schema.PRBatchItem, *schema.Err)
+	// MergePR and ReassignReviewer check ifMatch, when not nil, against the
+	// version of the locked PR.
+	MergePR(ctx context.Context, prID string, ifMatch *schema.Precondition) (*schema.PullRequest, *schema.Err)
+	ReassignReviewer(ctx context.Context, prID, oldUserID string, ifMatch *schema.Precondition) (string, *schema.PullRequest, *schema.Err)
 	GetUserReviews(ctx context.Context, userID string) ([]schema.PullRequestShort, *schema.Err)
 	GetPR(ctx context.Context, prID string) (*schema.PullRequest, *schema.Err)
+	GetUser(ctx context.Context, userID string) (*schema.User, *schema.Err)
+	GetStats(ctx context.Context) (*schema.Stats, *schema.Err)
+	ListTeams(ctx context.Context) ([]schema.Team, *schema.Err)
+	ListPRs(ctx context.Context) ([]schema.PullRequest, *schema.Err)
+	ImportPR(ctx context.Context, pr schema.PullRequest) *schema.Err
 	GetReviewersForPR(ctx context.Context, prID string) ([]string, *schema.Err)
 	AssignReviewersToPR(ctx context.Context, prID, authorID string) ([]string, *schema.Err)
+	CreateAPIKey(ctx context.Context, keyID string, hash []byte, userID string, role schema.Role) (*schema.APIKey, *schema.Err)
+	GetAPIKey(ctx context.Context, keyID string) (*schema.APIKey, *schema.Err)
+	RevokeAPIKey(ctx context.Context, keyID string) (*schema.APIKey, *schema.Err)
 }
 
 func R(tx pgx.Tx) Repository {
-	return &repository{qs: gensql.New(tx)}
+	return &repository{qs: gensql.New(tx), tx: tx}
 }
 
 func (r repository) AddTeam(ctx context.Context, teamName string) (string, error) {
 	return r.qs.CreateTeam(ctx, teamName)
 }
 
-func (r repository) AddUsersToTeam(ctx context.Context, users []schema.User, teamName string) (err error) {
-	r.qs.EnsureUsers(ctx, lo.Map(users, func(user schema.User, _ int) gensql.EnsureUsersParams {
+// AddUsersToTeam creates or updates the members and adds them to teamName.
+// Users listed in joining are only created, an existing one keeps their name
+// and activity.
+func (r repository) AddUsersToTeam(ctx context.Context, members []schema.TeamMember, teamName string, joining map[string]bool) (err error) {
+	users := lo.Map(members, func(member schema.TeamMember, _ int) schema.User {
+		return schema.User{UserID: member.UserID, UserName: member.UserName, IsActive: member.IsActive}
+	})
+	inserted, upserted := lo.FilterReject(users, func(user schema.User, _ int) bool { return joining[user.UserID] })
+
+	r.qs.EnsureUsers(ctx, lo.Map(upserted, func(user schema.User, _ int) gensql.EnsureUsersParams {
 		return user.EnsureSchema()
 	})).Exec(
 		func(_ int, ierr error) {
@@ -54,6 +87,20 @@ func (r repository) AddUsersToTeam(ctx context.Context, users []schema.User, tea
 		return
 	}
 
+	r.qs.InsertUsers(ctx, lo.Map(inserted, func(user schema.User, _ int) gensql.InsertUsersParams {
+		return user.InsertSchema()
+	})).Exec(
+		func(_ int, ierr error) {
+			if ierr != nil {
+				err = ierr
+				return
+			}
+		},
+	)
+	if err != nil {
+		return
+	}
+
 	r.qs.AddUsersToTeam(ctx, lo.Map(users, func(user schema.User, _ int) gensql.AddUsersToTeamParams {
 		return user.AddToTeamSchema(teamName)
 	})).Exec(
@@ -68,29 +115,41 @@ func (r repository) AddUsersToTeam(ctx context.Context, users []schema.User, tea
 		return
 	}
 
-	return
+	// the primary flag moves in two steps, one_primary_team_per_user is
+	// checked row by row
+	for _, member := range members {
+		if !member.IsPrimary {
+			continue
+		}
+		if err = r.qs.ClearPrimaryTeam(ctx, member.UserID); err != nil {
+			return
+		}
+		if _, err = r.qs.MarkPrimaryTeam(ctx, gensql.MarkPrimaryTeamParams{UserID: member.UserID, TeamName: teamName}); err != nil {
+			return
+		}
+	}
+
+	return r.qs.PromotePrimaryTeams(ctx, lo.Map(users, func(user schema.User, _ int) string { return user.UserID }))
 }
 
 func (r repository) GetTeamWithMembers(ctx context.Context, teamName string) (team *schema.Team, err *schema.Err) {
-	b, lerr := r.qs.CheckTeamExists(ctx, teamName)
-	if !b {
-		err = schema.Err{}.Wrap(schema.NotFound, fmt.Errorf("team does not exist"))
-		return
-	} else if lerr != nil {
-		err = schema.Err{}.Wrap(schema.Unknown, lerr)
+	row, lerr := r.qs.GetTeam(ctx, teamName)
+	if lerr != nil {
+		err = orNotFound(lerr, teamNotFound(teamName))
 		return
 	}
 
 	mbs, lerr := r.qs.GetUsersForTeam(ctx, teamName)
 	if lerr != nil {
-		err = schema.Err{}.Wrap(schema.Unknown, lerr)
+		err = dbErr(lerr)
 		return
 	}
 
 	team = &schema.Team{
-		TeamName: teamName,
-		Members: lo.Map(mbs, func(user gensql.User, _ int) schema.TeamMember {
-			return schema.TeamMember{}.FromDDL(user)
+		TeamName:   teamName,
+		ParentTeam: row.ParentTeam.String,
+		Members: lo.Map(mbs, func(row gensql.GetUsersForTeamRow, _ int) schema.TeamMember {
+			return schema.TeamMember{}.FromRow(row)
 		}),
 	}
 
@@ -101,36 +160,319 @@ func (r repository) AddTeamWithMembers(ctx context.Context, team schema.Team) (r
 	b, lerr := r.qs.CheckTeamExists(ctx, team.TeamName)
 
 	if lerr != nil {
-		err = schema.Err{}.Wrap(schema.Unknown, lerr)
+		err = dbErr(lerr)
 		return
 	} else if b {
-		err = schema.Err{}.Wrap(schema.TeamExists, fmt.Errorf("team %s already exists", team.TeamName))
+		err = schema.Err{}.Wrap(schema.TeamExists, fmt.Errorf("team %s already exists", team.TeamName)).With("team_name", team.TeamName)
+		return
+	}
+
+	if err = r.checkNotDeleted(ctx, team.Members); err != nil {
 		return
 	}
 
 	_, lerr = r.qs.CreateTeam(ctx, team.TeamName)
 
 	if lerr != nil {
-		err = schema.Err{}.Wrap(schema.Unknown, lerr)
+		err = dbErr(lerr)
 		return
 	}
 
-	var users []schema.User
-	for _, member := range team.Members {
-		users = append(users, schema.User{
-			UserID:   member.UserID,
-			UserName: member.UserName,
-			IsActive: member.IsActive,
-		})
+	// a new team has no sub-teams, so no parent can make a cycle
+	if team.ParentTeam != "" {
+		if err = r.checkTeam(ctx, team.ParentTeam); err != nil {
+			return
+		}
+		if _, lerr = r.qs.SetTeamParent(ctx, gensql.SetTeamParentParams{
+			TeamName:   team.TeamName,
+			ParentTeam: pgtype.Text{String: team.ParentTeam, Valid: true},
+		}); lerr != nil {
+			err = dbErr(lerr)
+			return
+		}
+	}
+
+	lerr = r.AddUsersToTeam(ctx, team.Members, team.TeamName, nil)
+	if lerr != nil {
+		err = dbErr(lerr)
+		return
+	}
+
+	return r.GetTeamWithMembers(ctx, team.TeamName)
+}
+
+func (r repository) checkTeam(ctx context.Context, teamName string) *schema.Err {
+	b, lerr := r.qs.CheckTeamExists(ctx, teamName)
+	if lerr != nil {
+		return dbErr(lerr)
+	} else if !b {
+		return teamNotFound(teamName)
+	}
+	return nil
+}
+
+// checkNotDeleted rejects members that were soft-deleted, EnsureUsers would
+// otherwise add memberships to them.
+func (r repository) checkNotDeleted(ctx context.Context, members []schema.TeamMember) *schema.Err {
+	deleted, lerr := r.qs.GetDeletedUsers(ctx, lo.Map(members, func(member schema.TeamMember, _ int) string { return member.UserID }))
+	if lerr != nil {
+		return dbErr(lerr)
+	} else if len(deleted) > 0 {
+		return userDeleted(deleted[0])
+	}
+	return nil
+}
+
+func (r repository) AddTeamMembers(ctx context.Context, teamName string, members []schema.TeamMember) (team *schema.Team, err *schema.Err) {
+	if err = r.checkTeam(ctx, teamName); err != nil {
+		return
+	}
+	if err = r.checkNotDeleted(ctx, members); err != nil {
+		return
+	}
+
+	// users from other teams join as they are, only the team's own members
+	// are updated
+	current, lerr := r.qs.GetUsersForTeam(ctx, teamName)
+	if lerr != nil {
+		err = dbErr(lerr)
+		return
+	}
+	joining := lo.SliceToMap(members, func(member schema.TeamMember) (string, bool) { return member.UserID, true })
+	for _, row := range current {
+		delete(joining, row.UserID)
+	}
+
+	if lerr = r.AddUsersToTeam(ctx, members, teamName, joining); lerr != nil {
+		err = dbErr(lerr)
+		return
+	}
+
+	return r.GetTeamWithMembers(ctx, teamName)
+}
+
+func (r repository) RemoveTeamMembers(ctx context.Context, teamName string, userIDs []string) (team *schema.Team, err *schema.Err) {
+	if err = r.checkTeam(ctx, teamName); err != nil {
+		return
+	}
+
+	for _, userID := range userIDs {
+		n, lerr := r.qs.RemoveUserFromTeam(ctx, gensql.RemoveUserFromTeamParams{UserID: userID, TeamName: teamName})
+		if lerr != nil {
+			err = dbErr(lerr)
+			return
+		} else if n == 0 {
+			err = notMember(userID, teamName)
+			return
+		}
+	}
+
+	if lerr := r.qs.PromotePrimaryTeams(ctx, userIDs); lerr != nil {
+		err = dbErr(lerr)
+		return
+	}
+
+	return r.GetTeamWithMembers(ctx, teamName)
+}
+
+// MoveTeamMember swaps the user's membership in fromTeam, their primary team
+// when empty, for one in teamName. A teamless user simply joins teamName, and
+// the primary flag follows the membership it was on.
+func (r repository) MoveTeamMember(ctx context.Context, userID, fromTeam, teamName string) (user *schema.User, err *schema.Err) {
+	if err = r.checkTeam(ctx, teamName); err != nil {
+		return
+	}
+	if user, err = r.GetUser(ctx, userID); err != nil {
+		return
+	}
+
+	if fromTeam == "" {
+		fromTeam = user.TeamName
+	} else if !slices.Contains(user.Teams, fromTeam) {
+		err = notMember(userID, fromTeam)
+		return
 	}
 
-	lerr = r.AddUsersToTeam(ctx, users, team.TeamName)
+	if fromTeam != "" && fromTeam != teamName {
+		if _, lerr := r.qs.RemoveUserFromTeam(ctx, gensql.RemoveUserFromTeamParams{UserID: userID, TeamName: fromTeam}); lerr != nil {
+			err = dbErr(lerr)
+			return
+		}
+	}
+
+	member := schema.TeamMember{
+		UserID:    user.UserID,
+		UserName:  user.UserName,
+		IsActive:  user.IsActive,
+		IsPrimary: fromTeam == user.TeamName,
+	}
+	if lerr := r.AddUsersToTeam(ctx, []schema.TeamMember{member}, teamName, nil); lerr != nil {
+		err = dbErr(lerr)
+		return
+	}
+
+	return r.GetUser(ctx, userID)
+}
+
+func (r repository) teamParents(ctx context.Context) (map[string]string, *schema.Err) {
+	rows, lerr := r.qs.ListTeamParents(ctx)
 	if lerr != nil {
-		err = schema.Err{}.Wrap(schema.Unknown, lerr)
+		return nil, dbErr(lerr)
+	}
+	return lo.SliceToMap(rows, func(row gensql.ListTeamParentsRow) (string, string) {
+		return row.TeamName, row.ParentTeam.String
+	}), nil
+}
+
+func (r repository) SetTeamParent(ctx context.Context, teamName, parentTeam string) (team *schema.Team, err *schema.Err) {
+	// two concurrent moves could each pass the cycle check and close a cycle
+	// together, so changes to the hierarchy are serialized
+	if lerr := r.qs.LockTeamHierarchy(ctx); lerr != nil {
+		err = dbErr(lerr)
+		return
+	}
+
+	if err = r.checkTeam(ctx, teamName); err != nil {
+		return
+	}
+	if parentTeam != "" {
+		if err = r.checkTeam(ctx, parentTeam); err != nil {
+			return
+		}
+	}
+
+	parents, err := r.teamParents(ctx)
+	if err != nil {
+		return
+	}
+	if err = checkParent(parents, teamName, parentTeam); err != nil {
+		return
+	}
+
+	if _, lerr := r.qs.SetTeamParent(ctx, gensql.SetTeamParentParams{
+		TeamName:   teamName,
+		ParentTeam: pgtype.Text{String: parentTeam, Valid: parentTeam != ""},
+	}); lerr != nil {
+		err = dbErr(lerr)
 		return
 	}
 
-	res = &team
+	return r.GetTeamWithMembers(ctx, teamName)
+}
+
+func (r repository) ListSubTeams(ctx context.Context, teamName string) (teams []string, err *schema.Err) {
+	var lerr error
+	if teams, lerr = r.qs.GetSubTeams(ctx, pgtype.Text{String: teamName, Valid: true}); lerr != nil {
+		err = dbErr(lerr)
+	}
+	return
+}
+
+// DeleteTeam hides the team: its sub-teams move up to its parent and its
+// members leave it. PRs keep pointing at it, so their reviewers are still
+// picked from its parents.
+func (r repository) DeleteTeam(ctx context.Context, teamName string) (team *schema.Team, err *schema.Err) {
+	if lerr := r.qs.LockTeamHierarchy(ctx); lerr != nil {
+		err = dbErr(lerr)
+		return
+	}
+
+	if team, err = r.GetTeamWithMembers(ctx, teamName); err != nil {
+		return
+	}
+
+	if lerr := r.qs.ReparentSubTeams(ctx, gensql.ReparentSubTeamsParams{
+		NewParent: pgtype.Text{String: team.ParentTeam, Valid: team.ParentTeam != ""},
+		OldParent: pgtype.Text{String: teamName, Valid: true},
+	}); lerr != nil {
+		err = dbErr(lerr)
+		return
+	}
+
+	ids, lerr := r.qs.RemoveTeamMemberships(ctx, teamName)
+	if lerr != nil {
+		err = dbErr(lerr)
+		return
+	}
+	if lerr = r.qs.PromotePrimaryTeams(ctx, ids); lerr != nil {
+		err = dbErr(lerr)
+		return
+	}
+
+	if _, lerr = r.qs.SoftDeleteTeam(ctx, teamName); lerr != nil {
+		err = dbErr(lerr)
+	}
+	return
+}
+
+// PurgeTeam removes the team for good, deleting it first if it is still
+// visible. Its PRs are left without a team.
+func (r repository) PurgeTeam(ctx context.Context, teamName string) (team *schema.Team, err *schema.Err) {
+	if team, err = r.DeleteTeam(ctx, teamName); err != nil && err.Code != schema.NotFound {
+		return
+	}
+
+	row, lerr := r.qs.PurgeTeam(ctx, teamName)
+	if lerr != nil {
+		err = orNotFound(lerr, teamNotFound(teamName))
+		return
+	}
+	if team == nil {
+		team = &schema.Team{TeamName: row.TeamName, ParentTeam: row.ParentTeam.String, Members: []schema.TeamMember{}}
+	}
+	err = nil
+	return
+}
+
+// DeleteUser hides the user: they leave their teams, lose their API keys and
+// are dropped from the open PRs they still review. PRs they wrote stay.
+func (r repository) DeleteUser(ctx context.Context, userID string) (user *schema.User, err *schema.Err) {
+	if user, err = r.GetUser(ctx, userID); err != nil {
+		return
+	}
+
+	prIDs, lerr := r.qs.DropOpenReviews(ctx, userID)
+	if lerr != nil {
+		err = dbErr(lerr)
+		return
+	}
+	for _, prID := range prIDs {
+		if _, lerr = r.qs.BumpPRVersion(ctx, prID); lerr != nil {
+			err = dbErr(lerr)
+			return
+		}
+	}
+
+	if lerr = r.qs.RemoveUserMemberships(ctx, userID); lerr != nil {
+		err = dbErr(lerr)
+		return
+	}
+	if lerr = r.qs.RevokeUserAPIKeys(ctx, pgtype.Text{String: userID, Valid: true}); lerr != nil {
+		err = dbErr(lerr)
+		return
+	}
+	if _, lerr = r.qs.SoftDeleteUser(ctx, userID); lerr != nil {
+		err = dbErr(lerr)
+	}
+	return
+}
+
+// PurgeUser removes the user for good, deleting them first if they are still
+// visible. The PRs they wrote are kept without an author.
+func (r repository) PurgeUser(ctx context.Context, userID string) (user *schema.User, err *schema.Err) {
+	if user, err = r.DeleteUser(ctx, userID); err != nil && err.Code != schema.NotFound {
+		return
+	}
+
+	row, lerr := r.qs.PurgeUser(ctx, userID)
+	if lerr != nil {
+		err = orNotFound(lerr, userNotFound(userID))
+		return
+	}
+	if user == nil {
+		user = &schema.User{UserID: row.UserID, UserName: row.UserName, IsActive: row.IsActive, Teams: []string{}}
+	}
+	err = nil
 	return
 }
 
@@ -140,7 +482,7 @@ func (r repository) SetUserActive(ctx context.Context, userID string, isActive b
 		IsActive: isActive,
 	})
 	if lerr != nil {
-		err = schema.Err{}.Wrap(schema.NotFound, fmt.Errorf("user %s not found", userID))
+		err = orNotFound(lerr, userNotFound(userID))
 		return
 	}
 
@@ -149,12 +491,12 @@ func (r repository) SetUserActive(ctx context.Context, userID string, isActive b
 	user.UserName = u.UserName
 	user.IsActive = u.IsActive
 
-	team, lerr := r.qs.GetUserTeam(ctx, userID)
-	if lerr != nil {
-		err = schema.Err{}.Wrap(schema.NotFound, fmt.Errorf("user %s not found", userID))
+	if user.Teams, err = r.getUserTeams(ctx, userID); err != nil {
 		return
 	}
-	user.TeamName = team
+	if len(user.Teams) > 0 {
+		user.TeamName = user.Teams[0]
+	}
 
 	return
 }
@@ -162,31 +504,40 @@ func (r repository) SetUserActive(ctx context.Context, userID string, isActive b
 func (r repository) CreatePR(ctx context.Context, prc schema.PullReqCreate) (res *schema.PullRequest, err *schema.Err) {
 	b, lerr := r.qs.CheckPRExists(ctx, prc.PRId)
 	if lerr != nil {
-		err = schema.Err{}.Wrap(schema.Unknown, lerr)
+		err = dbErr(lerr)
 		return
 	} else if b {
-		err = schema.Err{}.Wrap(schema.PRExists, fmt.Errorf("PR %s already exists", prc.PRId))
+		err = schema.Err{}.Wrap(schema.PRExists, fmt.Errorf("PR %s already exists", prc.PRId)).With("pull_request_id", prc.PRId)
 		return
 	}
 
 	b, lerr = r.qs.CheckUserExists(ctx, prc.AuthorID)
 	if lerr != nil {
-		err = schema.Err{}.Wrap(schema.Unknown, lerr)
+		err = dbErr(lerr)
 		return
 	} else if !b {
-		err = schema.Err{}.Wrap(schema.NotFound, fmt.Errorf("user %s not found", prc.AuthorID))
+		err = userNotFound(prc.AuthorID)
+		return
+	}
+
+	teams, lerr := r.qs.GetUserTeams(ctx, prc.AuthorID)
+	if lerr != nil {
+		err = dbErr(lerr)
+		return
+	}
+	if prc.TeamName, err = prTeam(prc, teams); err != nil {
 		return
 	}
 
 	_, lerr = r.qs.CreatePR(ctx, prc.ToCreateParams())
 	if lerr != nil {
-		err = schema.Err{}.Wrap(schema.Unknown, lerr)
+		err = dbErr(lerr)
 		return
 	}
 
 	pr, lerr := r.qs.GetPR(ctx, prc.PRId)
 	if lerr != nil {
-		err = schema.Err{}.Wrap(schema.Unknown, lerr)
+		err = dbErr(lerr)
 		return
 	}
 
@@ -194,25 +545,173 @@ func (r repository) CreatePR(ctx context.Context, prc schema.PullReqCreate) (res
 	return
 }
 
-func (r repository) MergePR(ctx context.Context, prID string) (res *schema.PullRequest, err *schema.Err) {
-	b, lerr := r.qs.CheckPRExists(ctx, prID)
+func (r repository) CreatePRBatch(ctx context.Context, prs []schema.PullReqCreate) (items []schema.PRBatchItem, err *schema.Err) {
+	plan := newBatchPlan()
+	prIDs, authors := batchKeys(prs)
+
+	existing, lerr := r.qs.GetExistingPRs(ctx, prIDs)
 	if lerr != nil {
-		err = schema.Err{}.Wrap(schema.Unknown, lerr)
+		err = dbErr(lerr)
 		return
-	} else if !b {
-		err = schema.Err{}.Wrap(schema.NotFound, fmt.Errorf("PR %s not found", prID))
+	}
+	for _, id := range existing {
+		plan.existing[id] = true
+	}
+
+	teams, lerr := r.qs.GetTeamsOfUsers(ctx, authors)
+	if lerr != nil {
+		err = dbErr(lerr)
+		return
+	}
+	for _, row := range teams {
+		authorTeams := plan.teams[row.UserID]
+		if row.TeamName.Valid {
+			authorTeams = append(authorTeams, row.TeamName.String)
+		}
+		plan.teams[row.UserID] = authorTeams
+	}
+
+	if plan.parents, err = r.teamParents(ctx); err != nil {
+		return
+	}
+
+	members, lerr := r.qs.GetActiveMembersOfTeams(ctx, plan.teamNames())
+	if lerr != nil {
+		err = dbErr(lerr)
+		return
+	}
+	for _, row := range members {
+		plan.members[row.TeamName] = append(plan.members[row.TeamName], row.UserID)
+	}
+
+	load, lerr := r.qs.GetReviewerLoad(ctx)
+	if lerr != nil {
+		err = dbErr(lerr)
+		return
+	}
+	for _, row := range load {
+		plan.load[row.UserID] = row.OpenReviews
+	}
+
+	items = plan.run(prs)
+	if !slices.ContainsFunc(items, func(item schema.PRBatchItem) bool { return item.Status == schema.BatchCreated }) {
+		return
+	}
+
+	var created []*schema.PRBatchItem
+	for i := range items {
+		if items[i].Status == schema.BatchCreated {
+			created = append(created, &items[i])
+		}
+	}
+
+	// the batch is pipelined, so one failing row fails all of it; it then
+	// goes again item by item and only the failing ones are reported
+	if ok, serr := r.savepoint(ctx, func(qs *gensql.Queries) error { return storeBatch(ctx, qs, created) }); serr != nil || ok {
+		err = serr
+		return
+	}
+	for _, item := range created {
+		var ierr error
+		ok, serr := r.savepoint(ctx, func(qs *gensql.Queries) error {
+			ierr = storeBatch(ctx, qs, []*schema.PRBatchItem{item})
+			return ierr
+		})
+		if serr != nil {
+			err = serr
+			return
+		} else if !ok {
+			failItem(item, dbErr(ierr))
+		}
+	}
+	return
+}
+
+// savepoint runs store under a savepoint and rolls back to it when store
+// fails, reporting whether store went through. err is only set when the
+// savepoint itself fails.
+func (r repository) savepoint(ctx context.Context, store func(qs *gensql.Queries) error) (ok bool, err *schema.Err) {
+	sp, lerr := r.tx.Begin(ctx)
+	if lerr != nil {
+		return false, dbErr(lerr)
+	}
+
+	if store(r.qs.WithTx(sp)) != nil {
+		if lerr = sp.Rollback(ctx); lerr != nil {
+			return false, dbErr(lerr)
+		}
+		return false, nil
+	}
+	if lerr = sp.Commit(ctx); lerr != nil {
+		return false, dbErr(lerr)
+	}
+	return true, nil
+}
+
+// storeBatch inserts planned PRs and their reviewers with two pipelined
+// batches and fills in the stored PRs.
+func storeBatch(ctx context.Context, qs *gensql.Queries, created []*schema.PRBatchItem) (err error) {
+	var (
+		params    []gensql.CreatePRBatchParams
+		reviewers []gensql.AddReviewerBatchParams
+	)
+	for _, item := range created {
+		params = append(params, gensql.CreatePRBatchParams{
+			PullReqID:   item.PR.PRId,
+			PullReqName: item.PR.Name,
+			AuthorID:    pgtype.Text{String: item.PR.AuthorId, Valid: true},
+			TeamName:    pgtype.Text{String: item.PR.TeamName, Valid: item.PR.TeamName != ""},
+		})
+		for _, id := range item.PR.AssignedReviewers {
+			reviewers = append(reviewers, gensql.AddReviewerBatchParams{UserID: id, PullReqID: item.PR.PRId})
+		}
+	}
+
+	stored := make([]gensql.PullRequest, len(created))
+	qs.CreatePRBatch(ctx, params).QueryRow(func(i int, pr gensql.PullRequest, ierr error) {
+		err = cmp.Or(err, ierr)
+		stored[i] = pr
+	})
+	if err != nil {
+		return
+	}
+
+	qs.AddReviewerBatch(ctx, reviewers).Exec(func(_ int, ierr error) {
+		err = cmp.Or(err, ierr)
+	})
+	if err != nil {
+		return
+	}
+
+	for i, item := range created {
+		item.PR = schema.PullRequest{}.FromDDL(stored[i], item.PR.AssignedReviewers)
+	}
+	return
+}
+
+func (r repository) MergePR(ctx context.Context, prID string, ifMatch *schema.Precondition) (res *schema.PullRequest, err *schema.Err) {
+	locked, lerr := r.qs.LockPR(ctx, prID)
+	if errors.Is(lerr, pgx.ErrNoRows) {
+		err = prNotFound(prID)
+		return
+	} else if lerr != nil {
+		err = dbErr(lerr)
+		return
+	}
+
+	if err = ifMatch.Check(prID, locked.Version); err != nil {
 		return
 	}
 
 	merged, lerr := r.qs.MergePR(ctx, prID)
 	if lerr != nil {
-		err = schema.Err{}.Wrap(schema.Unknown, lerr)
+		err = dbErr(lerr)
 		return
 	}
 
 	reviewers, lerr := r.qs.GetReviewersForPR(ctx, prID)
 	if lerr != nil {
-		err = schema.Err{}.Wrap(schema.Unknown, lerr)
+		err = dbErr(lerr)
 		return
 	}
 
@@ -224,16 +723,16 @@ func (r repository) MergePR(ctx context.Context, prID string) (res *schema.PullR
 func (r repository) GetUserReviews(ctx context.Context, userID string) (prs []schema.PullRequestShort, err *schema.Err) {
 	b, lerr := r.qs.CheckUserExists(ctx, userID)
 	if lerr != nil {
-		err = schema.Err{}.Wrap(schema.Unknown, lerr)
+		err = dbErr(lerr)
 		return
 	} else if !b {
-		err = schema.Err{}.Wrap(schema.NotFound, fmt.Errorf("user %s not found", userID))
+		err = userNotFound(userID)
 		return
 	}
 
 	prsDDL, lerr := r.qs.GetPRsReviewedByUser(ctx, userID)
 	if lerr != nil {
-		err = schema.Err{}.Wrap(schema.Unknown, lerr)
+		err = dbErr(lerr)
 		return
 	}
 
@@ -256,11 +755,22 @@ func (r repository) AddReviewersToPR(ctx context.Context, prID string, reviewers
 	return nil
 }
 
-func (r repository) ReassignReviewer(ctx context.Context, prID, oldUserID string) (newUserID string, updatedPR *schema.PullRequest, err *schema.Err) {
+func (r repository) ReassignReviewer(ctx context.Context, prID, oldUserID string, ifMatch *schema.Precondition) (newUserID string, updatedPR *schema.PullRequest, err *schema.Err) {
 	var prRow gensql.GetPRwithReviewersRow
 	var teamName string
 	var candidates []string
 
+	// concurrent reassignments and merges of the same PR queue up on the row
+	// lock, so each one sees the reviewers the previous one committed
+	locked, lerr := r.qs.LockPR(ctx, prID)
+	if lerr != nil {
+		err = orNotFound(lerr, prNotFound(prID))
+		return
+	}
+	if err = ifMatch.Check(prID, locked.Version); err != nil {
+		return
+	}
+
 	if err = r.isReviewerAssigned(ctx, prID, oldUserID); err != nil {
 		return
 	}
@@ -270,15 +780,25 @@ func (r repository) ReassignReviewer(ctx context.Context, prID, oldUserID string
 	}
 
 	if prRow.PullReqStatus == gensql.PrstatMerged {
-		err = schema.Err{}.Wrap(schema.PRMerged, fmt.Errorf("cannot reassign on merged PR"))
+		err = schema.Err{}.Wrap(schema.PRMerged, fmt.Errorf("cannot reassign on merged PR")).With("pull_request_id", prID)
 		return
 	}
 
-	if teamName, err = r.getUserTeam(ctx, oldUserID); err != nil {
-		return
+	// PRs created before teams were recorded fall back to the old
+	// reviewer's primary team
+	teamName = prRow.TeamName.String
+	if !prRow.TeamName.Valid {
+		var teams []string
+		if teams, err = r.getUserTeams(ctx, oldUserID); err != nil {
+			return
+		} else if len(teams) == 0 {
+			err = userNotFound(oldUserID)
+			return
+		}
+		teamName = teams[0]
 	}
 
-	exclude := []string{prRow.AuthorID}
+	exclude := []string{prRow.AuthorID.String}
 	if cst, ok := prRow.AssignedReviewers.([]any); ok && len(cst) > 0 {
 		for _, r := range cst {
 			if sr, ok := r.(string); ok {
@@ -287,12 +807,18 @@ func (r repository) ReassignReviewer(ctx context.Context, prID, oldUserID string
 		}
 	}
 
-	if candidates, err = r.getActiveTeammates(ctx, teamName, exclude); err != nil {
+	parents, err := r.teamParents(ctx)
+	if err != nil {
+		return
+	}
+	if candidates, err = nearestCandidates(teamChain(parents, teamName), exclude, r.activeTeammates(ctx)); err != nil {
 		return
 	}
 
 	if len(candidates) == 0 {
-		err = schema.Err{}.Wrap(schema.NoCandidate, fmt.Errorf("no active replacement candidate in team"))
+		err = schema.Err{}.Wrap(schema.NoCandidate, fmt.Errorf("no active replacement candidate in team")).
+			With("pull_request_id", prID).
+			With("team_name", teamName)
 		return
 	}
 
@@ -302,7 +828,7 @@ func (r repository) ReassignReviewer(ctx context.Context, prID, oldUserID string
 		PullReqID: prID,
 		UserID:    oldUserID,
 	}); lerr != nil {
-		err = schema.Err{}.Wrap(schema.Unknown, lerr)
+		err = dbErr(lerr)
 		return
 	}
 
@@ -310,7 +836,12 @@ func (r repository) ReassignReviewer(ctx context.Context, prID, oldUserID string
 		UserID:    newUserID,
 		PullReqID: prID,
 	}); lerr != nil {
-		err = schema.Err{}.Wrap(schema.Unknown, lerr)
+		err = dbErr(lerr)
+		return
+	}
+
+	if _, lerr := r.qs.BumpPRVersion(ctx, prID); lerr != nil {
+		err = dbErr(lerr)
 		return
 	}
 
@@ -323,17 +854,21 @@ func (r repository) ReassignReviewer(ctx context.Context, prID, oldUserID string
 	return
 }
 
-func (r repository) getUserTeam(ctx context.Context, userID string) (teamName string, err *schema.Err) {
+// getUserTeams lists the user's teams, primary first. A user removed from
+// every team gets an empty list.
+func (r repository) getUserTeams(ctx context.Context, userID string) (teams []string, err *schema.Err) {
 	var lerr error
-	if teamName, lerr = r.qs.GetUserTeam(ctx, userID); lerr != nil {
-		err = schema.Err{}.Wrap(schema.NotFound, fmt.Errorf("user %s not found", userID))
+	if teams, lerr = r.qs.GetUserTeams(ctx, userID); lerr != nil {
+		err = dbErr(lerr)
+	} else if teams == nil {
+		teams = []string{}
 	}
 
-	log.Debug().
+	zerolog.Ctx(ctx).Debug().
 		Any("userid", userID).
-		Any("teamName", teamName).
+		Any("teams", teams).
 		AnErr("err", err).
-		Msg("getUserTeam")
+		Msg("getUserTeams")
 
 	return
 }
@@ -341,10 +876,10 @@ func (r repository) getUserTeam(ctx context.Context, userID string) (teamName st
 func (r repository) getPRWithReviewers(ctx context.Context, prID string) (pr gensql.GetPRwithReviewersRow, err *schema.Err) {
 	var lerr error
 	if pr, lerr = r.qs.GetPRwithReviewers(ctx, prID); lerr != nil {
-		err = schema.Err{}.Wrap(schema.NotFound, fmt.Errorf("PR %s not found", prID))
+		err = orNotFound(lerr, prNotFound(prID))
 	}
 
-	log.Debug().
+	zerolog.Ctx(ctx).Debug().
 		Any("prid", prID).
 		Any("pr", pr).
 		AnErr("err", err).
@@ -360,12 +895,14 @@ func (r repository) isReviewerAssigned(ctx context.Context, prID string, userID
 	})
 
 	if lerr != nil {
-		err = schema.Err{}.Wrap(schema.Unknown, lerr)
+		err = dbErr(lerr)
 	} else if !b {
-		err = schema.Err{}.Wrap(schema.NotAssigned, fmt.Errorf("user %s is not assigned to PR %s", userID, prID))
+		err = schema.Err{}.Wrap(schema.NotAssigned, fmt.Errorf("user %s is not assigned to PR %s", userID, prID)).
+			With("pull_request_id", prID).
+			With("user_id", userID)
 	}
 
-	log.Debug().
+	zerolog.Ctx(ctx).Debug().
 		Any("userid", userID).
 		Any("prID", prID).
 		AnErr("err", err).
@@ -375,27 +912,37 @@ func (r repository) isReviewerAssigned(ctx context.Context, prID string, userID
 }
 
 func (r repository) AssignReviewersToPR(ctx context.Context, prID, authorID string) (reviewers []string, err *schema.Err) {
-	var candidates []string
-	teamName, lerr := r.qs.GetUserTeam(ctx, authorID)
+	pr, lerr := r.qs.GetPR(ctx, prID)
 	if lerr != nil {
-		err = schema.Err{}.Wrap(schema.NotFound, fmt.Errorf("user %s not found", authorID))
+		err = orNotFound(lerr, prNotFound(prID))
+		return
+	} else if !pr.TeamName.Valid {
+		err = noTeam(authorID)
 		return
 	}
 
-	if candidates, err = r.getActiveTeammates(ctx, teamName, []string{authorID}); err != nil {
+	parents, err := r.teamParents(ctx)
+	if err != nil {
+		return
+	}
+	if reviewers, err = fillCandidates(teamChain(parents, pr.TeamName.String), []string{authorID}, maxReviewers, r.activeTeammates(ctx)); err != nil {
 		return
 	}
-
-	reviewers = candidates[:min(len(candidates), 2)]
 
 	if lerr = r.AddReviewersToPR(ctx, prID, reviewers); lerr != nil {
-		err = schema.Err{}.Wrap(schema.Unknown, lerr)
+		err = dbErr(lerr)
 		return
 	}
 
 	return
 }
 
+func (r repository) activeTeammates(ctx context.Context) candidatesFunc {
+	return func(teamName string, exclude []string) ([]string, *schema.Err) {
+		return r.getActiveTeammates(ctx, teamName, exclude)
+	}
+}
+
 func (r repository) getActiveTeammates(ctx context.Context, teamName string, exclude []string) (candidates []string, err *schema.Err) {
 	var lerr error
 
@@ -403,10 +950,10 @@ func (r repository) getActiveTeammates(ctx context.Context, teamName string, exc
 		TeamName: teamName,
 		Column2:  exclude,
 	}); lerr != nil {
-		err = schema.Err{}.Wrap(schema.Unknown, lerr)
+		err = dbErr(lerr)
 	}
 
-	log.Debug().
+	zerolog.Ctx(ctx).Debug().
 		Any("team", teamName).
 		Any("exc", exclude).
 		Any("candidates", candidates).
@@ -419,13 +966,13 @@ func (r repository) getActiveTeammates(ctx context.Context, teamName string, exc
 func (r repository) GetPR(ctx context.Context, prID string) (pr *schema.PullRequest, err *schema.Err) {
 	prDDL, lerr := r.qs.GetPR(ctx, prID)
 	if lerr != nil {
-		err = schema.Err{}.Wrap(schema.NotFound, fmt.Errorf("PR %s not found", prID))
+		err = orNotFound(lerr, prNotFound(prID))
 		return
 	}
 
 	reviewers, lerr := r.qs.GetReviewersForPR(ctx, prID)
 	if lerr != nil {
-		err = schema.Err{}.Wrap(schema.Unknown, lerr)
+		err = dbErr(lerr)
 		return
 	}
 
@@ -433,12 +980,111 @@ func (r repository) GetPR(ctx context.Context, prID string) (pr *schema.PullRequ
 	return
 }
 
+func (r repository) GetUser(ctx context.Context, userID string) (user *schema.User, err *schema.Err) {
+	row, lerr := r.qs.GetUserWithTeam(ctx, userID)
+	if lerr != nil {
+		err = orNotFound(lerr, userNotFound(userID))
+		return
+	}
+
+	user = schema.User{}.FromRowWithTeam(row)
+
+	teams, lerr := r.qs.GetUserTeams(ctx, userID)
+	if lerr != nil {
+		err = dbErr(lerr)
+		return
+	}
+	user.Teams = append(user.Teams, teams...)
+	return
+}
+
 func (r repository) GetReviewersForPR(ctx context.Context, prID string) (reviewers []string, err *schema.Err) {
 	reviewersDDL, lerr := r.qs.GetReviewersForPR(ctx, prID)
 	if lerr != nil {
-		err = schema.Err{}.Wrap(schema.Unknown, lerr)
+		err = dbErr(lerr)
 		return
 	}
 	reviewers = reviewersDDL
 	return
 }
+
+func (r repository) GetStats(ctx context.Context) (stats *schema.Stats, err *schema.Err) {
+	open, lerr := r.qs.CountOpenPRs(ctx)
+	if lerr != nil {
+		err = dbErr(lerr)
+		return
+	}
+
+	load, lerr := r.qs.GetReviewerLoad(ctx)
+	if lerr != nil {
+		err = dbErr(lerr)
+		return
+	}
+
+	stats = &schema.Stats{
+		OpenPRs: open,
+		ReviewerLoad: lo.SliceToMap(load, func(row gensql.GetReviewerLoadRow) (string, int64) {
+			return row.UserID, row.OpenReviews
+		}),
+	}
+	return
+}
+
+func (r repository) ListTeams(ctx context.Context) (teams []schema.Team, err *schema.Err) {
+	names, lerr := r.qs.ListTeams(ctx)
+	if lerr != nil {
+		err = dbErr(lerr)
+		return
+	}
+
+	teams = make([]schema.Team, 0, len(names))
+	for _, name := range names {
+		var team *schema.Team
+		if team, err = r.GetTeamWithMembers(ctx, name); err != nil {
+			return
+		}
+		teams = append(teams, *team)
+	}
+	return
+}
+
+func (r repository) ListPRs(ctx context.Context) (prs []schema.PullRequest, err *schema.Err) {
+	rows, lerr := r.qs.ListPRsWithReviewers(ctx)
+	if lerr != nil {
+		err = dbErr(lerr)
+		return
+	}
+
+	prs = lo.Map(rows, func(row gensql.ListPRsWithReviewersRow, _ int) schema.PullRequest {
+		return *schema.PullRequest{}.FromRowWithRevs(gensql.GetPRwithReviewersRow(row))
+	})
+	return
+}
+
+func (r repository) ImportPR(ctx context.Context, pr schema.PullRequest) (err *schema.Err) {
+	params, lerr := pr.ImportSchema()
+	if lerr != nil {
+		err = schema.Err{}.Wrap(schema.ValidationFailed, lerr).With("pull_request_id", pr.PRId)
+		return
+	}
+
+	b, lerr := r.qs.CheckPRExists(ctx, pr.PRId)
+	if lerr != nil {
+		err = dbErr(lerr)
+		return
+	} else if b {
+		err = schema.Err{}.Wrap(schema.PRExists, fmt.Errorf("PR %s already exists", pr.PRId)).With("pull_request_id", pr.PRId)
+		return
+	}
+
+	if lerr = r.qs.ImportPR(ctx, params); lerr != nil {
+		err = dbErr(lerr)
+		return
+	}
+
+	if lerr = r.AddReviewersToPR(ctx, pr.PRId, pr.AssignedReviewers); lerr != nil {
+		err = dbErr(lerr)
+		return
+	}
+	return
+}
@@ -0,0 +1,114 @@
+package repotest
+
+import (
+	"fmt"
+	"slices"
+	"testing"
+
+	"plassstic.tech/trainee/avito/internal/repo"
+	"plassstic.tech/trainee/avito/internal/schema"
+)
+
+func prBatch(t *testing.T, s repo.Store) {
+	seed(t, s)
+	tx := begin(t, s)
+	createPR(t, tx, "pr-1", "u1")
+
+	items, err := tx.CreatePRBatch(t.Context(), []schema.PullReqCreate{
+		{PRId: "pr-1", Name: "taken", AuthorID: "u1"},
+		{PRId: "pr-2", Name: "fine", AuthorID: "u2"},
+		{PRId: "pr-2", Name: "twice", AuthorID: "u2"},
+		{PRId: "pr-3", Name: "ghost", AuthorID: "u404"},
+		{PRId: "pr-4", Name: "alone", AuthorID: "u5"},
+	})
+	ok(t, err)
+
+	want := []struct {
+		status schema.BatchStatus
+		code   schema.ErrorCode
+	}{
+		{schema.BatchFailed, schema.PRExists},
+		{schema.BatchCreated, ""},
+		{schema.BatchFailed, schema.PRExists},
+		{schema.BatchFailed, schema.NotFound},
+		{schema.BatchCreated, ""},
+	}
+	if len(items) != len(want) {
+		t.Fatalf("expected %d results, got %d", len(want), len(items))
+	}
+	for i, w := range want {
+		item := items[i]
+		if item.Status != w.status || (w.code != "") != (item.Error != nil) || (item.Error != nil && item.Error.Code != w.code) {
+			t.Fatalf("item %d: expected %s %s, got %+v", i, w.status, w.code, item)
+		}
+	}
+
+	if got := items[1].PR; got.Version != 1 || !sameSet(got.AssignedReviewers, []string{"u1", "u3"}) {
+		t.Fatalf("unexpected pr-2 %+v", got)
+	}
+	if got := items[4].PR; len(got.AssignedReviewers) != 0 {
+		t.Fatalf("lonely author got reviewers %v", got.AssignedReviewers)
+	}
+
+	stored, err := tx.GetPR(t.Context(), "pr-2")
+	ok(t, err)
+	if !sameSet(stored.AssignedReviewers, items[1].PR.AssignedReviewers) {
+		t.Fatalf("stored reviewers %v differ from reported %v", stored.AssignedReviewers, items[1].PR.AssignedReviewers)
+	}
+}
+
+// prBatchSpread checks that a batch spreads reviews over the whole team
+// instead of handing all of them to the same two people.
+func prBatchSpread(t *testing.T, s repo.Store) {
+	members := make([]schema.TeamMember, 6)
+	for i := range members {
+		members[i] = schema.TeamMember{UserID: fmt.Sprintf("w%d", i), UserName: fmt.Sprintf("Wide %d", i), IsActive: true}
+	}
+	tx := begin(t, s)
+	_, err := tx.AddTeamWithMembers(t.Context(), schema.Team{TeamName: "wide", Members: members})
+	ok(t, err)
+	createPR(t, tx, "pr-0", "w0")
+
+	batch := make([]schema.PullReqCreate, 6)
+	for i := range batch {
+		batch[i] = schema.PullReqCreate{PRId: fmt.Sprintf("pr-%d", i+1), Name: "spread", AuthorID: "w0"}
+	}
+	_, err = tx.CreatePRBatch(t.Context(), batch)
+	ok(t, err)
+
+	stats, err := tx.GetStats(t.Context())
+	ok(t, err)
+	var load []int64
+	for _, m := range members[1:] {
+		load = append(load, stats.ReviewerLoad[m.UserID])
+	}
+	if slices.Max(load)-slices.Min(load) > 1 {
+		t.Fatalf("reviews are not spread evenly: %v", load)
+	}
+}
+
+// prAuthorWithoutTeam checks that a PR by an author who left every team
+// reports that nobody can review it, rather than that the author is unknown.
+func prAuthorWithoutTeam(t *testing.T, s repo.Store) {
+	seed(t, s)
+	tx := begin(t, s)
+	_, err := tx.RemoveTeamMembers(t.Context(), "frontend", []string{"u5"})
+	ok(t, err)
+
+	_, err = tx.CreatePR(t.Context(), schema.PullReqCreate{PRId: "pr-1", Name: "alone", AuthorID: "u5"})
+	ok(t, err)
+	_, err = tx.AssignReviewersToPR(t.Context(), "pr-1", "u5")
+	wantCode(t, err, schema.NoCandidate)
+
+	items, err := tx.CreatePRBatch(t.Context(), []schema.PullReqCreate{
+		{PRId: "pr-2", Name: "alone", AuthorID: "u5"},
+		{PRId: "pr-3", Name: "fine", AuthorID: "u2"},
+	})
+	ok(t, err)
+	if items[0].Status != schema.BatchFailed || items[0].Error == nil || items[0].Error.Code != schema.NoCandidate {
+		t.Fatalf("expected pr-2 to fail with %s, got %+v", schema.NoCandidate, items[0])
+	}
+	if items[1].Status != schema.BatchCreated {
+		t.Fatalf("expected pr-3 created, got %+v", items[1])
+	}
+}
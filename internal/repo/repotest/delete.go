@@ -0,0 +1,195 @@
+package repotest
+
+import (
+	"slices"
+	"testing"
+
+	"plassstic.tech/trainee/avito/gensql"
+	"plassstic.tech/trainee/avito/internal/repo"
+	"plassstic.tech/trainee/avito/internal/schema"
+)
+
+func userDelete(t *testing.T, s repo.Store) {
+	seed(t, s)
+	tx := begin(t, s)
+	ok(t, tx.ImportPR(t.Context(), importable("pr-1", "u1", "u2", "u3")))
+	merged := importable("pr-2", "u2", "u1")
+	merged.Status, merged.MergedAt = gensql.PrstatMerged, "2025-01-02 10:00:00"
+	ok(t, tx.ImportPR(t.Context(), merged))
+	_, err := tx.CreateAPIKey(t.Context(), "k2", []byte("hash"), "u2", schema.RoleUser)
+	ok(t, err)
+
+	user, err := tx.DeleteUser(t.Context(), "u2")
+	ok(t, err)
+	if user.UserID != "u2" || user.TeamName != "backend" {
+		t.Fatalf("unexpected deleted user %+v", user)
+	}
+
+	_, err = tx.GetUser(t.Context(), "u2")
+	wantCode(t, err, schema.NotFound)
+	_, err = tx.SetUserActive(t.Context(), "u2", true)
+	wantCode(t, err, schema.NotFound)
+	team, err := tx.GetTeamWithMembers(t.Context(), "backend")
+	ok(t, err)
+	if slices.Contains(memberIDs(team), "u2") {
+		t.Fatalf("deleted user is still a member: %v", team.Members)
+	}
+
+	// open reviews are dropped, history stays
+	pr, err := tx.GetPR(t.Context(), "pr-1")
+	ok(t, err)
+	if !sameSet(pr.AssignedReviewers, []string{"u3"}) || pr.Version != 2 {
+		t.Fatalf("unexpected pr-1 %+v", pr)
+	}
+	pr, err = tx.GetPR(t.Context(), "pr-2")
+	ok(t, err)
+	if pr.AuthorId != "u2" || !sameSet(pr.AssignedReviewers, []string{"u1"}) {
+		t.Fatalf("unexpected pr-2 %+v", pr)
+	}
+
+	key, err := tx.GetAPIKey(t.Context(), "k2")
+	ok(t, err)
+	if key.RevokedAt == "" {
+		t.Fatalf("key of a deleted user was not revoked")
+	}
+
+	_, err = tx.DeleteUser(t.Context(), "u2")
+	wantCode(t, err, schema.NotFound)
+
+	// the id stays taken until the user is purged
+	_, err = tx.AddTeamMembers(t.Context(), "frontend", []schema.TeamMember{{UserID: "u2", UserName: "Bob", IsActive: true}})
+	wantCode(t, err, schema.Conflict)
+	_, err = tx.AddTeamWithMembers(t.Context(), schema.Team{TeamName: "mobile", Members: []schema.TeamMember{{UserID: "u2", UserName: "Bob"}}})
+	wantCode(t, err, schema.Conflict)
+	_, err = tx.GetUser(t.Context(), "u2")
+	wantCode(t, err, schema.NotFound)
+}
+
+func userPurge(t *testing.T, s repo.Store) {
+	seed(t, s)
+	tx := begin(t, s)
+	ok(t, tx.ImportPR(t.Context(), importable("pr-1", "u1", "u2", "u3")))
+	merged := importable("pr-2", "u5", "u1")
+	merged.Status, merged.MergedAt = gensql.PrstatMerged, "2025-01-02 10:00:00"
+	ok(t, tx.ImportPR(t.Context(), merged))
+
+	user, err := tx.PurgeUser(t.Context(), "u1")
+	ok(t, err)
+	if user.UserName != "Alice" {
+		t.Fatalf("unexpected purged user %+v", user)
+	}
+	pr, err := tx.GetPR(t.Context(), "pr-1")
+	ok(t, err)
+	if pr.AuthorId != "" || !sameSet(pr.AssignedReviewers, []string{"u2", "u3"}) {
+		t.Fatalf("unexpected pr-1 %+v", pr)
+	}
+	pr, err = tx.GetPR(t.Context(), "pr-2")
+	ok(t, err)
+	if len(pr.AssignedReviewers) != 0 {
+		t.Fatalf("purged reviewer is still on pr-2: %v", pr.AssignedReviewers)
+	}
+
+	// a deleted user can be purged later
+	_, err = tx.DeleteUser(t.Context(), "u2")
+	ok(t, err)
+	user, err = tx.PurgeUser(t.Context(), "u2")
+	ok(t, err)
+	if user.UserName != "Bob" {
+		t.Fatalf("unexpected purged user %+v", user)
+	}
+
+	_, err = tx.PurgeUser(t.Context(), "u2")
+	wantCode(t, err, schema.NotFound)
+	_, err = tx.GetUser(t.Context(), "u1")
+	wantCode(t, err, schema.NotFound)
+
+	// a purge frees the id
+	_, err = tx.AddTeamMembers(t.Context(), "frontend", []schema.TeamMember{{UserID: "u2", UserName: "Bob", IsActive: true}})
+	ok(t, err)
+}
+
+func teamDelete(t *testing.T, s repo.Store) {
+	seed(t, s)
+	tx := begin(t, s)
+
+	_, err := tx.AddTeamWithMembers(t.Context(), schema.Team{TeamName: "platform", Members: []schema.TeamMember{
+		{UserID: "p1", UserName: "Peggy", IsActive: true},
+		{UserID: "p2", UserName: "Pat", IsActive: true},
+		{UserID: "p3", UserName: "Paul", IsActive: true},
+	}})
+	ok(t, err)
+	_, err = tx.SetTeamParent(t.Context(), "frontend", "platform")
+	ok(t, err)
+	_, err = tx.AddTeamWithMembers(t.Context(), schema.Team{TeamName: "mobile", ParentTeam: "frontend"})
+	ok(t, err)
+	reviewers := createPR(t, tx, "pr-1", "u5")
+
+	team, err := tx.DeleteTeam(t.Context(), "frontend")
+	ok(t, err)
+	if !slices.Equal(memberIDs(team), []string{"u5"}) {
+		t.Fatalf("unexpected deleted team %+v", team)
+	}
+
+	_, err = tx.GetTeamWithMembers(t.Context(), "frontend")
+	wantCode(t, err, schema.NotFound)
+	teams, err := tx.ListTeams(t.Context())
+	ok(t, err)
+	if len(teams) != 3 {
+		t.Fatalf("expected backend, mobile and platform, got %+v", teams)
+	}
+	subTeams, err := tx.ListSubTeams(t.Context(), "platform")
+	ok(t, err)
+	if !slices.Equal(subTeams, []string{"mobile"}) {
+		t.Fatalf("sub-teams did not move up: %v", subTeams)
+	}
+	user, err := tx.GetUser(t.Context(), "u5")
+	ok(t, err)
+	if len(user.Teams) != 0 {
+		t.Fatalf("u5 is still in %v", user.Teams)
+	}
+
+	// the PR keeps its team and still finds reviewers above it
+	newID, pr, err := tx.ReassignReviewer(t.Context(), "pr-1", reviewers[0], nil)
+	ok(t, err)
+	if pr.TeamName != "frontend" || slices.Contains(reviewers, newID) || !slices.Contains([]string{"p1", "p2", "p3"}, newID) {
+		t.Fatalf("unexpected reassignment to %s in %+v", newID, pr)
+	}
+
+	_, err = tx.DeleteTeam(t.Context(), "frontend")
+	wantCode(t, err, schema.NotFound)
+	_, err = tx.SetTeamParent(t.Context(), "mobile", "frontend")
+	wantCode(t, err, schema.NotFound)
+	_, err = tx.AddTeamWithMembers(t.Context(), schema.Team{TeamName: "frontend"})
+	wantCode(t, err, schema.TeamExists)
+}
+
+func teamPurge(t *testing.T, s repo.Store) {
+	seed(t, s)
+	tx := begin(t, s)
+	createPR(t, tx, "pr-1", "u1")
+
+	team, err := tx.PurgeTeam(t.Context(), "backend")
+	ok(t, err)
+	if len(team.Members) != 4 {
+		t.Fatalf("unexpected purged team %+v", team)
+	}
+	pr, err := tx.GetPR(t.Context(), "pr-1")
+	ok(t, err)
+	if pr.TeamName != "" || pr.AuthorId != "u1" {
+		t.Fatalf("unexpected pr-1 %+v", pr)
+	}
+
+	_, err = tx.DeleteTeam(t.Context(), "frontend")
+	ok(t, err)
+	team, err = tx.PurgeTeam(t.Context(), "frontend")
+	ok(t, err)
+	if team.TeamName != "frontend" {
+		t.Fatalf("unexpected purged team %+v", team)
+	}
+	_, err = tx.PurgeTeam(t.Context(), "frontend")
+	wantCode(t, err, schema.NotFound)
+
+	// purged names are free again
+	_, err = tx.AddTeamWithMembers(t.Context(), schema.Team{TeamName: "backend"})
+	ok(t, err)
+}
@@ -0,0 +1,103 @@
+package repotest
+
+import (
+	"slices"
+	"testing"
+
+	"plassstic.tech/trainee/avito/internal/repo"
+	"plassstic.tech/trainee/avito/internal/schema"
+)
+
+func teamHierarchy(t *testing.T, s repo.Store) {
+	seed(t, s)
+	tx := begin(t, s)
+
+	team, err := tx.SetTeamParent(t.Context(), "frontend", "backend")
+	ok(t, err)
+	if team.ParentTeam != "backend" || len(team.Members) != 1 {
+		t.Fatalf("unexpected team %+v", team)
+	}
+	team, err = tx.AddTeamWithMembers(t.Context(), schema.Team{TeamName: "mobile", ParentTeam: "frontend"})
+	ok(t, err)
+	if team.ParentTeam != "frontend" {
+		t.Fatalf("parent was not set: %+v", team)
+	}
+
+	subTeams, err := tx.ListSubTeams(t.Context(), "backend")
+	ok(t, err)
+	if !slices.Equal(subTeams, []string{"frontend"}) {
+		t.Fatalf("unexpected sub-teams %v", subTeams)
+	}
+	subTeams, err = tx.ListSubTeams(t.Context(), "mobile")
+	ok(t, err)
+	if len(subTeams) != 0 {
+		t.Fatalf("unexpected sub-teams %v", subTeams)
+	}
+
+	_, err = tx.SetTeamParent(t.Context(), "backend", "backend")
+	wantCode(t, err, schema.TeamCycle)
+	_, err = tx.SetTeamParent(t.Context(), "backend", "mobile")
+	wantCode(t, err, schema.TeamCycle)
+
+	team, err = tx.SetTeamParent(t.Context(), "frontend", "")
+	ok(t, err)
+	if team.ParentTeam != "" {
+		t.Fatalf("parent was not cleared: %+v", team)
+	}
+	_, err = tx.SetTeamParent(t.Context(), "backend", "mobile")
+	ok(t, err)
+	team, err = tx.GetTeamWithMembers(t.Context(), "backend")
+	ok(t, err)
+	if team.ParentTeam != "mobile" {
+		t.Fatalf("unexpected parent of backend %q", team.ParentTeam)
+	}
+
+	_, err = tx.SetTeamParent(t.Context(), "nope", "backend")
+	wantCode(t, err, schema.NotFound)
+	_, err = tx.SetTeamParent(t.Context(), "frontend", "nope")
+	wantCode(t, err, schema.NotFound)
+	_, err = tx.AddTeamWithMembers(t.Context(), schema.Team{TeamName: "tablet", ParentTeam: "nope"})
+	wantCode(t, err, schema.NotFound)
+}
+
+// prHierarchy puts frontend, where u5 has nobody to review for them, under a
+// platform team and checks that every way of picking reviewers walks up.
+func prHierarchy(t *testing.T, s repo.Store) {
+	seed(t, s)
+	tx := begin(t, s)
+
+	_, err := tx.AddTeamWithMembers(t.Context(), schema.Team{TeamName: "platform", Members: []schema.TeamMember{
+		{UserID: "p1", UserName: "Peggy", IsActive: true},
+		{UserID: "p2", UserName: "Pat", IsActive: true},
+		{UserID: "p3", UserName: "Paul", IsActive: false},
+	}})
+	ok(t, err)
+	_, err = tx.SetTeamParent(t.Context(), "frontend", "platform")
+	ok(t, err)
+
+	if reviewers := createPR(t, tx, "pr-1", "u5"); !sameSet(reviewers, []string{"p1", "p2"}) {
+		t.Fatalf("expected reviewers from platform, got %v", reviewers)
+	}
+	items, err := tx.CreatePRBatch(t.Context(), []schema.PullReqCreate{{PRId: "pr-2", Name: "batched", AuthorID: "u5"}})
+	ok(t, err)
+	if got := items[0].PR; got == nil || !sameSet(got.AssignedReviewers, []string{"p1", "p2"}) {
+		t.Fatalf("expected batch reviewers from platform, got %+v", items[0])
+	}
+
+	// the PR's own team is still preferred once it has somebody
+	_, err = tx.AddTeamMembers(t.Context(), "frontend", []schema.TeamMember{{UserID: "u6", UserName: "Frank", IsActive: true}})
+	ok(t, err)
+	newID, _, err := tx.ReassignReviewer(t.Context(), "pr-1", "p1", nil)
+	ok(t, err)
+	if newID != "u6" {
+		t.Fatalf("expected u6 from frontend, got %s", newID)
+	}
+	newID, pr, err := tx.ReassignReviewer(t.Context(), "pr-1", "u6", nil)
+	ok(t, err)
+	if newID != "p1" || !sameSet(pr.AssignedReviewers, []string{"p1", "p2"}) {
+		t.Fatalf("expected p1 from platform, got %s in %v", newID, pr.AssignedReviewers)
+	}
+	if reviewers := createPR(t, tx, "pr-3", "u5"); !slices.Contains(reviewers, "u6") || len(reviewers) != 2 {
+		t.Fatalf("expected u6 and one of platform, got %v", reviewers)
+	}
+}
@@ -0,0 +1,208 @@
+package repotest
+
+import (
+	"slices"
+	"testing"
+
+	"plassstic.tech/trainee/avito/internal/repo"
+	"plassstic.tech/trainee/avito/internal/schema"
+)
+
+func memberIDs(team *schema.Team) []string {
+	ids := make([]string, 0, len(team.Members))
+	for _, m := range team.Members {
+		ids = append(ids, m.UserID)
+	}
+	return ids
+}
+
+func teamAddMembers(t *testing.T, s repo.Store) {
+	seed(t, s)
+	tx := begin(t, s)
+
+	team, err := tx.AddTeamMembers(t.Context(), "frontend", []schema.TeamMember{
+		{UserID: "u6", UserName: "Frank", IsActive: true},
+		{UserID: "u5", UserName: "Eve Renamed", IsActive: false},
+	})
+	ok(t, err)
+	if !sameSet(memberIDs(team), []string{"u5", "u6"}) {
+		t.Fatalf("unexpected members %v", team.Members)
+	}
+	user, err := tx.GetUser(t.Context(), "u5")
+	ok(t, err)
+	if user.UserName != "Eve Renamed" || user.IsActive {
+		t.Fatalf("existing member was not updated: %+v", user)
+	}
+
+	// joining another team keeps the primary one unless asked otherwise
+	_, err = tx.AddTeamMembers(t.Context(), "frontend", []schema.TeamMember{{UserID: "u1", UserName: "Alice", IsActive: true}})
+	ok(t, err)
+	user, err = tx.GetUser(t.Context(), "u1")
+	ok(t, err)
+	if user.TeamName != "backend" || !slices.Equal(user.Teams, []string{"backend", "frontend"}) {
+		t.Fatalf("unexpected teams of u1: %+v", user)
+	}
+	team, err = tx.AddTeamMembers(t.Context(), "frontend", []schema.TeamMember{{UserID: "u2", UserName: "Bob", IsActive: true, IsPrimary: true}})
+	ok(t, err)
+	user, err = tx.GetUser(t.Context(), "u2")
+	ok(t, err)
+	if user.TeamName != "frontend" || !slices.Equal(user.Teams, []string{"frontend", "backend"}) {
+		t.Fatalf("unexpected teams of u2: %+v", user)
+	}
+	for _, m := range team.Members {
+		if m.IsPrimary != (m.UserID != "u1") {
+			t.Fatalf("member %s has is_primary=%v", m.UserID, m.IsPrimary)
+		}
+	}
+
+	_, err = tx.AddTeamMembers(t.Context(), "nope", []schema.TeamMember{{UserID: "u7", UserName: "Gina"}})
+	wantCode(t, err, schema.NotFound)
+}
+
+func teamRemoveMembers(t *testing.T, s repo.Store) {
+	seed(t, s)
+	tx := begin(t, s)
+
+	team, err := tx.RemoveTeamMembers(t.Context(), "backend", []string{"u3", "u4"})
+	ok(t, err)
+	if !sameSet(memberIDs(team), []string{"u1", "u2"}) {
+		t.Fatalf("unexpected members %v", team.Members)
+	}
+	user, err := tx.GetUser(t.Context(), "u3")
+	ok(t, err)
+	if user.TeamName != "" {
+		t.Fatalf("removed user is still in %s", user.TeamName)
+	}
+
+	_, err = tx.RemoveTeamMembers(t.Context(), "nope", []string{"u1"})
+	wantCode(t, err, schema.NotFound)
+	_, err = tx.RemoveTeamMembers(t.Context(), "backend", []string{"u5"})
+	wantCode(t, err, schema.NotFound)
+}
+
+func teamMoveMember(t *testing.T, s repo.Store) {
+	seed(t, s)
+	tx := begin(t, s)
+	createPR(t, tx, "pr-1", "u1")
+
+	user, err := tx.MoveTeamMember(t.Context(), "u2", "", "frontend")
+	ok(t, err)
+	if user.TeamName != "frontend" {
+		t.Fatalf("expected u2 in frontend, got %+v", user)
+	}
+	frontend, err := tx.GetTeamWithMembers(t.Context(), "frontend")
+	ok(t, err)
+	backend, err := tx.GetTeamWithMembers(t.Context(), "backend")
+	ok(t, err)
+	if !sameSet(memberIDs(frontend), []string{"u2", "u5"}) || !sameSet(memberIDs(backend), []string{"u1", "u3", "u4"}) {
+		t.Fatalf("unexpected teams %v and %v", frontend.Members, backend.Members)
+	}
+
+	// moving keeps reviews, handing them over is up to the service
+	reviews, err := tx.GetUserReviews(t.Context(), "u2")
+	ok(t, err)
+	if len(reviews) != 1 {
+		t.Fatalf("expected u2 to keep reviewing pr-1, got %v", reviews)
+	}
+
+	// a teamless user is simply added
+	_, err = tx.RemoveTeamMembers(t.Context(), "backend", []string{"u3"})
+	ok(t, err)
+	user, err = tx.MoveTeamMember(t.Context(), "u3", "", "frontend")
+	ok(t, err)
+	if user.TeamName != "frontend" {
+		t.Fatalf("expected u3 in frontend, got %+v", user)
+	}
+
+	_, err = tx.MoveTeamMember(t.Context(), "u404", "", "frontend")
+	wantCode(t, err, schema.NotFound)
+	_, err = tx.MoveTeamMember(t.Context(), "u1", "", "nope")
+	wantCode(t, err, schema.NotFound)
+	_, err = tx.MoveTeamMember(t.Context(), "u1", "frontend", "backend")
+	wantCode(t, err, schema.NotFound)
+}
+
+// teamPrimary checks the primary flag moves with a membership and falls back
+// to the first remaining team by name.
+func teamPrimary(t *testing.T, s repo.Store) {
+	seed(t, s)
+	tx := begin(t, s)
+	for _, name := range []string{"mobile", "data"} {
+		_, err := tx.AddTeamWithMembers(t.Context(), schema.Team{TeamName: name, Members: []schema.TeamMember{
+			{UserID: "u1", UserName: "Alice", IsActive: true},
+		}})
+		ok(t, err)
+	}
+
+	// moving a secondary membership leaves the primary team alone
+	user, err := tx.MoveTeamMember(t.Context(), "u1", "mobile", "frontend")
+	ok(t, err)
+	if user.TeamName != "backend" || !slices.Equal(user.Teams, []string{"backend", "data", "frontend"}) {
+		t.Fatalf("unexpected teams after moving out of mobile: %+v", user)
+	}
+
+	// moving the primary one carries the flag over
+	user, err = tx.MoveTeamMember(t.Context(), "u1", "", "mobile")
+	ok(t, err)
+	if user.TeamName != "mobile" || !slices.Equal(user.Teams, []string{"mobile", "data", "frontend"}) {
+		t.Fatalf("unexpected teams after moving out of backend: %+v", user)
+	}
+
+	// removing it promotes the first team left by name
+	_, err = tx.RemoveTeamMembers(t.Context(), "mobile", []string{"u1"})
+	ok(t, err)
+	user, err = tx.GetUser(t.Context(), "u1")
+	ok(t, err)
+	if user.TeamName != "data" || !slices.Equal(user.Teams, []string{"data", "frontend"}) {
+		t.Fatalf("unexpected teams after leaving mobile: %+v", user)
+	}
+}
+
+// prTeam checks a PR belongs to one of its author's teams and takes every
+// reviewer from it.
+func prTeam(t *testing.T, s repo.Store) {
+	seed(t, s)
+	tx := begin(t, s)
+	_, err := tx.AddTeamMembers(t.Context(), "frontend", []schema.TeamMember{
+		{UserID: "u2", UserName: "Bob", IsActive: true},
+		{UserID: "u6", UserName: "Frank", IsActive: true},
+	})
+	ok(t, err)
+
+	createPR(t, tx, "pr-1", "u2")
+	pr, err := tx.GetPR(t.Context(), "pr-1")
+	ok(t, err)
+	if pr.TeamName != "backend" || !sameSet(pr.AssignedReviewers, []string{"u1", "u3"}) {
+		t.Fatalf("expected a backend PR reviewed by u1 and u3, got %+v", pr)
+	}
+
+	pr, err = tx.CreatePR(t.Context(), schema.PullReqCreate{PRId: "pr-2", Name: "restyle", AuthorID: "u2", TeamName: "frontend"})
+	ok(t, err)
+	if pr.TeamName != "frontend" {
+		t.Fatalf("expected a frontend PR, got %+v", pr)
+	}
+	reviewers, err := tx.AssignReviewersToPR(t.Context(), "pr-2", "u2")
+	ok(t, err)
+	if !sameSet(reviewers, []string{"u5", "u6"}) {
+		t.Fatalf("expected frontend reviewers, got %v", reviewers)
+	}
+
+	// backend has free reviewers, but they are not in the PR's team
+	_, _, err = tx.ReassignReviewer(t.Context(), "pr-2", "u5", nil)
+	wantCode(t, err, schema.NoCandidate)
+
+	items, err := tx.CreatePRBatch(t.Context(), []schema.PullReqCreate{
+		{PRId: "pr-3", Name: "restyle more", AuthorID: "u2", TeamName: "frontend"},
+		{PRId: "pr-4", Name: "elsewhere", AuthorID: "u2", TeamName: "mobile"},
+	})
+	ok(t, err)
+	if items[0].Status != schema.BatchCreated || items[0].PR.TeamName != "frontend" || !sameSet(items[0].PR.AssignedReviewers, []string{"u5", "u6"}) {
+		t.Fatalf("unexpected batch item %+v", items[0])
+	}
+	if items[1].Status != schema.BatchFailed || items[1].Error.Code != schema.NotFound {
+		t.Fatalf("expected pr-4 to fail, got %+v", items[1])
+	}
+
+	_, err = tx.CreatePR(t.Context(), schema.PullReqCreate{PRId: "pr-5", Name: "elsewhere", AuthorID: "u2", TeamName: "mobile"})
+	wantCode(t, err, schema.NotFound)
+}
@@ -0,0 +1,62 @@
+package repotest
+
+import (
+	"context"
+	"crypto/rand"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"plassstic.tech/trainee/avito/internal/migrate"
+	"plassstic.tech/trainee/avito/internal/repo"
+	"plassstic.tech/trainee/avito/internal/tracing"
+)
+
+const postgresURLEnv = "REPOTEST_POSTGRES_URL"
+
+// Postgres migrates a scratch schema on the database in REPOTEST_POSTGRES_URL
+// and drops it when the test ends; without the variable the test is skipped.
+func Postgres(t *testing.T) repo.Store {
+	url := os.Getenv(postgresURLEnv)
+	if url == "" {
+		t.Skip(postgresURLEnv + " is not set")
+	}
+	ctx := t.Context()
+
+	cfg, err := pgxpool.ParseConfig(url)
+	if err != nil {
+		t.Fatalf("parse %s: %v", postgresURLEnv, err)
+	}
+
+	conn, err := pgx.ConnectConfig(ctx, cfg.ConnConfig)
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	t.Cleanup(func() { _ = conn.Close(context.Background()) })
+
+	name := "repotest_" + strings.ToLower(rand.Text())
+	if _, err = conn.Exec(ctx, "create schema "+name); err != nil {
+		t.Fatalf("create schema: %v", err)
+	}
+	t.Cleanup(func() { _, _ = conn.Exec(context.Background(), "drop schema "+name+" cascade") })
+
+	cfg.ConnConfig.RuntimeParams["search_path"] = name
+	m, err := migrate.FromConfig(cfg.ConnConfig)
+	if err != nil {
+		t.Fatalf("load migrations: %v", err)
+	}
+	defer m.Close()
+	if err = m.Up(ctx); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+
+	cfg.ConnConfig.Tracer = tracing.PgTracer{}
+	pool, err := pgxpool.NewWithConfig(ctx, cfg)
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	t.Cleanup(pool.Close)
+	return repo.NewPg(pool)
+}
@@ -0,0 +1,555 @@
+// Package repotest is a conformance suite every repo.Store backend must pass.
+//
+//	func TestMemory(t *testing.T) { repotest.Run(t, repotest.Memory) }
+package repotest
+
+import (
+	"context"
+	"os"
+	"slices"
+	"testing"
+
+	"plassstic.tech/trainee/avito/gensql"
+	"plassstic.tech/trainee/avito/internal/repo"
+	"plassstic.tech/trainee/avito/internal/schema"
+)
+
+// Factory returns an empty store, releasing it through t.Cleanup.
+type Factory func(t *testing.T) repo.Store
+
+func Memory(*testing.T) repo.Store {
+	return repo.NewMemory()
+}
+
+// RunAll runs the suite against every backend.
+func RunAll(t *testing.T) {
+	forEachBackend(t, Run)
+}
+
+// StressAll runs Stress against every backend, it is meant for go test -race.
+func StressAll(t *testing.T) {
+	forEachBackend(t, Stress)
+}
+
+// forEachBackend runs fn for the memory and sqlite backends and, when
+// REPOTEST_POSTGRES_URL is set, for Postgres.
+func forEachBackend(t *testing.T, fn func(t *testing.T, newStore Factory)) {
+	t.Run("memory", func(t *testing.T) { fn(t, Memory) })
+	t.Run("sqlite", func(t *testing.T) { fn(t, SQLite) })
+	t.Run("postgres", func(t *testing.T) {
+		if os.Getenv(postgresURLEnv) == "" {
+			t.Skip(postgresURLEnv + " is not set")
+		}
+		fn(t, Postgres)
+	})
+}
+
+var cases = []struct {
+	name string
+	run  func(t *testing.T, s repo.Store)
+}{
+	{"team/create", teamCreate},
+	{"team/exists", teamExists},
+	{"team/member of several teams", teamMemberOfSeveralTeams},
+	{"team/existing users", teamExistingUsers},
+	{"team/not found", teamNotFound},
+	{"team/add members", teamAddMembers},
+	{"team/remove members", teamRemoveMembers},
+	{"team/move member", teamMoveMember},
+	{"team/primary", teamPrimary},
+	{"team/hierarchy", teamHierarchy},
+	{"team/delete", teamDelete},
+	{"team/purge", teamPurge},
+	{"user/set active", userSetActive},
+	{"user/set active without team", userSetActiveWithoutTeam},
+	{"user/not found", userNotFound},
+	{"user/delete", userDelete},
+	{"user/purge", userPurge},
+	{"pr/create", prCreate},
+	{"pr/create conflicts", prCreateConflicts},
+	{"pr/assign reviewers", prAssignReviewers},
+	{"pr/merge", prMerge},
+	{"pr/merge not found", prMergeNotFound},
+	{"pr/version", prVersion},
+	{"pr/batch", prBatch},
+	{"pr/batch spread", prBatchSpread},
+	{"pr/author without team", prAuthorWithoutTeam},
+	{"pr/precondition", prPrecondition},
+	{"pr/team", prTeam},
+	{"pr/hierarchy", prHierarchy},
+	{"reassign/ok", reassignOK},
+	{"reassign/merged", reassignMerged},
+	{"reassign/not assigned", reassignNotAssigned},
+	{"reassign/no candidate", reassignNoCandidate},
+	{"reviewers/limit", reviewersLimit},
+	{"user/reviews", userReviews},
+	{"tx/rollback", txRollback},
+}
+
+func Run(t *testing.T, newStore Factory) {
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			c.run(t, newStore(t))
+		})
+	}
+}
+
+func begin(t *testing.T, s repo.Store) repo.Tx {
+	t.Helper()
+	tx, err := s.Begin(t.Context())
+	if err != nil {
+		t.Fatalf("begin: %v", err)
+	}
+	t.Cleanup(func() { _ = tx.Rollback(context.Background()) })
+	return tx
+}
+
+func commit(t *testing.T, tx repo.Tx) {
+	t.Helper()
+	if err := tx.Commit(t.Context()); err != nil {
+		t.Fatalf("commit: %v", err)
+	}
+}
+
+func ok(t *testing.T, err *schema.Err) {
+	t.Helper()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func wantCode(t *testing.T, err *schema.Err, code schema.ErrorCode) {
+	t.Helper()
+	if err == nil {
+		t.Fatalf("expected %s, got no error", code)
+	}
+	if err.Code != code {
+		t.Fatalf("expected %s, got %s: %v", code, err.Code, err)
+	}
+}
+
+// seed adds backend (u1..u3 active, u4 inactive) and frontend (u5 alone).
+func seed(t *testing.T, s repo.Store) {
+	t.Helper()
+	tx := begin(t, s)
+	for _, team := range []schema.Team{
+		{TeamName: "backend", Members: []schema.TeamMember{
+			{UserID: "u1", UserName: "Alice", IsActive: true},
+			{UserID: "u2", UserName: "Bob", IsActive: true},
+			{UserID: "u3", UserName: "Carol", IsActive: true},
+			{UserID: "u4", UserName: "Dave", IsActive: false},
+		}},
+		{TeamName: "frontend", Members: []schema.TeamMember{
+			{UserID: "u5", UserName: "Eve", IsActive: true},
+		}},
+	} {
+		_, err := tx.AddTeamWithMembers(t.Context(), team)
+		ok(t, err)
+	}
+	commit(t, tx)
+}
+
+// createPR creates a PR and assigns reviewers the way the service does.
+func createPR(t *testing.T, tx repo.Tx, prID, authorID string) []string {
+	t.Helper()
+	_, err := tx.CreatePR(t.Context(), schema.PullReqCreate{PRId: prID, Name: "change " + prID, AuthorID: authorID})
+	ok(t, err)
+	reviewers, err := tx.AssignReviewersToPR(t.Context(), prID, authorID)
+	ok(t, err)
+	return reviewers
+}
+
+func teamCreate(t *testing.T, s repo.Store) {
+	seed(t, s)
+	tx := begin(t, s)
+
+	team, err := tx.GetTeamWithMembers(t.Context(), "backend")
+	ok(t, err)
+	if len(team.Members) != 4 {
+		t.Fatalf("expected 4 members, got %+v", team.Members)
+	}
+	for _, m := range team.Members {
+		if m.IsActive != (m.UserID != "u4") {
+			t.Fatalf("member %s has is_active=%v", m.UserID, m.IsActive)
+		}
+	}
+
+	teams, err := tx.ListTeams(t.Context())
+	ok(t, err)
+	if len(teams) != 2 {
+		t.Fatalf("expected 2 teams, got %d", len(teams))
+	}
+}
+
+func teamExists(t *testing.T, s repo.Store) {
+	seed(t, s)
+	tx := begin(t, s)
+
+	_, err := tx.AddTeamWithMembers(t.Context(), schema.Team{TeamName: "backend"})
+	wantCode(t, err, schema.TeamExists)
+}
+
+func teamMemberOfSeveralTeams(t *testing.T, s repo.Store) {
+	seed(t, s)
+	tx := begin(t, s)
+
+	team, err := tx.AddTeamWithMembers(t.Context(), schema.Team{TeamName: "mobile", Members: []schema.TeamMember{
+		{UserID: "u6", UserName: "Frank", IsActive: true},
+		{UserID: "u1", UserName: "Alice", IsActive: true},
+	}})
+	ok(t, err)
+	for _, m := range team.Members {
+		if m.IsPrimary != (m.UserID == "u6") {
+			t.Fatalf("member %s has is_primary=%v", m.UserID, m.IsPrimary)
+		}
+	}
+
+	user, err := tx.GetUser(t.Context(), "u1")
+	ok(t, err)
+	if user.TeamName != "backend" || !slices.Equal(user.Teams, []string{"backend", "mobile"}) {
+		t.Fatalf("unexpected teams of u1: %+v", user)
+	}
+	backend, err := tx.GetTeamWithMembers(t.Context(), "backend")
+	ok(t, err)
+	if !slices.Contains(memberIDs(backend), "u1") {
+		t.Fatalf("u1 left backend: %v", backend.Members)
+	}
+}
+
+// teamExistingUsers adds existing users to more teams: creating a team
+// updates them, joining one from another team leaves their name and activity
+// alone.
+func teamExistingUsers(t *testing.T, s repo.Store) {
+	seed(t, s)
+	tx := begin(t, s)
+
+	_, err := tx.AddTeamWithMembers(t.Context(), schema.Team{TeamName: "mobile", Members: []schema.TeamMember{
+		{UserID: "u2", UserName: "Robert"},
+	}})
+	ok(t, err)
+	_, err = tx.AddTeamMembers(t.Context(), "frontend", []schema.TeamMember{
+		{UserID: "u3", UserName: "Caroline"},
+		{UserID: "u4", UserName: "David", IsActive: true},
+	})
+	ok(t, err)
+
+	for id, want := range map[string]schema.User{
+		"u2": {UserName: "Robert", IsActive: false},
+		"u3": {UserName: "Carol", IsActive: true},
+		"u4": {UserName: "Dave", IsActive: false},
+	} {
+		user, err := tx.GetUser(t.Context(), id)
+		ok(t, err)
+		if user.UserName != want.UserName || user.IsActive != want.IsActive || len(user.Teams) != 2 {
+			t.Fatalf("unexpected %s: %+v", id, user)
+		}
+	}
+}
+
+func teamNotFound(t *testing.T, s repo.Store) {
+	tx := begin(t, s)
+
+	_, err := tx.GetTeamWithMembers(t.Context(), "nobody")
+	wantCode(t, err, schema.NotFound)
+}
+
+func userSetActive(t *testing.T, s repo.Store) {
+	seed(t, s)
+	tx := begin(t, s)
+
+	user, err := tx.SetUserActive(t.Context(), "u2", false)
+	ok(t, err)
+	if user.IsActive || user.TeamName != "backend" || user.UserName != "Bob" {
+		t.Fatalf("unexpected user %+v", user)
+	}
+
+	user, err = tx.GetUser(t.Context(), "u2")
+	ok(t, err)
+	if user.IsActive {
+		t.Fatalf("u2 is still active")
+	}
+}
+
+// userSetActiveWithoutTeam toggles a user that was removed from every
+// team, which must still be possible.
+func userSetActiveWithoutTeam(t *testing.T, s repo.Store) {
+	seed(t, s)
+	tx := begin(t, s)
+
+	_, err := tx.RemoveTeamMembers(t.Context(), "frontend", []string{"u5"})
+	ok(t, err)
+
+	user, err := tx.SetUserActive(t.Context(), "u5", false)
+	ok(t, err)
+	if user.IsActive || user.TeamName != "" || len(user.Teams) != 0 {
+		t.Fatalf("unexpected user %+v", user)
+	}
+	user, err = tx.SetUserActive(t.Context(), "u5", true)
+	ok(t, err)
+	if !user.IsActive {
+		t.Fatalf("u5 was not activated")
+	}
+}
+
+func userNotFound(t *testing.T, s repo.Store) {
+	seed(t, s)
+	tx := begin(t, s)
+
+	_, err := tx.SetUserActive(t.Context(), "ghost", true)
+	wantCode(t, err, schema.NotFound)
+	_, err = tx.GetUser(t.Context(), "ghost")
+	wantCode(t, err, schema.NotFound)
+	_, err = tx.GetUserReviews(t.Context(), "ghost")
+	wantCode(t, err, schema.NotFound)
+}
+
+func prCreate(t *testing.T, s repo.Store) {
+	seed(t, s)
+	tx := begin(t, s)
+
+	pr, err := tx.CreatePR(t.Context(), schema.PullReqCreate{PRId: "pr-1", Name: "add search", AuthorID: "u1"})
+	ok(t, err)
+	if pr.PRId != "pr-1" || pr.AuthorId != "u1" || pr.Status != gensql.PrstatOpen || pr.CreatedAt == "" || pr.MergedAt != "" {
+		t.Fatalf("unexpected pr %+v", pr)
+	}
+}
+
+func prCreateConflicts(t *testing.T, s repo.Store) {
+	seed(t, s)
+	tx := begin(t, s)
+	createPR(t, tx, "pr-1", "u1")
+
+	_, err := tx.CreatePR(t.Context(), schema.PullReqCreate{PRId: "pr-1", Name: "again", AuthorID: "u2"})
+	wantCode(t, err, schema.PRExists)
+	_, err = tx.CreatePR(t.Context(), schema.PullReqCreate{PRId: "pr-2", Name: "orphan", AuthorID: "ghost"})
+	wantCode(t, err, schema.NotFound)
+}
+
+func prAssignReviewers(t *testing.T, s repo.Store) {
+	seed(t, s)
+	tx := begin(t, s)
+
+	reviewers := createPR(t, tx, "pr-1", "u1")
+	if len(reviewers) != 2 {
+		t.Fatalf("expected 2 reviewers, got %v", reviewers)
+	}
+	for _, r := range reviewers {
+		if r == "u1" || r == "u4" {
+			t.Fatalf("%s must not review pr-1", r)
+		}
+	}
+
+	stored, err := tx.GetReviewersForPR(t.Context(), "pr-1")
+	ok(t, err)
+	if !sameSet(stored, reviewers) {
+		t.Fatalf("stored reviewers %v, assigned %v", stored, reviewers)
+	}
+
+	if reviewers = createPR(t, tx, "pr-2", "u5"); len(reviewers) != 0 {
+		t.Fatalf("lonely author got reviewers %v", reviewers)
+	}
+}
+
+func prMerge(t *testing.T, s repo.Store) {
+	seed(t, s)
+	tx := begin(t, s)
+	reviewers := createPR(t, tx, "pr-1", "u1")
+
+	pr, err := tx.MergePR(t.Context(), "pr-1", nil)
+	ok(t, err)
+	if pr.Status != gensql.PrstatMerged || pr.MergedAt == "" || !sameSet(pr.AssignedReviewers, reviewers) {
+		t.Fatalf("unexpected merged pr %+v", pr)
+	}
+
+	again, err := tx.MergePR(t.Context(), "pr-1", nil)
+	ok(t, err)
+	if again.Status != gensql.PrstatMerged || again.MergedAt != pr.MergedAt {
+		t.Fatalf("merge is not idempotent: %+v then %+v", pr, again)
+	}
+}
+
+func prMergeNotFound(t *testing.T, s repo.Store) {
+	tx := begin(t, s)
+
+	_, err := tx.MergePR(t.Context(), "pr-404", nil)
+	wantCode(t, err, schema.NotFound)
+}
+
+func prVersion(t *testing.T, s repo.Store) {
+	seed(t, s)
+	tx := begin(t, s)
+	ok(t, tx.ImportPR(t.Context(), importable("pr-1", "u1", "u2")))
+
+	want := func(pr *schema.PullRequest, version int64) {
+		t.Helper()
+		if pr.Version != version {
+			t.Fatalf("expected version %d, got %d", version, pr.Version)
+		}
+	}
+
+	pr, err := tx.GetPR(t.Context(), "pr-1")
+	ok(t, err)
+	want(pr, 1)
+
+	_, pr, err = tx.ReassignReviewer(t.Context(), "pr-1", "u2", nil)
+	ok(t, err)
+	want(pr, 2)
+
+	pr, err = tx.MergePR(t.Context(), "pr-1", nil)
+	ok(t, err)
+	want(pr, 3)
+
+	pr, err = tx.MergePR(t.Context(), "pr-1", nil)
+	ok(t, err)
+	want(pr, 3)
+}
+
+func prPrecondition(t *testing.T, s repo.Store) {
+	seed(t, s)
+	tx := begin(t, s)
+	ok(t, tx.ImportPR(t.Context(), importable("pr-1", "u1", "u2")))
+
+	stale := &schema.Precondition{Versions: []int64{0, 2}}
+	_, _, err := tx.ReassignReviewer(t.Context(), "pr-1", "u2", stale)
+	wantCode(t, err, schema.PreconditionFailed)
+	_, err = tx.MergePR(t.Context(), "pr-1", stale)
+	wantCode(t, err, schema.PreconditionFailed)
+
+	current := &schema.Precondition{Versions: []int64{1}}
+	_, pr, err := tx.ReassignReviewer(t.Context(), "pr-1", "u2", current)
+	ok(t, err)
+
+	_, err = tx.MergePR(t.Context(), "pr-1", current)
+	wantCode(t, err, schema.PreconditionFailed)
+	pr, err = tx.MergePR(t.Context(), "pr-1", &schema.Precondition{Versions: []int64{pr.Version}})
+	ok(t, err)
+	if pr.Status != gensql.PrstatMerged {
+		t.Fatalf("expected merged pr, got %+v", pr)
+	}
+
+	_, err = tx.MergePR(t.Context(), "pr-1", &schema.Precondition{Any: true})
+	ok(t, err)
+	_, err = tx.MergePR(t.Context(), "pr-404", stale)
+	wantCode(t, err, schema.NotFound)
+	_, _, err = tx.ReassignReviewer(t.Context(), "pr-404", "u2", stale)
+	wantCode(t, err, schema.NotFound)
+}
+
+func reassignOK(t *testing.T, s repo.Store) {
+	seed(t, s)
+	tx := begin(t, s)
+	ok(t, tx.ImportPR(t.Context(), importable("pr-1", "u1", "u2")))
+
+	newID, pr, err := tx.ReassignReviewer(t.Context(), "pr-1", "u2", nil)
+	ok(t, err)
+	if newID != "u3" {
+		t.Fatalf("expected u3 as the only candidate, got %s", newID)
+	}
+	if !sameSet(pr.AssignedReviewers, []string{"u3"}) {
+		t.Fatalf("unexpected reviewers %v", pr.AssignedReviewers)
+	}
+}
+
+func reassignMerged(t *testing.T, s repo.Store) {
+	seed(t, s)
+	tx := begin(t, s)
+	createPR(t, tx, "pr-1", "u1")
+	pr, err := tx.MergePR(t.Context(), "pr-1", nil)
+	ok(t, err)
+
+	_, _, err = tx.ReassignReviewer(t.Context(), "pr-1", pr.AssignedReviewers[0], nil)
+	wantCode(t, err, schema.PRMerged)
+}
+
+func reassignNotAssigned(t *testing.T, s repo.Store) {
+	seed(t, s)
+	tx := begin(t, s)
+	createPR(t, tx, "pr-1", "u1")
+
+	_, _, err := tx.ReassignReviewer(t.Context(), "pr-1", "u5", nil)
+	wantCode(t, err, schema.NotAssigned)
+	_, _, err = tx.ReassignReviewer(t.Context(), "pr-404", "u2", nil)
+	wantCode(t, err, schema.NotFound)
+}
+
+func reassignNoCandidate(t *testing.T, s repo.Store) {
+	seed(t, s)
+	tx := begin(t, s)
+	reviewers := createPR(t, tx, "pr-1", "u1")
+
+	// u2 and u3 both review and u4 is inactive, nobody is left in backend
+	_, _, err := tx.ReassignReviewer(t.Context(), "pr-1", reviewers[0], nil)
+	wantCode(t, err, schema.NoCandidate)
+}
+
+func reviewersLimit(t *testing.T, s repo.Store) {
+	seed(t, s)
+	tx := begin(t, s)
+
+	err := tx.ImportPR(t.Context(), importable("pr-1", "u1", "u2", "u3", "u5"))
+	wantCode(t, err, schema.TooManyReviewers)
+}
+
+func userReviews(t *testing.T, s repo.Store) {
+	seed(t, s)
+	tx := begin(t, s)
+	ok(t, tx.ImportPR(t.Context(), importable("pr-1", "u1", "u2")))
+	ok(t, tx.ImportPR(t.Context(), importable("pr-2", "u3", "u2")))
+	_, err := tx.MergePR(t.Context(), "pr-2", nil)
+	ok(t, err)
+
+	prs, err := tx.GetUserReviews(t.Context(), "u2")
+	ok(t, err)
+	var ids []string
+	for _, pr := range prs {
+		ids = append(ids, pr.PRId)
+	}
+	if !sameSet(ids, []string{"pr-1", "pr-2"}) {
+		t.Fatalf("unexpected reviews %v", ids)
+	}
+
+	if prs, err = tx.GetUserReviews(t.Context(), "u5"); err != nil || len(prs) != 0 {
+		t.Fatalf("expected no reviews for u5, got %v, %v", prs, err)
+	}
+}
+
+func txRollback(t *testing.T, s repo.Store) {
+	seed(t, s)
+
+	tx := begin(t, s)
+	createPR(t, tx, "pr-1", "u1")
+	_, err := tx.SetUserActive(t.Context(), "u1", false)
+	ok(t, err)
+	if rerr := tx.Rollback(t.Context()); rerr != nil {
+		t.Fatalf("rollback: %v", rerr)
+	}
+
+	tx = begin(t, s)
+	_, err = tx.GetPR(t.Context(), "pr-1")
+	wantCode(t, err, schema.NotFound)
+	user, err := tx.GetUser(t.Context(), "u1")
+	ok(t, err)
+	if !user.IsActive {
+		t.Fatalf("rolled back deactivation is visible")
+	}
+}
+
+func importable(prID, authorID string, reviewers ...string) schema.PullRequest {
+	return schema.PullRequest{
+		PullRequestShort: schema.PullRequestShort{
+			PRId:     prID,
+			Name:     "change " + prID,
+			AuthorId: authorID,
+			Status:   gensql.PrstatOpen,
+		},
+		AssignedReviewers: reviewers,
+		CreatedAt:         "2025-01-01 10:00:00",
+	}
+}
+
+func sameSet(a, b []string) bool {
+	a, b = slices.Clone(a), slices.Clone(b)
+	slices.Sort(a)
+	slices.Sort(b)
+	return slices.Equal(a, b)
+}
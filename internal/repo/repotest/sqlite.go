@@ -0,0 +1,29 @@
+//go:build cgo
+
+package repotest
+
+import (
+	"path/filepath"
+	"testing"
+
+	"plassstic.tech/trainee/avito/internal/migrate"
+	"plassstic.tech/trainee/avito/internal/repo"
+)
+
+// SQLite migrates a fresh database file in the test's temp dir.
+func SQLite(t *testing.T) repo.Store {
+	db, err := repo.OpenSQLite(filepath.Join(t.TempDir(), "repotest.db"))
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+
+	m, err := migrate.NewSQLite(db)
+	if err != nil {
+		t.Fatalf("load migrations: %v", err)
+	}
+	if err = m.Up(t.Context()); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	return repo.NewSQLite(db)
+}
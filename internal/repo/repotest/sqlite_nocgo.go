@@ -0,0 +1,15 @@
+//go:build !cgo
+
+package repotest
+
+import (
+	"testing"
+
+	"plassstic.tech/trainee/avito/internal/repo"
+)
+
+// SQLite skips the test, the backend is not built without cgo.
+func SQLite(t *testing.T) repo.Store {
+	t.Skip("the sqlite backend needs a build with CGO_ENABLED=1")
+	return nil
+}
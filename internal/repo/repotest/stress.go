@@ -0,0 +1,146 @@
+package repotest
+
+import (
+	"fmt"
+	"math/rand"
+	"slices"
+	"sync"
+	"testing"
+
+	"plassstic.tech/trainee/avito/internal/repo"
+	"plassstic.tech/trainee/avito/internal/schema"
+)
+
+const (
+	stressWorkers = 8
+	stressRounds  = 25
+)
+
+// tolerated are the outcomes a reassignment may lose a race with.
+var tolerated = []schema.ErrorCode{schema.NotAssigned, schema.PRMerged, schema.Conflict}
+
+// Stress races reassignments of one PR against each other and against its
+// merge, then checks that the reviewer invariants still hold.
+func Stress(t *testing.T, newStore Factory) {
+	s := newStore(t)
+
+	members := make([]schema.TeamMember, 10)
+	for i := range members {
+		members[i] = schema.TeamMember{UserID: fmt.Sprintf("s%d", i), UserName: fmt.Sprintf("Stress %d", i), IsActive: true}
+	}
+
+	tx := begin(t, s)
+	_, err := tx.AddTeamWithMembers(t.Context(), schema.Team{TeamName: "stress", Members: members})
+	ok(t, err)
+	createPR(t, tx, "pr-stress", "s0")
+	commit(t, tx)
+
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		failures []string
+		merged   *schema.PullRequest
+	)
+	fail := func(format string, args ...any) {
+		mu.Lock()
+		defer mu.Unlock()
+		failures = append(failures, fmt.Sprintf(format, args...))
+	}
+
+	for w := range stressWorkers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for round := range stressRounds {
+				if w == 0 && round == stressRounds/2 {
+					pr, err := inTx(t, s, func(tx repo.Tx) (*schema.PullRequest, *schema.Err) {
+						return tx.MergePR(t.Context(), "pr-stress", nil)
+					})
+					if err != nil {
+						fail("merge: %v", err)
+						continue
+					}
+					mu.Lock()
+					merged = pr
+					mu.Unlock()
+					continue
+				}
+
+				reviewers, err := inTx(t, s, func(tx repo.Tx) ([]string, *schema.Err) {
+					return tx.GetReviewersForPR(t.Context(), "pr-stress")
+				})
+				if err != nil {
+					fail("read reviewers: %v", err)
+					continue
+				}
+				if len(reviewers) == 0 {
+					fail("pr-stress lost its reviewers")
+					continue
+				}
+
+				// the reviewer read above may already be stale, which is the point
+				old := reviewers[rand.Intn(len(reviewers))]
+				pr, err := inTx(t, s, func(tx repo.Tx) (*schema.PullRequest, *schema.Err) {
+					_, pr, err := tx.ReassignReviewer(t.Context(), "pr-stress", old, nil)
+					return pr, err
+				})
+				if err != nil {
+					if !slices.Contains(tolerated, err.Code) {
+						fail("reassign %s: %v", old, err)
+					}
+					continue
+				}
+				if msg := checkReviewers(pr); msg != "" {
+					fail("after reassigning %s: %s", old, msg)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	for _, f := range failures {
+		t.Error(f)
+	}
+
+	tx = begin(t, s)
+	final, err := tx.GetPR(t.Context(), "pr-stress")
+	ok(t, err)
+	if msg := checkReviewers(final); msg != "" {
+		t.Fatalf("final state: %s", msg)
+	}
+	if merged == nil {
+		t.Fatal("the merge never went through")
+	}
+	if !sameSet(final.AssignedReviewers, merged.AssignedReviewers) {
+		t.Fatalf("reviewers changed after merge: %v, merged with %v", final.AssignedReviewers, merged.AssignedReviewers)
+	}
+}
+
+func checkReviewers(pr *schema.PullRequest) string {
+	switch {
+	case len(pr.AssignedReviewers) != 2:
+		return fmt.Sprintf("expected 2 reviewers, got %v", pr.AssignedReviewers)
+	case pr.AssignedReviewers[0] == pr.AssignedReviewers[1]:
+		return fmt.Sprintf("duplicate reviewer %v", pr.AssignedReviewers)
+	case slices.Contains(pr.AssignedReviewers, pr.AuthorId):
+		return fmt.Sprintf("author %s reviews their own PR", pr.AuthorId)
+	}
+	return ""
+}
+
+// inTx runs fn in its own transaction, committing only when it succeeds.
+func inTx[T any](t *testing.T, s repo.Store, fn func(tx repo.Tx) (T, *schema.Err)) (res T, err *schema.Err) {
+	tx, lerr := s.Begin(t.Context())
+	if lerr != nil {
+		return res, schema.Err{}.Wrap(schema.Unknown, lerr)
+	}
+
+	if res, err = fn(tx); err != nil {
+		_ = tx.Rollback(t.Context())
+		return
+	}
+	if lerr = tx.Commit(t.Context()); lerr != nil {
+		err = schema.Err{}.Wrap(schema.Conflict, lerr)
+	}
+	return
+}
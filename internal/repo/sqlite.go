@@ -0,0 +1,1019 @@
+//go:build cgo
+
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math/rand"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/mattn/go-sqlite3"
+	"plassstic.tech/trainee/avito/gensql"
+	"plassstic.tech/trainee/avito/internal/schema"
+)
+
+const sqliteTime = "2006-01-02 15:04:05"
+
+// sqliteConstraints names sqlite's "table.column" unique failures after the
+// matching Postgres constraints so both backends report the same details.
+var sqliteConstraints = map[string]string{
+	"teams.team_name":           "teams_pkey",
+	"pull_requests.pull_req_id": "pull_requests_pkey",
+	"users_to_teams.user_id":    "one_primary_team_per_user",
+	"api_keys.key_id":           "api_keys_pkey",
+	"reviewers_to_pull_requests.user_id, reviewers_to_pull_requests.pull_req_id": "reviewers_to_pull_requests_pkey",
+}
+
+type sqliteStore struct {
+	db *sql.DB
+}
+
+type sqliteTx struct {
+	tx *sql.Tx
+}
+
+var (
+	_ Store      = (*sqliteStore)(nil)
+	_ Repository = (*sqliteTx)(nil)
+)
+
+// OpenSQLite opens the database file at path. It keeps a single connection:
+// sqlite allows one writer at a time anyway, and this way transactions queue
+// up in the pool instead of failing with SQLITE_BUSY.
+func OpenSQLite(path string) (*sql.DB, error) {
+	db, err := sql.Open("sqlite3", "file:"+path+"?_foreign_keys=on&_busy_timeout=5000&_txlock=immediate&_journal_mode=WAL")
+	if err != nil {
+		return nil, err
+	}
+	db.SetMaxOpenConns(1)
+	if err = db.Ping(); err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+	return db, nil
+}
+
+// NewSQLite runs over a database opened by OpenSQLite and migrated with
+// migrate.NewSQLite.
+func NewSQLite(db *sql.DB) Store {
+	return &sqliteStore{db: db}
+}
+
+func (s *sqliteStore) Begin(ctx context.Context) (Tx, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &sqliteTx{tx: tx}, nil
+}
+
+func (t *sqliteTx) Commit(context.Context) error {
+	return t.tx.Commit()
+}
+
+func (t *sqliteTx) Rollback(context.Context) error {
+	return t.tx.Rollback()
+}
+
+// sqliteErr classifies sqlite errors the way dbErr does for Postgres.
+func sqliteErr(err error) *schema.Err {
+	var se sqlite3.Error
+	if !errors.As(err, &se) {
+		return schema.Err{}.Wrap(schema.Unknown, err)
+	}
+
+	switch se.ExtendedCode {
+	case sqlite3.ErrConstraintUnique, sqlite3.ErrConstraintPrimaryKey:
+		constraint := sqliteConstraints[strings.TrimPrefix(se.Error(), "UNIQUE constraint failed: ")]
+		code, ok := uniqueCodes[constraint]
+		if !ok {
+			code = schema.Conflict
+		}
+		return schema.Err{}.Wrap(code, fmt.Errorf("%s", se.Error())).With("constraint", constraint)
+	case sqlite3.ErrConstraintForeignKey:
+		return schema.Err{}.Wrap(schema.NotFound, fmt.Errorf("%s", se.Error()))
+	case sqlite3.ErrConstraintTrigger:
+		switch {
+		case strings.HasPrefix(se.Error(), "reviewers count for pull request"):
+			return schema.Err{}.Wrap(schema.TooManyReviewers, fmt.Errorf("%s", se.Error()))
+		case strings.HasSuffix(se.Error(), "already merged"):
+			return schema.Err{}.Wrap(schema.PRMerged, fmt.Errorf("%s", se.Error()))
+		}
+	}
+	if se.Code == sqlite3.ErrBusy || se.Code == sqlite3.ErrLocked {
+		return schema.Err{}.Wrap(schema.Conflict, fmt.Errorf("concurrent update, retry the request")).
+			With("retryable", true)
+	}
+
+	return schema.Err{}.Wrap(schema.Unknown, err)
+}
+
+func sqliteOrNotFound(err error, nf *schema.Err) *schema.Err {
+	if errors.Is(err, sql.ErrNoRows) {
+		return nf
+	}
+	return sqliteErr(err)
+}
+
+func timestamp(t sql.NullTime) pgtype.Timestamp {
+	return pgtype.Timestamp{Time: t.Time, Valid: t.Valid}
+}
+
+func nullTime(t pgtype.Timestamp) any {
+	if !t.Valid {
+		return nil
+	}
+	return t.Time.UTC().Format(sqliteTime)
+}
+
+func (t *sqliteTx) exists(ctx context.Context, query string, args ...any) (b bool, err *schema.Err) {
+	if lerr := t.tx.QueryRowContext(ctx, "select exists("+query+")", args...).Scan(&b); lerr != nil {
+		err = sqliteErr(lerr)
+	}
+	return
+}
+
+func (t *sqliteTx) strings(ctx context.Context, query string, args ...any) (res []string, err *schema.Err) {
+	rows, lerr := t.tx.QueryContext(ctx, query, args...)
+	if lerr != nil {
+		return nil, sqliteErr(lerr)
+	}
+	defer func() { _ = rows.Close() }()
+
+	for rows.Next() {
+		var s string
+		if lerr = rows.Scan(&s); lerr != nil {
+			return nil, sqliteErr(lerr)
+		}
+		res = append(res, s)
+	}
+	if lerr = rows.Err(); lerr != nil {
+		return nil, sqliteErr(lerr)
+	}
+	return res, nil
+}
+
+// userTeams lists the user's teams, primary first, like GetUserTeams.
+func (t *sqliteTx) userTeams(ctx context.Context, userID string) ([]string, *schema.Err) {
+	return t.strings(ctx, "select team_name from users_to_teams where user_id = ? order by is_primary desc, team_name", userID)
+}
+
+// promote makes the first team by name primary for a user left without one.
+func (t *sqliteTx) promote(ctx context.Context, userID string) *schema.Err {
+	if _, lerr := t.tx.ExecContext(ctx, `
+		update users_to_teams
+		set is_primary = true
+		where user_id = ?1
+		  and team_name = (select min(team_name) from users_to_teams where user_id = ?1)
+		  and not exists(select 1 from users_to_teams where user_id = ?1 and is_primary)`, userID); lerr != nil {
+		return sqliteErr(lerr)
+	}
+	return nil
+}
+
+func (t *sqliteTx) candidates(ctx context.Context) candidatesFunc {
+	return func(teamName string, exclude []string) ([]string, *schema.Err) {
+		return t.activeTeammates(ctx, teamName, exclude)
+	}
+}
+
+func (t *sqliteTx) activeTeammates(ctx context.Context, teamName string, exclude []string) ([]string, *schema.Err) {
+	ids, err := t.strings(ctx, `
+		select ut.user_id
+		from users_to_teams ut
+		inner join users u on u.user_id = ut.user_id
+		where ut.team_name = ? and u.is_active
+		order by ut.user_id`, teamName)
+	if err != nil {
+		return nil, err
+	}
+	return slices.DeleteFunc(ids, func(id string) bool { return slices.Contains(exclude, id) }), nil
+}
+
+func (t *sqliteTx) addReviewer(ctx context.Context, prID, userID string) *schema.Err {
+	if _, lerr := t.tx.ExecContext(ctx, "insert into reviewers_to_pull_requests (user_id, pull_req_id) values (?, ?)", userID, prID); lerr != nil {
+		return sqliteErr(lerr)
+	}
+	return nil
+}
+
+func (t *sqliteTx) pr(ctx context.Context, prID string) (*schema.PullRequest, *schema.Err) {
+	var (
+		pr     gensql.PullRequest
+		merged sql.NullTime
+		team   sql.NullString
+	)
+	if lerr := t.tx.QueryRowContext(ctx, `
+		select pull_req_id, pull_req_name, author_id, pull_req_status, created_at, merged_at, version, team_name
+		from pull_requests
+		where pull_req_id = ?`, prID).Scan(&pr.PullReqID, &pr.PullReqName, &pr.AuthorID, &pr.PullReqStatus, &pr.CreatedAt.Time, &merged, &pr.Version, &team); lerr != nil {
+		return nil, sqliteOrNotFound(lerr, prNotFound(prID))
+	}
+	pr.CreatedAt.Valid = true
+	pr.MergedAt = timestamp(merged)
+	pr.TeamName = pgtype.Text{String: team.String, Valid: team.Valid}
+
+	reviewers, err := t.GetReviewersForPR(ctx, prID)
+	if err != nil {
+		return nil, err
+	}
+	return schema.PullRequest{}.FromDDL(pr, reviewers), nil
+}
+
+// precondition checks ifMatch against the stored version; a missing PR is
+// left for the caller to report.
+func (t *sqliteTx) precondition(ctx context.Context, prID string, ifMatch *schema.Precondition) *schema.Err {
+	if ifMatch == nil {
+		return nil
+	}
+
+	var version int64
+	lerr := t.tx.QueryRowContext(ctx, "select version from pull_requests where pull_req_id = ?", prID).Scan(&version)
+	if errors.Is(lerr, sql.ErrNoRows) {
+		return nil
+	} else if lerr != nil {
+		return sqliteErr(lerr)
+	}
+	return ifMatch.Check(prID, version)
+}
+
+func (t *sqliteTx) AddTeamWithMembers(ctx context.Context, team schema.Team) (*schema.Team, *schema.Err) {
+	b, err := t.exists(ctx, "select 1 from teams where team_name = ?", team.TeamName)
+	if err != nil {
+		return nil, err
+	} else if b {
+		return nil, schema.Err{}.Wrap(schema.TeamExists, fmt.Errorf("team %s already exists", team.TeamName)).With("team_name", team.TeamName)
+	}
+
+	if team.ParentTeam != "" {
+		if err = t.checkTeam(ctx, team.ParentTeam); err != nil {
+			return nil, err
+		}
+	}
+	if _, lerr := t.tx.ExecContext(ctx, "insert into teams (team_name, parent_team) values (?, nullif(?, ''))", team.TeamName, team.ParentTeam); lerr != nil {
+		return nil, sqliteErr(lerr)
+	}
+	if err = t.join(ctx, team.TeamName, team.Members, nil); err != nil {
+		return nil, err
+	}
+	return t.GetTeamWithMembers(ctx, team.TeamName)
+}
+
+// join upserts members and adds them to teamName. Users listed in joining are
+// only created, an existing one keeps their name and activity.
+func (t *sqliteTx) join(ctx context.Context, teamName string, members []schema.TeamMember, joining map[string]bool) *schema.Err {
+	for _, m := range members {
+		deleted, err := t.exists(ctx, "select 1 from users where user_id = ? and deleted_at is not null", m.UserID)
+		if err != nil {
+			return err
+		} else if deleted {
+			return userDeleted(m.UserID)
+		}
+	}
+	for _, m := range members {
+		query := `
+			insert into users (user_id, user_name, is_active)
+			values (?, ?, ?)
+			on conflict (user_id) do update
+			set user_name  = excluded.user_name,
+			    is_active  = excluded.is_active,
+			    deleted_at = null`
+		if joining[m.UserID] {
+			query = `
+			insert into users (user_id, user_name, is_active)
+			values (?, ?, ?)
+			on conflict (user_id) do nothing`
+		}
+		if _, lerr := t.tx.ExecContext(ctx, query, m.UserID, m.UserName, m.IsActive); lerr != nil {
+			return sqliteErr(lerr)
+		}
+	}
+	for _, m := range members {
+		if _, lerr := t.tx.ExecContext(ctx, `
+			insert into users_to_teams (user_id, team_name)
+			values (?, ?)
+			on conflict (user_id, team_name) do nothing`, m.UserID, teamName); lerr != nil {
+			return sqliteErr(lerr)
+		}
+	}
+	for _, m := range members {
+		if m.IsPrimary {
+			if _, lerr := t.tx.ExecContext(ctx, "update users_to_teams set is_primary = false where user_id = ? and is_primary", m.UserID); lerr != nil {
+				return sqliteErr(lerr)
+			}
+			if _, lerr := t.tx.ExecContext(ctx, "update users_to_teams set is_primary = true where user_id = ? and team_name = ?", m.UserID, teamName); lerr != nil {
+				return sqliteErr(lerr)
+			}
+		}
+		if err := t.promote(ctx, m.UserID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (t *sqliteTx) checkTeam(ctx context.Context, teamName string) *schema.Err {
+	b, err := t.exists(ctx, "select 1 from teams where team_name = ? and deleted_at is null", teamName)
+	if err != nil {
+		return err
+	} else if !b {
+		return teamNotFound(teamName)
+	}
+	return nil
+}
+
+func (t *sqliteTx) AddTeamMembers(ctx context.Context, teamName string, members []schema.TeamMember) (*schema.Team, *schema.Err) {
+	if err := t.checkTeam(ctx, teamName); err != nil {
+		return nil, err
+	}
+	// users from other teams join as they are, only the team's own members
+	// are updated
+	joining := map[string]bool{}
+	for _, m := range members {
+		inTeam, err := t.exists(ctx, "select 1 from users_to_teams where user_id = ? and team_name = ?", m.UserID, teamName)
+		if err != nil {
+			return nil, err
+		}
+		joining[m.UserID] = !inTeam
+	}
+	if err := t.join(ctx, teamName, members, joining); err != nil {
+		return nil, err
+	}
+	return t.GetTeamWithMembers(ctx, teamName)
+}
+
+func (t *sqliteTx) RemoveTeamMembers(ctx context.Context, teamName string, userIDs []string) (*schema.Team, *schema.Err) {
+	if err := t.checkTeam(ctx, teamName); err != nil {
+		return nil, err
+	}
+	for _, id := range userIDs {
+		res, lerr := t.tx.ExecContext(ctx, "delete from users_to_teams where user_id = ? and team_name = ?", id, teamName)
+		if lerr != nil {
+			return nil, sqliteErr(lerr)
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			return nil, notMember(id, teamName)
+		}
+		if err := t.promote(ctx, id); err != nil {
+			return nil, err
+		}
+	}
+	return t.GetTeamWithMembers(ctx, teamName)
+}
+
+func (t *sqliteTx) MoveTeamMember(ctx context.Context, userID, fromTeam, teamName string) (*schema.User, *schema.Err) {
+	if err := t.checkTeam(ctx, teamName); err != nil {
+		return nil, err
+	}
+	user, err := t.GetUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if fromTeam == "" {
+		fromTeam = user.TeamName
+	} else if !slices.Contains(user.Teams, fromTeam) {
+		return nil, notMember(userID, fromTeam)
+	}
+
+	if fromTeam != "" && fromTeam != teamName {
+		if _, lerr := t.tx.ExecContext(ctx, "delete from users_to_teams where user_id = ? and team_name = ?", userID, fromTeam); lerr != nil {
+			return nil, sqliteErr(lerr)
+		}
+	}
+	if err = t.join(ctx, teamName, []schema.TeamMember{{
+		UserID:    user.UserID,
+		UserName:  user.UserName,
+		IsActive:  user.IsActive,
+		IsPrimary: fromTeam == user.TeamName,
+	}}, nil); err != nil {
+		return nil, err
+	}
+	return t.GetUser(ctx, userID)
+}
+
+func (t *sqliteTx) GetTeamWithMembers(ctx context.Context, teamName string) (*schema.Team, *schema.Err) {
+	team := &schema.Team{TeamName: teamName, Members: []schema.TeamMember{}}
+	if lerr := t.tx.QueryRowContext(ctx, "select coalesce(parent_team, '') from teams where team_name = ? and deleted_at is null", teamName).Scan(&team.ParentTeam); lerr != nil {
+		return nil, sqliteOrNotFound(lerr, teamNotFound(teamName))
+	}
+
+	rows, lerr := t.tx.QueryContext(ctx, `
+		select u.user_id, u.user_name, u.is_active, ut.is_primary
+		from users_to_teams ut
+		inner join users u using (user_id)
+		where ut.team_name = ?
+		order by u.user_id`, teamName)
+	if lerr != nil {
+		return nil, sqliteErr(lerr)
+	}
+	defer func() { _ = rows.Close() }()
+
+	for rows.Next() {
+		var m schema.TeamMember
+		if lerr = rows.Scan(&m.UserID, &m.UserName, &m.IsActive, &m.IsPrimary); lerr != nil {
+			return nil, sqliteErr(lerr)
+		}
+		team.Members = append(team.Members, m)
+	}
+	if lerr = rows.Err(); lerr != nil {
+		return nil, sqliteErr(lerr)
+	}
+	return team, nil
+}
+
+func (t *sqliteTx) teamParents(ctx context.Context) (map[string]string, *schema.Err) {
+	rows, lerr := t.tx.QueryContext(ctx, "select team_name, parent_team from teams where parent_team is not null")
+	if lerr != nil {
+		return nil, sqliteErr(lerr)
+	}
+	defer func() { _ = rows.Close() }()
+
+	parents := map[string]string{}
+	for rows.Next() {
+		var team, parent string
+		if lerr = rows.Scan(&team, &parent); lerr != nil {
+			return nil, sqliteErr(lerr)
+		}
+		parents[team] = parent
+	}
+	if lerr = rows.Err(); lerr != nil {
+		return nil, sqliteErr(lerr)
+	}
+	return parents, nil
+}
+
+func (t *sqliteTx) SetTeamParent(ctx context.Context, teamName, parentTeam string) (*schema.Team, *schema.Err) {
+	if err := t.checkTeam(ctx, teamName); err != nil {
+		return nil, err
+	}
+	if parentTeam != "" {
+		if err := t.checkTeam(ctx, parentTeam); err != nil {
+			return nil, err
+		}
+	}
+
+	parents, err := t.teamParents(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err = checkParent(parents, teamName, parentTeam); err != nil {
+		return nil, err
+	}
+
+	if _, lerr := t.tx.ExecContext(ctx, "update teams set parent_team = nullif(?, '') where team_name = ?", parentTeam, teamName); lerr != nil {
+		return nil, sqliteErr(lerr)
+	}
+	return t.GetTeamWithMembers(ctx, teamName)
+}
+
+func (t *sqliteTx) ListSubTeams(ctx context.Context, teamName string) ([]string, *schema.Err) {
+	return t.strings(ctx, "select team_name from teams where parent_team = ? and deleted_at is null order by team_name", teamName)
+}
+
+func (t *sqliteTx) DeleteTeam(ctx context.Context, teamName string) (*schema.Team, *schema.Err) {
+	team, err := t.GetTeamWithMembers(ctx, teamName)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, lerr := t.tx.ExecContext(ctx, "update teams set parent_team = nullif(?, '') where parent_team = ?", team.ParentTeam, teamName); lerr != nil {
+		return nil, sqliteErr(lerr)
+	}
+	if _, lerr := t.tx.ExecContext(ctx, "delete from users_to_teams where team_name = ?", teamName); lerr != nil {
+		return nil, sqliteErr(lerr)
+	}
+	for _, m := range team.Members {
+		if err = t.promote(ctx, m.UserID); err != nil {
+			return nil, err
+		}
+	}
+	if _, lerr := t.tx.ExecContext(ctx, "update teams set deleted_at = datetime('now') where team_name = ?", teamName); lerr != nil {
+		return nil, sqliteErr(lerr)
+	}
+	return team, nil
+}
+
+func (t *sqliteTx) PurgeTeam(ctx context.Context, teamName string) (*schema.Team, *schema.Err) {
+	team, err := t.DeleteTeam(ctx, teamName)
+	if err != nil && err.Code != schema.NotFound {
+		return nil, err
+	}
+
+	var parent sql.NullString
+	if lerr := t.tx.QueryRowContext(ctx, "delete from teams where team_name = ? returning parent_team", teamName).Scan(&parent); lerr != nil {
+		return nil, sqliteOrNotFound(lerr, teamNotFound(teamName))
+	}
+	if team == nil {
+		team = &schema.Team{TeamName: teamName, ParentTeam: parent.String, Members: []schema.TeamMember{}}
+	}
+	return team, nil
+}
+
+func (t *sqliteTx) DeleteUser(ctx context.Context, userID string) (*schema.User, *schema.Err) {
+	user, err := t.GetUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	prIDs, err := t.strings(ctx, `
+		select rtp.pull_req_id
+		from reviewers_to_pull_requests rtp
+		inner join pull_requests pr on pr.pull_req_id = rtp.pull_req_id
+		where rtp.user_id = ? and pr.pull_req_status = 'open'`, userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, prID := range prIDs {
+		if _, lerr := t.tx.ExecContext(ctx, "delete from reviewers_to_pull_requests where pull_req_id = ? and user_id = ?", prID, userID); lerr != nil {
+			return nil, sqliteErr(lerr)
+		}
+		if _, lerr := t.tx.ExecContext(ctx, "update pull_requests set version = version + 1 where pull_req_id = ?", prID); lerr != nil {
+			return nil, sqliteErr(lerr)
+		}
+	}
+
+	for _, query := range []string{
+		"delete from users_to_teams where user_id = ?",
+		"update api_keys set revoked_at = datetime('now') where user_id = ? and revoked_at is null",
+		"update users set deleted_at = datetime('now'), is_active = false where user_id = ?",
+	} {
+		if _, lerr := t.tx.ExecContext(ctx, query, userID); lerr != nil {
+			return nil, sqliteErr(lerr)
+		}
+	}
+	return user, nil
+}
+
+func (t *sqliteTx) PurgeUser(ctx context.Context, userID string) (*schema.User, *schema.Err) {
+	user, err := t.DeleteUser(ctx, userID)
+	if err != nil && err.Code != schema.NotFound {
+		return nil, err
+	}
+
+	row := schema.User{UserID: userID, Teams: []string{}}
+	if lerr := t.tx.QueryRowContext(ctx, "delete from users where user_id = ? returning user_name, is_active", userID).Scan(&row.UserName, &row.IsActive); lerr != nil {
+		return nil, sqliteOrNotFound(lerr, userNotFound(userID))
+	}
+	if user == nil {
+		user = &row
+	}
+	return user, nil
+}
+
+func (t *sqliteTx) SetUserActive(ctx context.Context, userID string, isActive bool) (*schema.User, *schema.Err) {
+	res, lerr := t.tx.ExecContext(ctx, "update users set is_active = ? where user_id = ? and deleted_at is null", isActive, userID)
+	if lerr != nil {
+		return nil, sqliteErr(lerr)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return nil, userNotFound(userID)
+	}
+
+	return t.GetUser(ctx, userID)
+}
+
+func (t *sqliteTx) CreatePR(ctx context.Context, prc schema.PullReqCreate) (*schema.PullRequest, *schema.Err) {
+	b, err := t.exists(ctx, "select 1 from pull_requests where pull_req_id = ?", prc.PRId)
+	if err != nil {
+		return nil, err
+	} else if b {
+		return nil, schema.Err{}.Wrap(schema.PRExists, fmt.Errorf("PR %s already exists", prc.PRId)).With("pull_request_id", prc.PRId)
+	}
+
+	if b, err = t.exists(ctx, "select 1 from users where user_id = ? and deleted_at is null", prc.AuthorID); err != nil {
+		return nil, err
+	} else if !b {
+		return nil, userNotFound(prc.AuthorID)
+	}
+
+	teams, err := t.userTeams(ctx, prc.AuthorID)
+	if err != nil {
+		return nil, err
+	}
+	if prc.TeamName, err = prTeam(prc, teams); err != nil {
+		return nil, err
+	}
+
+	team := sql.NullString{String: prc.TeamName, Valid: prc.TeamName != ""}
+	if _, lerr := t.tx.ExecContext(ctx, "insert into pull_requests (pull_req_id, pull_req_name, author_id, team_name) values (?, ?, ?, ?)",
+		prc.PRId, prc.Name, prc.AuthorID, team); lerr != nil {
+		return nil, sqliteErr(lerr)
+	}
+	return t.pr(ctx, prc.PRId)
+}
+
+func (t *sqliteTx) CreatePRBatch(ctx context.Context, prs []schema.PullReqCreate) ([]schema.PRBatchItem, *schema.Err) {
+	plan := newBatchPlan()
+	for _, prc := range prs {
+		b, err := t.exists(ctx, "select 1 from pull_requests where pull_req_id = ?", prc.PRId)
+		if err != nil {
+			return nil, err
+		}
+		plan.existing[prc.PRId] = b
+
+		if b, err = t.exists(ctx, "select 1 from users where user_id = ? and deleted_at is null", prc.AuthorID); err != nil {
+			return nil, err
+		} else if b {
+			if plan.teams[prc.AuthorID], err = t.userTeams(ctx, prc.AuthorID); err != nil {
+				return nil, err
+			}
+		}
+	}
+	var err *schema.Err
+	if plan.parents, err = t.teamParents(ctx); err != nil {
+		return nil, err
+	}
+	for _, team := range plan.teamNames() {
+		members, err := t.activeTeammates(ctx, team, nil)
+		if err != nil {
+			return nil, err
+		}
+		plan.members[team] = members
+	}
+	stats, err := t.GetStats(ctx)
+	if err != nil {
+		return nil, err
+	}
+	plan.load = stats.ReviewerLoad
+
+	items := plan.run(prs)
+	for i, item := range items {
+		if item.Status != schema.BatchCreated {
+			continue
+		}
+		pr, ierr, err := t.createBatchItem(ctx, item)
+		if err != nil {
+			return nil, err
+		} else if ierr != nil {
+			failItem(&items[i], ierr)
+			continue
+		}
+		items[i].PR = pr
+	}
+	return items, nil
+}
+
+// createBatchItem stores one planned PR under a savepoint, so a failing item
+// leaves the rest of the batch alone. ierr is why the item failed, err is
+// only set when the savepoint itself does.
+func (t *sqliteTx) createBatchItem(ctx context.Context, item schema.PRBatchItem) (pr *schema.PullRequest, ierr, err *schema.Err) {
+	if _, lerr := t.tx.ExecContext(ctx, "savepoint batch_item"); lerr != nil {
+		return nil, nil, sqliteErr(lerr)
+	}
+
+	pr, ierr = t.CreatePR(ctx, schema.PullReqCreate{PRId: item.PRId, Name: item.PR.Name, AuthorID: item.PR.AuthorId, TeamName: item.PR.TeamName})
+	for _, id := range item.PR.AssignedReviewers {
+		if ierr != nil {
+			break
+		}
+		ierr = t.addReviewer(ctx, item.PRId, id)
+	}
+
+	release := "release batch_item"
+	if ierr != nil {
+		release = "rollback to batch_item; " + release
+	}
+	if _, lerr := t.tx.ExecContext(ctx, release); lerr != nil {
+		return nil, nil, sqliteErr(lerr)
+	}
+	if ierr != nil {
+		return nil, ierr, nil
+	}
+	pr.AssignedReviewers = item.PR.AssignedReviewers
+	return pr, nil, nil
+}
+
+func (t *sqliteTx) MergePR(ctx context.Context, prID string, ifMatch *schema.Precondition) (*schema.PullRequest, *schema.Err) {
+	b, err := t.exists(ctx, "select 1 from pull_requests where pull_req_id = ?", prID)
+	if err != nil {
+		return nil, err
+	} else if !b {
+		return nil, prNotFound(prID)
+	}
+	if err = t.precondition(ctx, prID, ifMatch); err != nil {
+		return nil, err
+	}
+
+	if _, lerr := t.tx.ExecContext(ctx, `
+		update pull_requests
+		set pull_req_status = 'merged', version = version + (pull_req_status = 'open')
+		where pull_req_id = ?`, prID); lerr != nil {
+		return nil, sqliteErr(lerr)
+	}
+	return t.pr(ctx, prID)
+}
+
+func (t *sqliteTx) ReassignReviewer(ctx context.Context, prID, oldUserID string, ifMatch *schema.Precondition) (string, *schema.PullRequest, *schema.Err) {
+	if b, err := t.exists(ctx, "select 1 from pull_requests where pull_req_id = ?", prID); err != nil {
+		return "", nil, err
+	} else if !b {
+		return "", nil, prNotFound(prID)
+	}
+	if err := t.precondition(ctx, prID, ifMatch); err != nil {
+		return "", nil, err
+	}
+
+	b, err := t.exists(ctx, "select 1 from reviewers_to_pull_requests where pull_req_id = ? and user_id = ?", prID, oldUserID)
+	if err != nil {
+		return "", nil, err
+	} else if !b {
+		return "", nil, schema.Err{}.Wrap(schema.NotAssigned, fmt.Errorf("user %s is not assigned to PR %s", oldUserID, prID)).
+			With("pull_request_id", prID).
+			With("user_id", oldUserID)
+	}
+
+	pr, err := t.pr(ctx, prID)
+	if err != nil {
+		return "", nil, err
+	}
+	if pr.Status == gensql.PrstatMerged {
+		return "", nil, schema.Err{}.Wrap(schema.PRMerged, fmt.Errorf("cannot reassign on merged PR")).With("pull_request_id", prID)
+	}
+
+	teamName := pr.TeamName
+	if teamName == "" {
+		teams, err := t.userTeams(ctx, oldUserID)
+		if err != nil {
+			return "", nil, err
+		} else if len(teams) == 0 {
+			return "", nil, userNotFound(oldUserID)
+		}
+		teamName = teams[0]
+	}
+
+	parents, err := t.teamParents(ctx)
+	if err != nil {
+		return "", nil, err
+	}
+	candidates, err := nearestCandidates(teamChain(parents, teamName), append([]string{pr.AuthorId}, pr.AssignedReviewers...), t.candidates(ctx))
+	if err != nil {
+		return "", nil, err
+	}
+	if len(candidates) == 0 {
+		return "", nil, schema.Err{}.Wrap(schema.NoCandidate, fmt.Errorf("no active replacement candidate in team")).
+			With("pull_request_id", prID).
+			With("team_name", teamName)
+	}
+
+	newUserID := candidates[rand.Intn(len(candidates))]
+	if _, lerr := t.tx.ExecContext(ctx, "delete from reviewers_to_pull_requests where pull_req_id = ? and user_id = ?", prID, oldUserID); lerr != nil {
+		return "", nil, sqliteErr(lerr)
+	}
+	if err = t.addReviewer(ctx, prID, newUserID); err != nil {
+		return "", nil, err
+	}
+	if _, lerr := t.tx.ExecContext(ctx, "update pull_requests set version = version + 1 where pull_req_id = ?", prID); lerr != nil {
+		return "", nil, sqliteErr(lerr)
+	}
+
+	if pr, err = t.pr(ctx, prID); err != nil {
+		return "", nil, err
+	}
+	return newUserID, pr, nil
+}
+
+func (t *sqliteTx) GetUserReviews(ctx context.Context, userID string) ([]schema.PullRequestShort, *schema.Err) {
+	b, err := t.exists(ctx, "select 1 from users where user_id = ? and deleted_at is null", userID)
+	if err != nil {
+		return nil, err
+	} else if !b {
+		return nil, userNotFound(userID)
+	}
+
+	rows, lerr := t.tx.QueryContext(ctx, `
+		select pr.pull_req_id, pr.pull_req_name, coalesce(pr.author_id, ''), pr.pull_req_status
+		from pull_requests pr
+		inner join reviewers_to_pull_requests rtp on rtp.pull_req_id = pr.pull_req_id
+		where rtp.user_id = ?
+		order by pr.pull_req_id`, userID)
+	if lerr != nil {
+		return nil, sqliteErr(lerr)
+	}
+	defer func() { _ = rows.Close() }()
+
+	prs := []schema.PullRequestShort{}
+	for rows.Next() {
+		var pr schema.PullRequestShort
+		if lerr = rows.Scan(&pr.PRId, &pr.Name, &pr.AuthorId, &pr.Status); lerr != nil {
+			return nil, sqliteErr(lerr)
+		}
+		prs = append(prs, pr)
+	}
+	if lerr = rows.Err(); lerr != nil {
+		return nil, sqliteErr(lerr)
+	}
+	return prs, nil
+}
+
+func (t *sqliteTx) GetPR(ctx context.Context, prID string) (*schema.PullRequest, *schema.Err) {
+	return t.pr(ctx, prID)
+}
+
+func (t *sqliteTx) GetUser(ctx context.Context, userID string) (*schema.User, *schema.Err) {
+	var (
+		user schema.User
+		team sql.NullString
+	)
+	if lerr := t.tx.QueryRowContext(ctx, `
+		select u.user_id, u.user_name, u.is_active, ut.team_name
+		from users u
+		left join users_to_teams ut on ut.user_id = u.user_id and ut.is_primary
+		where u.user_id = ? and u.deleted_at is null`, userID).Scan(&user.UserID, &user.UserName, &user.IsActive, &team); lerr != nil {
+		return nil, sqliteOrNotFound(lerr, userNotFound(userID))
+	}
+	user.TeamName = team.String
+
+	teams, err := t.userTeams(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	user.Teams = append([]string{}, teams...)
+	return &user, nil
+}
+
+func (t *sqliteTx) GetStats(ctx context.Context) (*schema.Stats, *schema.Err) {
+	stats := &schema.Stats{ReviewerLoad: map[string]int64{}}
+	if lerr := t.tx.QueryRowContext(ctx, "select count(*) from pull_requests where pull_req_status = 'open'").Scan(&stats.OpenPRs); lerr != nil {
+		return nil, sqliteErr(lerr)
+	}
+
+	rows, lerr := t.tx.QueryContext(ctx, `
+		select rtp.user_id, count(*)
+		from reviewers_to_pull_requests rtp
+		inner join pull_requests pr on pr.pull_req_id = rtp.pull_req_id
+		where pr.pull_req_status = 'open'
+		group by rtp.user_id`)
+	if lerr != nil {
+		return nil, sqliteErr(lerr)
+	}
+	defer func() { _ = rows.Close() }()
+
+	for rows.Next() {
+		var (
+			id string
+			n  int64
+		)
+		if lerr = rows.Scan(&id, &n); lerr != nil {
+			return nil, sqliteErr(lerr)
+		}
+		stats.ReviewerLoad[id] = n
+	}
+	if lerr = rows.Err(); lerr != nil {
+		return nil, sqliteErr(lerr)
+	}
+	return stats, nil
+}
+
+func (t *sqliteTx) ListTeams(ctx context.Context) ([]schema.Team, *schema.Err) {
+	names, err := t.strings(ctx, "select team_name from teams where deleted_at is null order by team_name")
+	if err != nil {
+		return nil, err
+	}
+
+	teams := make([]schema.Team, 0, len(names))
+	for _, name := range names {
+		team, err := t.GetTeamWithMembers(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		teams = append(teams, *team)
+	}
+	return teams, nil
+}
+
+func (t *sqliteTx) ListPRs(ctx context.Context) ([]schema.PullRequest, *schema.Err) {
+	ids, err := t.strings(ctx, "select pull_req_id from pull_requests order by created_at, pull_req_id")
+	if err != nil {
+		return nil, err
+	}
+
+	prs := make([]schema.PullRequest, 0, len(ids))
+	for _, id := range ids {
+		pr, err := t.pr(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		prs = append(prs, *pr)
+	}
+	return prs, nil
+}
+
+func (t *sqliteTx) ImportPR(ctx context.Context, pr schema.PullRequest) *schema.Err {
+	params, lerr := pr.ImportSchema()
+	if lerr != nil {
+		return schema.Err{}.Wrap(schema.ValidationFailed, lerr).With("pull_request_id", pr.PRId)
+	}
+
+	b, err := t.exists(ctx, "select 1 from pull_requests where pull_req_id = ?", pr.PRId)
+	if err != nil {
+		return err
+	} else if b {
+		return schema.Err{}.Wrap(schema.PRExists, fmt.Errorf("PR %s already exists", pr.PRId)).With("pull_request_id", pr.PRId)
+	}
+
+	if _, lerr = t.tx.ExecContext(ctx, `
+		insert into pull_requests (pull_req_id, pull_req_name, author_id, pull_req_status, created_at, merged_at, version, team_name)
+		values (?, ?, ?, ?, ?, ?, ?, ?)`,
+		params.PullReqID, params.PullReqName, params.AuthorID, params.PullReqStatus,
+		nullTime(params.CreatedAt), nullTime(params.MergedAt), params.Version,
+		sql.NullString{String: params.TeamName.String, Valid: params.TeamName.Valid}); lerr != nil {
+		return sqliteErr(lerr)
+	}
+
+	for _, id := range pr.AssignedReviewers {
+		if err = t.addReviewer(ctx, pr.PRId, id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (t *sqliteTx) GetReviewersForPR(ctx context.Context, prID string) ([]string, *schema.Err) {
+	return t.strings(ctx, "select user_id from reviewers_to_pull_requests where pull_req_id = ? order by rowid", prID)
+}
+
+func (t *sqliteTx) AssignReviewersToPR(ctx context.Context, prID, authorID string) ([]string, *schema.Err) {
+	pr, err := t.pr(ctx, prID)
+	if err != nil {
+		return nil, err
+	} else if pr.TeamName == "" {
+		return nil, noTeam(authorID)
+	}
+
+	parents, err := t.teamParents(ctx)
+	if err != nil {
+		return nil, err
+	}
+	reviewers, err := fillCandidates(teamChain(parents, pr.TeamName), []string{authorID}, maxReviewers, t.candidates(ctx))
+	if err != nil {
+		return nil, err
+	}
+
+	for _, id := range reviewers {
+		if err = t.addReviewer(ctx, prID, id); err != nil {
+			return nil, err
+		}
+	}
+	return reviewers, nil
+}
+
+func (t *sqliteTx) apiKey(ctx context.Context, keyID string, nf *schema.Err) (*schema.APIKey, *schema.Err) {
+	var (
+		k       gensql.ApiKey
+		userID  sql.NullString
+		revoked sql.NullTime
+	)
+	if lerr := t.tx.QueryRowContext(ctx, `
+		select key_id, key_hash, user_id, role, created_at, revoked_at
+		from api_keys
+		where key_id = ?`, keyID).Scan(&k.KeyID, &k.KeyHash, &userID, &k.Role, &k.CreatedAt.Time, &revoked); lerr != nil {
+		return nil, sqliteOrNotFound(lerr, nf)
+	}
+	k.UserID = pgtype.Text{String: userID.String, Valid: userID.Valid}
+	k.CreatedAt.Valid = true
+	k.RevokedAt = timestamp(revoked)
+	return schema.APIKey{}.FromDDL(k), nil
+}
+
+func (t *sqliteTx) CreateAPIKey(ctx context.Context, keyID string, hash []byte, userID string, role schema.Role) (*schema.APIKey, *schema.Err) {
+	if userID != "" {
+		b, err := t.exists(ctx, "select 1 from users where user_id = ? and deleted_at is null", userID)
+		if err != nil {
+			return nil, err
+		} else if !b {
+			return nil, userNotFound(userID)
+		}
+	}
+
+	owner := sql.NullString{String: userID, Valid: userID != ""}
+	if _, lerr := t.tx.ExecContext(ctx, "insert into api_keys (key_id, key_hash, user_id, role) values (?, ?, ?, ?)",
+		keyID, hash, owner, string(role)); lerr != nil {
+		return nil, sqliteErr(lerr)
+	}
+	return t.apiKey(ctx, keyID, schema.Err{}.Wrap(schema.NotFound, fmt.Errorf("api key %s not found", keyID)).With("key_id", keyID))
+}
+
+func (t *sqliteTx) GetAPIKey(ctx context.Context, keyID string) (*schema.APIKey, *schema.Err) {
+	return t.apiKey(ctx, keyID, schema.Err{}.Wrap(schema.NotFound, fmt.Errorf("api key %s not found", keyID)).With("key_id", keyID))
+}
+
+func (t *sqliteTx) RevokeAPIKey(ctx context.Context, keyID string) (*schema.APIKey, *schema.Err) {
+	nf := schema.Err{}.Wrap(schema.NotFound, fmt.Errorf("active api key %s not found", keyID)).With("key_id", keyID)
+
+	res, lerr := t.tx.ExecContext(ctx, "update api_keys set revoked_at = ? where key_id = ? and revoked_at is null",
+		time.Now().UTC().Format(sqliteTime), keyID)
+	if lerr != nil {
+		return nil, sqliteErr(lerr)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return nil, nf
+	}
+	return t.apiKey(ctx, keyID, nf)
+}
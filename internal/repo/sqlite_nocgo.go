@@ -0,0 +1,25 @@
+//go:build !cgo
+
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+)
+
+var errNoCgo = errors.New("the sqlite backend needs a build with CGO_ENABLED=1")
+
+func OpenSQLite(string) (*sql.DB, error) {
+	return nil, errNoCgo
+}
+
+func NewSQLite(*sql.DB) Store {
+	return sqliteStore{}
+}
+
+type sqliteStore struct{}
+
+func (sqliteStore) Begin(context.Context) (Tx, error) {
+	return nil, errNoCgo
+}
@@ -0,0 +1,76 @@
+//go:build cgo
+
+package repo_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"plassstic.tech/trainee/avito/internal/migrate"
+	"plassstic.tech/trainee/avito/internal/repo"
+	"plassstic.tech/trainee/avito/internal/schema"
+)
+
+// TestSQLiteBatchItemFailure makes the store refuse one PR of a batch, which
+// the plan cannot foresee, and checks that only that item fails.
+func TestSQLiteBatchItemFailure(t *testing.T) {
+	ctx := t.Context()
+	db, err := repo.OpenSQLite(filepath.Join(t.TempDir(), "batch.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+
+	m, err := migrate.NewSQLite(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = m.Up(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = db.ExecContext(ctx, `
+		create trigger refuse_pr before insert on reviewers_to_pull_requests
+		when new.pull_req_id = 'pr-2'
+		begin select raise(abort, 'refused'); end`); err != nil {
+		t.Fatal(err)
+	}
+
+	tx, err := repo.NewSQLite(db).Begin(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	if _, serr := tx.AddTeamWithMembers(ctx, schema.Team{TeamName: "backend", Members: []schema.TeamMember{
+		{UserID: "u1", UserName: "Alice", IsActive: true},
+		{UserID: "u2", UserName: "Bob", IsActive: true},
+	}}); serr != nil {
+		t.Fatal(serr)
+	}
+
+	items, serr := tx.CreatePRBatch(ctx, []schema.PullReqCreate{
+		{PRId: "pr-1", Name: "first", AuthorID: "u1"},
+		{PRId: "pr-2", Name: "refused", AuthorID: "u1"},
+		{PRId: "pr-3", Name: "third", AuthorID: "u1"},
+	})
+	if serr != nil {
+		t.Fatal(serr)
+	}
+
+	for i, want := range []schema.BatchStatus{schema.BatchCreated, schema.BatchFailed, schema.BatchCreated} {
+		if items[i].Status != want {
+			t.Fatalf("item %d: expected %s, got %+v", i, want, items[i])
+		}
+	}
+	if items[1].Error == nil || items[1].PR != nil {
+		t.Fatalf("expected the failed item to carry its error only, got %+v", items[1])
+	}
+	if _, serr = tx.GetPR(ctx, "pr-2"); serr == nil || serr.Code != schema.NotFound {
+		t.Fatalf("expected the failed PR rolled back, got %+v", serr)
+	}
+	for _, id := range []string{"pr-1", "pr-3"} {
+		if pr, serr := tx.GetPR(ctx, id); serr != nil || len(pr.AssignedReviewers) != 1 {
+			t.Fatalf("expected %s stored with its reviewer, got %+v %+v", id, pr, serr)
+		}
+	}
+}
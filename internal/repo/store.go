@@ -0,0 +1,49 @@
+package repo
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Tx is a Repository bound to one transaction.
+type Tx interface {
+	Repository
+	Commit(ctx context.Context) error
+	Rollback(ctx context.Context) error
+}
+
+// Store opens transactions on a storage backend.
+type Store interface {
+	Begin(ctx context.Context) (Tx, error)
+}
+
+type pgStore struct {
+	pool *pgxpool.Pool
+}
+
+type pgTx struct {
+	Repository
+	tx pgx.Tx
+}
+
+func NewPg(pool *pgxpool.Pool) Store {
+	return &pgStore{pool: pool}
+}
+
+func (s *pgStore) Begin(ctx context.Context) (Tx, error) {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &pgTx{Repository: R(tx), tx: tx}, nil
+}
+
+func (t *pgTx) Commit(ctx context.Context) error {
+	return t.tx.Commit(ctx)
+}
+
+func (t *pgTx) Rollback(ctx context.Context) error {
+	return t.tx.Rollback(ctx)
+}
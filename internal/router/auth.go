@@ -0,0 +1,51 @@
+package router
+
+import (
+	"github.com/rs/zerolog/log"
+	"github.com/samber/lo"
+	"plassstic.tech/trainee/avito/internal/auth"
+	"plassstic.tech/trainee/avito/internal/router/routes"
+	"plassstic.tech/trainee/avito/internal/schema"
+	"plassstic.tech/trainee/avito/internal/utils"
+)
+
+func (r *router) authenticator() auth.Authenticator {
+	cfg := r.cfg.Auth
+	chain := auth.Chain{}
+
+	if cfg.AdminKey != "" {
+		chain = append(chain, auth.StaticKey(cfg.AdminKey, auth.Identity{KeyID: "bootstrap", Role: schema.RoleAdmin}))
+	}
+	chain = append(chain, routes.APIKeyAuthenticator(r.service))
+
+	if cfg.JWT.Enabled() {
+		chain = append(chain, jwtAuthenticator(cfg.JWT))
+	}
+
+	return chain
+}
+
+func jwtAuthenticator(cfg utils.JWT) auth.Authenticator {
+	var keys auth.KeySet
+	if cfg.JWKSURL != "" {
+		keys = auth.NewJWKS(nil, cfg.JWKSURL, cfg.JWKSRefresh)
+	} else {
+		var err error
+		if keys, err = auth.LoadPEMKeys(cfg.PublicKeyFiles); err != nil {
+			log.Fatal().Err(err).Msg("failed to load jwt public keys")
+		}
+	}
+
+	return auth.JWT(auth.JWTConfig{
+		Keys:        keys,
+		Issuer:      cfg.Issuer,
+		Audience:    cfg.Audience,
+		UserClaim:   cfg.UserClaim,
+		RoleClaim:   cfg.RoleClaim,
+		DefaultRole: schema.Role(cfg.DefaultRole),
+		RoleMap: lo.MapValues(cfg.RoleMap, func(role string, _ string) schema.Role {
+			return schema.Role(role)
+		}),
+		Leeway: cfg.Leeway,
+	})
+}
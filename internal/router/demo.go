@@ -0,0 +1,42 @@
+package router
+
+import (
+	"context"
+
+	"github.com/rs/zerolog/log"
+	"plassstic.tech/trainee/avito/internal/schema"
+	"plassstic.tech/trainee/avito/internal/service"
+)
+
+var demoTeams = []schema.Team{
+	{TeamName: "backend", Members: []schema.TeamMember{
+		{UserID: "u1", UserName: "Alice", IsActive: true},
+		{UserID: "u2", UserName: "Bob", IsActive: true},
+		{UserID: "u3", UserName: "Carol", IsActive: true},
+		{UserID: "u4", UserName: "Dave", IsActive: false},
+	}},
+	{TeamName: "frontend", Members: []schema.TeamMember{
+		{UserID: "u5", UserName: "Eve", IsActive: true},
+		{UserID: "u6", UserName: "Frank", IsActive: true},
+	}},
+}
+
+var demoPRs = []schema.CreatePRRequest{
+	{PRId: "pr-1001", Name: "Add search endpoint", AuthorID: "u1"},
+	{PRId: "pr-1002", Name: "Fix button styles", AuthorID: "u5"},
+}
+
+func seedDemo(s service.Service) {
+	ctx := context.Background()
+	for _, team := range demoTeams {
+		if _, err := s.AddTeam(ctx, team); err != nil {
+			log.Fatal().Err(err).Str("team", team.TeamName).Msg("failed to seed demo team")
+		}
+	}
+	for _, pr := range demoPRs {
+		if _, err := s.CreatePR(ctx, pr); err != nil {
+			log.Fatal().Err(err).Str("pr", pr.PRId).Msg("failed to seed demo pull request")
+		}
+	}
+	log.Info().Int("teams", len(demoTeams)).Int("pull_requests", len(demoPRs)).Msg("seeded demo data")
+}
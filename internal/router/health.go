@@ -0,0 +1,20 @@
+package router
+
+import (
+	"plassstic.tech/trainee/avito/internal/health"
+	"plassstic.tech/trainee/avito/internal/utils"
+	"plassstic.tech/trainee/avito/migrations"
+)
+
+func setupHealth(box utils.Box) *health.Checker {
+	h := health.New()
+	if db := box.SQLite(); db != nil {
+		h.Add("sqlite", true, health.PingSQL(db))
+	}
+	if box.Pg() == nil {
+		return h
+	}
+	h.Add("postgres", true, health.Ping(box.Pg()))
+	h.Add("migrations", true, health.MigrationVersion(box.Pg(), migrations.Latest()))
+	return h
+}
@@ -0,0 +1,72 @@
+package router
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/rs/zerolog/log"
+	"plassstic.tech/trainee/avito/internal/metrics"
+	"plassstic.tech/trainee/avito/internal/router/routes"
+	"plassstic.tech/trainee/avito/internal/utils"
+)
+
+// setupMetrics registers the collectors of this router on its own registry,
+// so that several routers can live in one process.
+func (r *router) setupMetrics(box utils.Box) {
+	cfg := r.cfg.Metrics
+	if !cfg.Enabled {
+		return
+	}
+
+	r.metrics = prometheus.NewRegistry()
+	r.metrics.MustRegister(metrics.NewDomainCollector(r.service))
+	if box.Pg() != nil {
+		r.metrics.MustRegister(metrics.NewPoolCollector(box.Pg()))
+	}
+	r.Use(routes.Metrics())
+}
+
+// setupMetricsRoute puts /metrics on the API port unless it has one of its
+// own. Without a token it is only open to admins, like the rest of the API.
+func (r *router) setupMetricsRoute(api *gin.RouterGroup) {
+	cfg := r.cfg.Metrics
+	switch {
+	case !cfg.Enabled || cfg.Port != 0:
+	case cfg.Token != "":
+		routes.SetupMetricsRoute(r.Engine, r.metrics, routes.MetricsToken(cfg.Token))
+	default:
+		routes.SetupMetricsRoute(api, r.metrics, routes.AdminOnly())
+	}
+}
+
+func (r *router) serveMetrics(ctx context.Context) {
+	cfg := r.cfg.Metrics
+	if !cfg.Enabled || cfg.Port == 0 {
+		return
+	}
+
+	engine := gin.New()
+	engine.Use(gin.Recovery())
+	var guards []gin.HandlerFunc
+	if cfg.Token != "" {
+		guards = append(guards, routes.MetricsToken(cfg.Token))
+	}
+	routes.SetupMetricsRoute(engine, r.metrics, guards...)
+
+	srv := http.Server{Addr: fmt.Sprintf(":%d", cfg.Port), Handler: engine}
+	go func() {
+		<-ctx.Done()
+		tctx, c := context.WithTimeout(context.Background(), 5*time.Second)
+		defer c()
+		_ = srv.Shutdown(tctx)
+	}()
+
+	log.Info().Int("port", cfg.Port).Msg("serving metrics")
+	if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		log.Error().Err(err).Int("port", cfg.Port).Msg("metrics server failed")
+	}
+}
@@ -0,0 +1,59 @@
+package router_test
+
+import (
+	"bytes"
+	"net/http"
+	"testing"
+
+	"plassstic.tech/trainee/avito/internal/schema"
+)
+
+func TestMetricsToken(t *testing.T) {
+	srv := newServer(t, map[string]string{"METRICS_TOKEN": "secret"})
+
+	for _, tc := range []struct {
+		name   string
+		header http.Header
+		want   int
+	}{
+		{"no token", nil, http.StatusUnauthorized},
+		{"wrong token", bearer("guess"), http.StatusUnauthorized},
+		{"admin key without token", apiKey(adminKey), http.StatusUnauthorized},
+		{"token", bearer("secret"), http.StatusOK},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			res, body := get(t, srv, "/metrics", tc.header)
+			if res.StatusCode != tc.want {
+				t.Fatalf("expected %d, got %d", tc.want, res.StatusCode)
+			}
+			if tc.want == http.StatusOK && !bytes.Contains(body, []byte("avito_reviews_open_prs")) {
+				t.Fatalf("expected the domain metrics, got %s", body)
+			}
+		})
+	}
+}
+
+func TestMetricsAdminOnly(t *testing.T) {
+	srv := newServer(t, nil)
+	user := issueKey(t, srv, schema.IssueAPIKeyRequest{UserID: "u1", Role: schema.RoleTeamLead})
+
+	for _, tc := range []struct {
+		name   string
+		header http.Header
+		want   int
+	}{
+		{"no key", nil, http.StatusUnauthorized},
+		{"lead key", apiKey(user), http.StatusForbidden},
+		{"admin key", apiKey(adminKey), http.StatusOK},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			res, body := get(t, srv, "/metrics", tc.header)
+			if res.StatusCode != tc.want {
+				t.Fatalf("expected %d, got %d", tc.want, res.StatusCode)
+			}
+			if tc.want == http.StatusOK && !bytes.Contains(body, []byte("avito_http_requests_total")) {
+				t.Fatalf("expected the http metrics, got %s", body)
+			}
+		})
+	}
+}
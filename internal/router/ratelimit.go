@@ -0,0 +1,63 @@
+package router
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+	"plassstic.tech/trainee/avito/internal/ratelimit"
+	"plassstic.tech/trainee/avito/internal/router/routes"
+	"plassstic.tech/trainee/avito/internal/utils"
+)
+
+func setupLimiter(box utils.Box) ratelimit.Limiter {
+	cfg := box.Config().RateLimit
+	if !cfg.Enabled {
+		return nil
+	}
+
+	switch cfg.Backend {
+	case "memory":
+		return ratelimit.NewMemory()
+	case "postgres":
+		if box.Pg() == nil {
+			log.Warn().Msg("no database, falling back to the memory rate limit backend")
+			return ratelimit.NewMemory()
+		}
+		return ratelimit.NewPostgres(box.Pg())
+	default:
+		log.Fatal().Str("backend", cfg.Backend).Msg("unknown rate limit backend")
+		return nil
+	}
+}
+
+func (r *router) rateLimit(group string) []gin.HandlerFunc {
+	if r.limiter == nil {
+		return nil
+	}
+
+	cfg := r.cfg.RateLimit
+	raw := cfg.Default
+	if override, ok := cfg.Groups[group]; ok {
+		raw = override
+	}
+	return []gin.HandlerFunc{routes.RateLimit(r.limiter, group, parseLimit(group, raw), cfg.By == "key")}
+}
+
+// ipLimit runs before authentication, so callers that fail it are limited by
+// their address as well.
+func (r *router) ipLimit() []gin.HandlerFunc {
+	if r.limiter == nil {
+		return nil
+	}
+	return []gin.HandlerFunc{routes.RateLimit(r.limiter, ipGroup, parseLimit(ipGroup, r.cfg.RateLimit.IP), false)}
+}
+
+// ipGroup names the buckets of ipLimit.
+const ipGroup = "ip"
+
+func parseLimit(group, raw string) ratelimit.Limit {
+	limit, err := ratelimit.ParseLimit(raw)
+	if err != nil {
+		log.Fatal().Err(err).Str("group", group).Msg("invalid rate limit")
+	}
+	return limit
+}
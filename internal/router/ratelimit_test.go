@@ -0,0 +1,44 @@
+package router_test
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestRateLimitGroups(t *testing.T) {
+	srv := newServer(t, map[string]string{
+		"RATELIMIT_ENABLED": "true",
+		"RATELIMIT_DEFAULT": "0.001:2",
+		"RATELIMIT_GROUPS":  "team=0.001:1",
+		"RATELIMIT_IP":      "1000:1000",
+	})
+
+	for i, want := range []int{http.StatusOK, http.StatusTooManyRequests} {
+		if res, _ := get(t, srv, "/team/get?team_name=backend", apiKey(adminKey)); res.StatusCode != want {
+			t.Fatalf("team request %d: expected %d, got %d", i+1, want, res.StatusCode)
+		}
+	}
+	for i, want := range []int{http.StatusOK, http.StatusOK, http.StatusTooManyRequests} {
+		if res, _ := get(t, srv, "/users/getReview?user_id=u2", apiKey(adminKey)); res.StatusCode != want {
+			t.Fatalf("users request %d: expected %d, got %d", i+1, want, res.StatusCode)
+		}
+	}
+}
+
+// TestRateLimitBeforeAuth makes sure guessing api keys is limited too.
+func TestRateLimitBeforeAuth(t *testing.T) {
+	srv := newServer(t, map[string]string{
+		"RATELIMIT_ENABLED": "true",
+		"RATELIMIT_IP":      "0.001:2",
+	})
+
+	for i, want := range []int{http.StatusUnauthorized, http.StatusUnauthorized, http.StatusTooManyRequests} {
+		res, _ := get(t, srv, "/team/get?team_name=backend", apiKey("guess"))
+		if res.StatusCode != want {
+			t.Fatalf("request %d: expected %d, got %d", i+1, want, res.StatusCode)
+		}
+		if want == http.StatusTooManyRequests && res.Header.Get("Retry-After") == "" {
+			t.Fatal("expected a Retry-After header")
+		}
+	}
+}
@@ -2,13 +2,20 @@ package router
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net"
 	"net/http"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
+	"plassstic.tech/trainee/avito/internal/health"
+	"plassstic.tech/trainee/avito/internal/idempotency"
+	"plassstic.tech/trainee/avito/internal/policy"
+	"plassstic.tech/trainee/avito/internal/ratelimit"
 	"plassstic.tech/trainee/avito/internal/router/routes"
 	"plassstic.tech/trainee/avito/internal/service"
 	"plassstic.tech/trainee/avito/internal/utils"
@@ -17,6 +24,11 @@ import (
 type router struct {
 	*gin.Engine
 	service service.Service
+	cfg     *utils.Config
+	idem    idempotency.Store
+	limiter ratelimit.Limiter
+	health  *health.Checker
+	metrics *prometheus.Registry
 }
 
 func (r *router) Serve(ctx context.Context, port int) {
@@ -26,50 +38,94 @@ func (r *router) Serve(ctx context.Context, port int) {
 	}
 
 	log.Info().Int("port", port).Msg("OK, registered")
+	go idempotency.RunPurger(ctx, r.idem, r.cfg.Idempotency.PurgeInterval, r.health.Worker("idempotency_purger"))
+	if r.limiter != nil {
+		go ratelimit.RunCleanup(ctx, r.limiter, r.cfg.RateLimit.Idle, r.health.Worker("ratelimit_cleanup"))
+	}
+	go r.serveMetrics(ctx)
+
 	srv := http.Server{Handler: r.Handler()}
+	closed := make(chan struct{})
 
 	go func() {
+		defer close(closed)
 		<-ctx.Done()
-		log.Info().Msg("context done, closing listener")
-		_ = lis.Close()
-		tctx, c := context.WithTimeout(context.Background(), 10*time.Second)
+		r.health.Drain()
+		log.Info().Dur("delay", r.cfg.Server.DrainDelay).Msg("context done, draining")
+		time.Sleep(r.cfg.Server.DrainDelay)
+
+		tctx, c := context.WithTimeout(context.Background(), r.cfg.Server.ShutdownTimeout)
 		defer c()
-		_ = srv.Shutdown(tctx)
-		log.Info().Msg("OK, closed")
+		if err := srv.Shutdown(tctx); err != nil {
+			log.Error().Err(err).Msg("failed to shut down gracefully")
+		}
 	}()
 
-	if err = srv.Serve(lis); err != nil {
+	if err = srv.Serve(lis); err != nil && !errors.Is(err, http.ErrServerClosed) {
 		log.Fatal().Int("port", port).Err(err).Msg("failed to serve")
 	}
+	<-closed
+	log.Info().Msg("OK, closed")
 }
 
+// Router is also an http.Handler, so it can be mounted without Serve, e.g.
+// in an httptest.Server.
 type Router interface {
+	http.Handler
 	Serve(ctx context.Context, port int)
 }
 
 func New(box utils.Box) Router {
 	r := &router{
 		Engine:  gin.New(),
-		service: service.New(box.Pg()),
+		service: service.NewWithStore(box.Store(), box.Events(), box.EventSource()),
+		cfg:     box.Config(),
+		idem:    setupIdempotency(box),
+		limiter: setupLimiter(box),
+		health:  setupHealth(box),
 	}
+	r.ContextWithFallback = true
 	gin.DefaultWriter = log.Logger
-	r.Use(gin.Logger(), gin.Recovery())
+	r.Use(routes.Tracing(r.cfg.Tracing.ServiceName), routes.RequestID(), routes.AccessLog(), gin.Recovery())
 	r.Use(r.errorHandler)
+	r.setupMetrics(box)
 	gin.SetMode(gin.ReleaseMode)
+	if r.cfg.Auth.Enabled && r.cfg.Auth.AdminKey == "" && !r.cfg.Auth.JWT.Enabled() {
+		log.Warn().Msg("auth is enabled without AUTH_ADMIN_KEY, only stored api keys will be accepted")
+	}
+	if r.cfg.Server.Demo {
+		seedDemo(r.service)
+	}
 	r.setupRoutes()
 	return r
 }
 
+func setupIdempotency(box utils.Box) idempotency.Store {
+	if box.Pg() == nil {
+		return idempotency.NewMemory()
+	}
+	return idempotency.NewPgStore(box.Pg())
+}
+
 func (r *router) errorHandler(c *gin.Context) {
 	c.Next()
 	if len(c.Errors) > 0 {
-		log.Error().Err(c.Errors.Last()).Msg("handler error")
+		zerolog.Ctx(c).Error().Err(c.Errors.Last()).Msg("handler error")
 	}
 }
 
 func (r *router) setupRoutes() {
-	routes.SetupTeamRoutes(r.Engine.Group("/team"), r.service)
-	routes.SetupUsersRoutes(r.Engine.Group("/users"), r.service)
-	routes.SetupPRRoutes(r.Engine.Group("/pullRequest"), r.service)
-	routes.SetupHealthRoute(r.Engine)
+	api := r.Engine.Group("", append(r.ipLimit(), routes.Authenticate(r.authenticator(), r.cfg.Auth.Enabled))...)
+	guarded := policy.New(r.service)
+	routes.SetupTeamRoutes(r.group(api, "team"), guarded)
+	routes.SetupUsersRoutes(r.group(api, "users"), guarded)
+	routes.SetupPRRoutes(r.group(api, "pullRequest"), guarded)
+	routes.SetupAuthRoutes(r.group(api, "auth"), r.service)
+	routes.SetupHealthRoute(r.Engine, r.health)
+	r.setupMetricsRoute(api)
+}
+
+func (r *router) group(api *gin.RouterGroup, name string) *gin.RouterGroup {
+	handlers := append(r.rateLimit(name), routes.Idempotency(r.idem, r.cfg.Idempotency.TTL, r.cfg.Idempotency.MaxBody))
+	return api.Group("/"+name, handlers...)
 }
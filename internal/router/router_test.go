@@ -0,0 +1,91 @@
+package router_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"plassstic.tech/trainee/avito/internal/auth"
+	"plassstic.tech/trainee/avito/internal/router"
+	"plassstic.tech/trainee/avito/internal/schema"
+	"plassstic.tech/trainee/avito/internal/utils"
+)
+
+const adminKey = "test-admin-key"
+
+// newServer serves the demo router configured by overrides.
+func newServer(t *testing.T, overrides map[string]string) *httptest.Server {
+	t.Helper()
+	env := map[string]string{
+		"SERVER_DEMO":       "true",
+		"AUTH_ENABLED":      "true",
+		"AUTH_ADMIN_KEY":    adminKey,
+		"RATELIMIT_ENABLED": "false",
+		"METRICS_PORT":      "0",
+		"METRICS_TOKEN":     "",
+	}
+	for k, v := range overrides {
+		env[k] = v
+	}
+	cfg, err := utils.LoadConfig(env)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(router.New(utils.SetupBox(t.Context(), cfg)))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func apiKey(key string) http.Header {
+	return http.Header{auth.APIKeyHeader: {key}}
+}
+
+func bearer(token string) http.Header {
+	return http.Header{"Authorization": {"Bearer " + token}}
+}
+
+// get fetches path and returns the response with its body read.
+func get(t *testing.T, srv *httptest.Server, path string, header http.Header) (*http.Response, []byte) {
+	t.Helper()
+	req, err := http.NewRequestWithContext(t.Context(), http.MethodGet, srv.URL+path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header = header
+	res, err := srv.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+
+	var body bytes.Buffer
+	if _, err = body.ReadFrom(res.Body); err != nil {
+		t.Fatal(err)
+	}
+	return res, body.Bytes()
+}
+
+// issueKey has the admin issue an api key and returns it.
+func issueKey(t *testing.T, srv *httptest.Server, req schema.IssueAPIKeyRequest) string {
+	t.Helper()
+	raw, _ := json.Marshal(req)
+	r, err := http.NewRequestWithContext(t.Context(), http.MethodPost, srv.URL+"/auth/issueKey", bytes.NewReader(raw))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Header = apiKey(adminKey)
+	r.Header.Set("Content-Type", "application/json")
+	res, err := srv.Client().Do(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+
+	var key schema.APIKeyResponse
+	if err = json.NewDecoder(res.Body).Decode(&key); err != nil || res.StatusCode != http.StatusCreated {
+		t.Fatalf("issue key: status %d, %v", res.StatusCode, err)
+	}
+	return key.Key.Key
+}
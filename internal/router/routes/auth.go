@@ -0,0 +1,115 @@
+package routes
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"plassstic.tech/trainee/avito/internal/auth"
+	"plassstic.tech/trainee/avito/internal/schema"
+	"plassstic.tech/trainee/avito/internal/service"
+)
+
+func Authenticate(authn auth.Authenticator, enabled bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !enabled {
+			setIdentity(c, auth.Identity{Role: schema.RoleAdmin})
+			c.Next()
+			return
+		}
+
+		id, err := authn.Authenticate(c, c.Request)
+		if errors.Is(err, auth.ErrNoCredentials) {
+			respondError(c, schema.Err{}.Wrap(schema.Unauthorized, fmt.Errorf("%s header or bearer token is required", auth.APIKeyHeader)))
+			c.Abort()
+			return
+		} else if err != nil {
+			var serr *schema.Err
+			if !errors.As(err, &serr) {
+				serr = schema.Err{}.Wrap(schema.Unauthorized, err)
+			}
+			respondError(c, serr)
+			c.Abort()
+			return
+		}
+
+		setIdentity(c, *id)
+		c.Next()
+	}
+}
+
+func APIKeyAuthenticator(service service.Service) auth.Authenticator {
+	return auth.APIKeys(func(ctx context.Context, key string) (*auth.Identity, error) {
+		id, serr := service.Authenticate(ctx, key)
+		if serr != nil {
+			return nil, serr
+		}
+		return id, nil
+	})
+}
+
+func setIdentity(c *gin.Context, id auth.Identity) {
+	c.Request = c.Request.WithContext(auth.WithIdentity(c.Request.Context(), id))
+}
+
+func require(min schema.Role) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, ok := auth.FromContext(c)
+		if !ok {
+			respondError(c, schema.Err{}.Wrap(schema.Unauthorized, fmt.Errorf("caller is not authenticated")))
+			c.Abort()
+			return
+		}
+
+		if !id.Role.AtLeast(min) {
+			respondError(c, schema.Err{}.Wrap(schema.InsufficientRole, fmt.Errorf("role %s required", min)))
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+func SetupAuthRoutes(keys *gin.RouterGroup, service service.Service) {
+	keys.POST("/issueKey", require(schema.RoleAdmin), issueKey(service))
+	keys.POST("/revokeKey", require(schema.RoleAdmin), revokeKey(service))
+}
+
+func issueKey(service service.Service) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req schema.IssueAPIKeyRequest
+		if serr := bindJSON(c, &req); serr != nil {
+			respondError(c, serr)
+			return
+		}
+
+		result, serr := service.IssueAPIKey(c, req)
+		if serr != nil {
+			respondError(c, serr)
+			return
+		}
+
+		c.JSON(http.StatusCreated, schema.APIKeyResponse{Key: *result})
+	}
+}
+
+func revokeKey(service service.Service) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req schema.RevokeAPIKeyRequest
+		if serr := bindJSON(c, &req); serr != nil {
+			respondError(c, serr)
+			return
+		}
+
+		result, serr := service.RevokeAPIKey(c, req.KeyID)
+		if serr != nil {
+			respondError(c, serr)
+			return
+		}
+
+		c.JSON(http.StatusOK, schema.APIKeyResponse{Key: *result})
+	}
+}
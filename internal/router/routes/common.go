@@ -1,26 +1,82 @@
 package routes
 
 import (
+	"encoding/json"
+	"errors"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog"
 	"plassstic.tech/trainee/avito/internal/schema"
+	"plassstic.tech/trainee/avito/internal/utils"
 )
 
-func respondError(c *gin.Context, err *schema.Err) {
-	var status int
-	switch err.Code {
-	case schema.TeamExists:
-		status = http.StatusBadRequest
-	case schema.PRExists:
-		status = http.StatusConflict
+func statusOf(code schema.ErrorCode) int {
+	switch code {
+	case schema.TeamExists, schema.ValidationFailed:
+		return http.StatusBadRequest
+	case schema.PRExists, schema.IdempotencyInProgress, schema.TooManyReviewers, schema.UserInOtherTeam, schema.TeamCycle, schema.Conflict:
+		return http.StatusConflict
 	case schema.PRMerged, schema.NotAssigned, schema.NoCandidate, schema.NotFound:
-		status = http.StatusNotFound
+		return http.StatusNotFound
+	case schema.IdempotencyKeyReused:
+		return http.StatusUnprocessableEntity
+	case schema.RequestTooLarge:
+		return http.StatusRequestEntityTooLarge
+	case schema.PreconditionFailed:
+		return http.StatusPreconditionFailed
+	case schema.RateLimited:
+		return http.StatusTooManyRequests
+	case schema.Unauthorized:
+		return http.StatusUnauthorized
+	case schema.InsufficientRole, schema.Forbidden:
+		return http.StatusForbidden
 	default:
-		status = http.StatusInternalServerError
+		return http.StatusInternalServerError
 	}
+}
 
-	c.JSON(status, schema.ErrorResponse{
-		Err: *err,
+func respondError(c *gin.Context, err *schema.Err) {
+	if err.Internal() {
+		zerolog.Ctx(c).Error().
+			Err(err).
+			Str("path", c.Request.URL.Path).
+			Msg("internal error")
+	}
+
+	resp := err.Public()
+	resp.RequestID = utils.RequestID(c)
+	c.JSON(statusOf(err.Code), schema.ErrorResponse{
+		Err: resp,
 	})
 }
+
+func bindError(err error) *schema.Err {
+	var ute *json.UnmarshalTypeError
+	if errors.As(err, &ute) {
+		return &schema.Err{
+			Code: schema.ValidationFailed,
+			Msg:  "request body has fields of the wrong type",
+			Fields: []schema.FieldError{{
+				Field: ute.Field,
+				Rule:  "type",
+				Msg:   "must be " + ute.Type.String(),
+			}},
+		}
+	}
+	return schema.Err{}.Wrap(schema.ValidationFailed, err)
+}
+
+func bindJSON(c *gin.Context, dst any) *schema.Err {
+	if err := c.ShouldBindJSON(dst); err != nil {
+		return bindError(err)
+	}
+	return schema.Validate(dst)
+}
+
+func bindQuery(c *gin.Context, dst any) *schema.Err {
+	if err := c.ShouldBindQuery(dst); err != nil {
+		return bindError(err)
+	}
+	return schema.Validate(dst)
+}
@@ -4,15 +4,29 @@ import (
 	"net/http"
 
 	"github.com/gin-gonic/gin"
+	"plassstic.tech/trainee/avito/internal/health"
 	"plassstic.tech/trainee/avito/internal/schema"
 )
 
-func SetupHealthRoute(r *gin.Engine) {
-	r.GET("/health", health())
+func SetupHealthRoute(r *gin.Engine, h *health.Checker) {
+	r.GET("/health", live())
+	r.GET("/livez", live())
+	r.GET("/readyz", ready(h))
 }
 
-func health() gin.HandlerFunc {
+func live() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		c.JSON(http.StatusOK, schema.HealthResponse{Status: "ok"})
 	}
 }
+
+func ready(h *health.Checker) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		report := h.Run(c)
+		status := http.StatusOK
+		if !report.Ready() {
+			status = http.StatusServiceUnavailable
+		}
+		c.JSON(status, report)
+	}
+}
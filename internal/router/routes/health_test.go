@@ -0,0 +1,124 @@
+package routes
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"plassstic.tech/trainee/avito/internal/health"
+)
+
+func readyz(t *testing.T, h *health.Checker) (int, health.Report) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	SetupHealthRoute(r, h)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+
+	var report health.Report
+	if err := json.Unmarshal(w.Body.Bytes(), &report); err != nil {
+		t.Fatal(err)
+	}
+	return w.Code, report
+}
+
+func TestReadyz(t *testing.T) {
+	ok := func(context.Context) error { return nil }
+	down := func(context.Context) error { return errors.New("down") }
+
+	for _, tc := range []struct {
+		name   string
+		setup  func(h *health.Checker)
+		code   int
+		status string
+		// checks maps check names to the status they report
+		checks map[string]string
+	}{
+		{
+			name:   "ready",
+			setup:  func(h *health.Checker) { h.Add("db", true, ok) },
+			code:   http.StatusOK,
+			status: health.StatusOK,
+			checks: map[string]string{"db": health.StatusOK},
+		},
+		{
+			name:   "critical check failing",
+			setup:  func(h *health.Checker) { h.Add("db", true, down) },
+			code:   http.StatusServiceUnavailable,
+			status: health.StatusFailing,
+			checks: map[string]string{"db": health.StatusFailing},
+		},
+		{
+			name:   "optional check failing",
+			setup:  func(h *health.Checker) { h.Add("cache", false, down) },
+			code:   http.StatusOK,
+			status: health.StatusOK,
+			checks: map[string]string{"cache": health.StatusFailing},
+		},
+		{
+			name: "draining",
+			setup: func(h *health.Checker) {
+				h.Add("db", true, ok)
+				h.Drain()
+			},
+			code:   http.StatusServiceUnavailable,
+			status: health.StatusDraining,
+			checks: map[string]string{"db": health.StatusOK},
+		},
+		{
+			name: "workers",
+			setup: func(h *health.Checker) {
+				h.Worker("idle").Start()
+				failed := h.Worker("purge")
+				failed.Start()
+				failed.Done(errors.New("purge failed"))
+				h.Worker("stopped")
+			},
+			code:   http.StatusOK,
+			status: health.StatusOK,
+			checks: map[string]string{
+				"worker:idle":    health.StatusOK,
+				"worker:purge":   health.StatusFailing,
+				"worker:stopped": "stopped",
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			h := health.New()
+			tc.setup(h)
+
+			code, report := readyz(t, h)
+			if code != tc.code || report.Status != tc.status {
+				t.Fatalf("expected %d %s, got %d %s", tc.code, tc.status, code, report.Status)
+			}
+			if len(report.Checks) != len(tc.checks) {
+				t.Fatalf("expected checks %v, got %+v", tc.checks, report.Checks)
+			}
+			for name, status := range tc.checks {
+				if got := report.Checks[name]; got.Status != status || (status == health.StatusFailing) != (got.Error != "") {
+					t.Fatalf("expected %s %s, got %+v", name, status, got)
+				}
+			}
+		})
+	}
+}
+
+func TestLivezWhileDraining(t *testing.T) {
+	h := health.New()
+	h.Add("db", true, func(context.Context) error { return errors.New("down") })
+	h.Drain()
+
+	r := gin.New()
+	SetupHealthRoute(r, h)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/livez", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected /livez to stay up, got %d", w.Code)
+	}
+}
@@ -0,0 +1,136 @@
+package routes
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog"
+	"plassstic.tech/trainee/avito/internal/auth"
+	"plassstic.tech/trainee/avito/internal/idempotency"
+	"plassstic.tech/trainee/avito/internal/schema"
+)
+
+const ReplayedHeader = "Idempotent-Replayed"
+
+// replayedHeaders are stored with the response and sent again on replay.
+var replayedHeaders = []string{"ETag"}
+
+type capturingWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *capturingWriter) Write(b []byte) (int, error) {
+	w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *capturingWriter) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
+
+func idempotencyScope(c *gin.Context) string {
+	subject := "anonymous"
+	if id, ok := auth.FromContext(c); ok {
+		switch {
+		case id.UserID != "":
+			subject = "user:" + id.UserID
+		case id.KeyID != "":
+			subject = "key:" + id.KeyID
+		}
+	}
+	return fmt.Sprintf("%s %s %s", subject, c.Request.Method, c.FullPath())
+}
+
+// Idempotency replays the stored response of a POST repeated with the same
+// Idempotency-Key. Bodies over maxBody bytes are refused, the body is hashed
+// in memory. A key is released again when the handler fails with a 5xx or 429
+// or panics, so the client can retry.
+func Idempotency(store idempotency.Store, ttl time.Duration, maxBody int64) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader(idempotency.Header)
+		if key == "" || c.Request.Method != http.MethodPost {
+			c.Next()
+			return
+		}
+
+		body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxBody))
+		if err != nil {
+			code := schema.Unknown
+			if mbe := new(http.MaxBytesError); errors.As(err, &mbe) {
+				code = schema.RequestTooLarge
+			}
+			respondError(c, schema.Err{}.Wrap(code, err))
+			c.Abort()
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		hash := sha256.Sum256(body)
+		scope := idempotencyScope(c)
+
+		claimed, rec, err := store.Claim(c, key, scope, hash[:], ttl)
+		if err != nil {
+			respondError(c, schema.Err{}.Wrap(schema.Unknown, err))
+			c.Abort()
+			return
+		}
+
+		if !claimed {
+			switch {
+			case !bytes.Equal(rec.RequestHash, hash[:]):
+				respondError(c, schema.Err{}.Wrap(schema.IdempotencyKeyReused, fmt.Errorf("idempotency key %s was used with a different request", key)))
+			case !rec.Done:
+				respondError(c, schema.Err{}.Wrap(schema.IdempotencyInProgress, fmt.Errorf("request with idempotency key %s is still in progress", key)))
+			default:
+				for name, values := range rec.Headers {
+					c.Header(name, values[0])
+				}
+				c.Header(ReplayedHeader, "true")
+				c.Data(rec.StatusCode, gin.MIMEJSON+"; charset=utf-8", rec.Body)
+			}
+			c.Abort()
+			return
+		}
+
+		w := &capturingWriter{ResponseWriter: c.Writer}
+		c.Writer = w
+
+		ctx := context.WithoutCancel(c.Request.Context())
+		completed := false
+		defer func() {
+			// also runs while a handler panics on its way to gin.Recovery
+			if completed {
+				return
+			}
+			if err := store.Release(ctx, key, scope); err != nil {
+				zerolog.Ctx(ctx).Error().Err(err).Str("key", key).Msg("failed to release idempotency key")
+			}
+		}()
+
+		c.Next()
+
+		if w.Status() >= http.StatusInternalServerError || w.Status() == http.StatusTooManyRequests {
+			return
+		}
+		headers := http.Header{}
+		for _, name := range replayedHeaders {
+			if v := w.Header().Get(name); v != "" {
+				headers.Set(name, v)
+			}
+		}
+		if err = store.Complete(ctx, key, scope, w.Status(), w.body.Bytes(), headers); err != nil {
+			zerolog.Ctx(ctx).Error().Err(err).Str("key", key).Msg("failed to store idempotent response")
+			return
+		}
+		completed = true
+	}
+}
@@ -0,0 +1,120 @@
+package routes
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"plassstic.tech/trainee/avito/internal/idempotency"
+	"plassstic.tech/trainee/avito/internal/schema"
+)
+
+// newIdempotent serves handler at POST /do behind the middleware, with
+// gin.Recovery outside of it as in the router.
+func newIdempotent(store idempotency.Store, handler gin.HandlerFunc) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(gin.Recovery())
+	r.POST("/do", Idempotency(store, time.Hour, 64), handler)
+	return r
+}
+
+func do(r http.Handler, key, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/do", strings.NewReader(body))
+	req.Header.Set(idempotency.Header, key)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func errorCode(t *testing.T, w *httptest.ResponseRecorder) schema.ErrorCode {
+	t.Helper()
+	var res schema.ErrorResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &res); err != nil {
+		t.Fatal(err)
+	}
+	return res.Code
+}
+
+func TestIdempotencyReplay(t *testing.T) {
+	calls := 0
+	r := newIdempotent(idempotency.NewMemory(), func(c *gin.Context) {
+		calls++
+		c.Header("ETag", `"1"`)
+		c.JSON(http.StatusCreated, gin.H{"calls": calls})
+	})
+
+	first := do(r, "k", `{"a":1}`)
+	second := do(r, "k", `{"a":1}`)
+	if calls != 1 {
+		t.Fatalf("expected one call, got %d", calls)
+	}
+	if second.Code != http.StatusCreated || second.Body.String() != first.Body.String() {
+		t.Fatalf("expected %d %s replayed, got %d %s", first.Code, first.Body, second.Code, second.Body)
+	}
+	if second.Header().Get("ETag") != `"1"` || second.Header().Get(ReplayedHeader) != "true" {
+		t.Fatalf("expected the ETag replayed, got %v", second.Header())
+	}
+
+	if w := do(r, "k", `{"a":2}`); w.Code != http.StatusUnprocessableEntity || errorCode(t, w) != schema.IdempotencyKeyReused {
+		t.Fatalf("expected the key refused for another body, got %d %s", w.Code, w.Body)
+	}
+}
+
+func TestIdempotencyInProgress(t *testing.T) {
+	store := idempotency.NewMemory()
+	r := newIdempotent(store, func(c *gin.Context) { c.Status(http.StatusNoContent) })
+
+	hash := sha256.Sum256([]byte("{}"))
+	if _, _, err := store.Claim(t.Context(), "k", "anonymous POST /do", hash[:], time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	if w := do(r, "k", "{}"); w.Code != http.StatusConflict || errorCode(t, w) != schema.IdempotencyInProgress {
+		t.Fatalf("expected the key in progress, got %d %s", w.Code, w.Body)
+	}
+}
+
+func TestIdempotencyRelease(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		fail gin.HandlerFunc
+	}{
+		{"server error", func(c *gin.Context) { c.Status(http.StatusInternalServerError) }},
+		{"rate limited", func(c *gin.Context) { c.Status(http.StatusTooManyRequests) }},
+		{"panic", func(*gin.Context) { panic("boom") }},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			calls := 0
+			r := newIdempotent(idempotency.NewMemory(), func(c *gin.Context) {
+				if calls++; calls == 1 {
+					tc.fail(c)
+					return
+				}
+				c.Status(http.StatusNoContent)
+			})
+
+			if w := do(r, "k", "{}"); w.Code < http.StatusTooManyRequests {
+				t.Fatalf("expected the first call to fail, got %d", w.Code)
+			}
+			if w := do(r, "k", "{}"); w.Code != http.StatusNoContent || calls != 2 {
+				t.Fatalf("expected the retry to run, got %d after %d calls", w.Code, calls)
+			}
+		})
+	}
+}
+
+func TestIdempotencyBodyLimit(t *testing.T) {
+	r := newIdempotent(idempotency.NewMemory(), func(c *gin.Context) { c.Status(http.StatusNoContent) })
+
+	if w := do(r, "k", strings.Repeat("x", 65)); w.Code != http.StatusRequestEntityTooLarge || errorCode(t, w) != schema.RequestTooLarge {
+		t.Fatalf("expected the body refused, got %d %s", w.Code, w.Body)
+	}
+	if w := do(r, "k", strings.Repeat("x", 64)); w.Code != http.StatusNoContent {
+		t.Fatalf("expected a body at the limit through, got %d %s", w.Code, w.Body)
+	}
+}
@@ -0,0 +1,59 @@
+package routes
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"plassstic.tech/trainee/avito/internal/auth"
+	"plassstic.tech/trainee/avito/internal/metrics"
+	"plassstic.tech/trainee/avito/internal/schema"
+)
+
+func Metrics() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		status := strconv.Itoa(c.Writer.Status())
+
+		metrics.HTTPRequests.WithLabelValues(route, c.Request.Method, status).Inc()
+		metrics.HTTPDuration.WithLabelValues(route, c.Request.Method, status).Observe(time.Since(start).Seconds())
+	}
+}
+
+// MetricsHandler serves the process wide metrics together with g.
+func MetricsHandler(g prometheus.Gatherer) http.Handler {
+	return promhttp.HandlerFor(prometheus.Gatherers{metrics.Registry, g}, promhttp.HandlerOpts{})
+}
+
+// SetupMetricsRoute exposes /metrics behind the given guards.
+func SetupMetricsRoute(r gin.IRoutes, g prometheus.Gatherer, guards ...gin.HandlerFunc) {
+	r.GET("/metrics", append(guards, gin.WrapH(MetricsHandler(g)))...)
+}
+
+// MetricsToken requires token as a bearer token.
+func MetricsToken(token string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if subtle.ConstantTimeCompare([]byte(auth.BearerToken(c.Request)), []byte(token)) != 1 {
+			respondError(c, schema.Err{}.Wrap(schema.Unauthorized, fmt.Errorf("metrics token is required")))
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// AdminOnly lets through callers the API's authentication found to be admins.
+func AdminOnly() gin.HandlerFunc {
+	return require(schema.RoleAdmin)
+}
@@ -0,0 +1,48 @@
+package routes
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"plassstic.tech/trainee/avito/internal/schema"
+)
+
+// parseIfMatch reads strong entity tags of the form "N". Weak and malformed
+// tags are kept out of the list, so they never match, as the RFC requires.
+func parseIfMatch(header string) schema.Precondition {
+	var p schema.Precondition
+	for tag := range strings.SplitSeq(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" {
+			p.Any = true
+			continue
+		}
+		unquoted, ok := strings.CutPrefix(tag, `"`)
+		if !ok {
+			continue
+		}
+		if unquoted, ok = strings.CutSuffix(unquoted, `"`); !ok {
+			continue
+		}
+		if v, err := strconv.ParseInt(unquoted, 10, 64); err == nil {
+			p.Versions = append(p.Versions, v)
+		}
+	}
+	return p
+}
+
+// ifMatch reads the If-Match header for the repository, which checks it once
+// the PR row is locked; it is nil when the request has none.
+func ifMatch(c *gin.Context) *schema.Precondition {
+	header := c.GetHeader("If-Match")
+	if header == "" {
+		return nil
+	}
+	p := parseIfMatch(header)
+	return &p
+}
+
+func setETag(c *gin.Context, pr *schema.PullRequest) {
+	c.Header("ETag", strconv.Quote(strconv.FormatInt(pr.Version, 10)))
+}
@@ -6,19 +6,21 @@ import (
 	"github.com/gin-gonic/gin"
 	"plassstic.tech/trainee/avito/internal/schema"
 	"plassstic.tech/trainee/avito/internal/service"
+	"plassstic.tech/trainee/avito/internal/utils"
 )
 
 func SetupPRRoutes(pr *gin.RouterGroup, service service.Service) {
-	pr.POST("/create", createPR(service))
-	pr.POST("/merge", mergePR(service))
-	pr.POST("/reassign", reassignReviewer(service))
+	pr.POST("/create", require(schema.RoleUser), createPR(service))
+	pr.POST("/createBatch", require(schema.RoleUser), createPRBatch(service))
+	pr.POST("/merge", require(schema.RoleUser), mergePR(service))
+	pr.POST("/reassign", require(schema.RoleUser), reassignReviewer(service))
 }
 
 func createPR(service service.Service) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		var req schema.CreatePRRequest
-		if err := c.ShouldBindJSON(&req); err != nil {
-			respondError(c, schema.Err{}.Wrap(schema.Unknown, err))
+		if serr := bindJSON(c, &req); serr != nil {
+			respondError(c, serr)
 			return
 		}
 
@@ -28,24 +30,65 @@ func createPR(service service.Service) gin.HandlerFunc {
 			return
 		}
 
+		setETag(c, result)
 		c.JSON(http.StatusCreated, schema.PRResponse{PR: *result})
 	}
 }
 
+func createPRBatch(service service.Service) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req schema.CreatePRBatchRequest
+		if serr := bindJSON(c, &req); serr != nil {
+			respondError(c, serr)
+			return
+		}
+
+		result, serr := service.CreatePRBatch(c, req)
+		if serr != nil {
+			respondError(c, serr)
+			return
+		}
+
+		// 201 when everything was created, 200 for a partial best-effort
+		// batch and the first item's error status for a rolled back one
+		status := http.StatusCreated
+		for i, item := range result.Results {
+			if item.Error == nil {
+				continue
+			}
+			public := item.Error.Public()
+			result.Results[i].Error = &public
+			if status != http.StatusCreated {
+				continue
+			}
+			status = http.StatusOK
+			if result.Mode == schema.BatchAtomic {
+				status = statusOf(item.Error.Code)
+				rolledBack := public
+				rolledBack.RequestID = utils.RequestID(c)
+				result.Error = &rolledBack
+			}
+		}
+
+		c.JSON(status, result)
+	}
+}
+
 func mergePR(service service.Service) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		var req schema.MergePRRequest
-		if err := c.ShouldBindJSON(&req); err != nil {
-			respondError(c, schema.Err{}.Wrap(schema.Unknown, err))
+		if serr := bindJSON(c, &req); serr != nil {
+			respondError(c, serr)
 			return
 		}
 
-		result, serr := service.MergePR(c, req.PRId)
+		result, serr := service.MergePR(c, req.PRId, ifMatch(c))
 		if serr != nil {
 			respondError(c, serr)
 			return
 		}
 
+		setETag(c, result)
 		c.JSON(http.StatusOK, schema.PRResponse{PR: *result})
 	}
 }
@@ -53,17 +96,18 @@ func mergePR(service service.Service) gin.HandlerFunc {
 func reassignReviewer(service service.Service) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		var req schema.ReassignReviewerRequest
-		if err := c.ShouldBindJSON(&req); err != nil {
-			respondError(c, schema.Err{}.Wrap(schema.Unknown, err))
+		if serr := bindJSON(c, &req); serr != nil {
+			respondError(c, serr)
 			return
 		}
 
-		newID, pr, serr := service.ReassignReviewer(c, req.PRId, req.OldUserID)
+		newID, pr, serr := service.ReassignReviewer(c, req.PRId, req.OldUserID, ifMatch(c))
 		if serr != nil {
 			respondError(c, serr)
 			return
 		}
 
+		setETag(c, pr)
 		c.JSON(http.StatusOK, schema.ReassignResponse{
 			PR:      *pr,
 			NewUser: newID,
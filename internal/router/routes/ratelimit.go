@@ -0,0 +1,49 @@
+package routes
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog"
+	"plassstic.tech/trainee/avito/internal/auth"
+	"plassstic.tech/trainee/avito/internal/metrics"
+	"plassstic.tech/trainee/avito/internal/ratelimit"
+	"plassstic.tech/trainee/avito/internal/schema"
+)
+
+// RateLimit buckets requests per route group and per caller, callers are told
+// apart by api key or user when byKey is set and the client address otherwise.
+// It fails open: when the limiter backend errors the request goes through and
+// avito_ratelimit_errors_total is counted.
+func RateLimit(limiter ratelimit.Limiter, group string, limit ratelimit.Limit, byKey bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		subject := "ip:" + c.ClientIP()
+		if id, ok := auth.FromContext(c); ok && byKey {
+			switch {
+			case id.KeyID != "":
+				subject = "key:" + id.KeyID
+			case id.UserID != "":
+				subject = "user:" + id.UserID
+			}
+		}
+
+		ok, retryAfter, err := limiter.Allow(c, group+" "+subject, limit)
+		if err != nil {
+			zerolog.Ctx(c).Error().Err(err).Str("group", group).Msg("rate limiter failed, letting request through")
+			metrics.RateLimitErrors.WithLabelValues(group).Inc()
+			c.Next()
+			return
+		}
+
+		if !ok {
+			c.Header("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+			respondError(c, schema.Err{}.Wrap(schema.RateLimited, fmt.Errorf("rate limit for %s exceeded, retry in %s", group, retryAfter)))
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -0,0 +1,94 @@
+package routes
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"plassstic.tech/trainee/avito/internal/auth"
+	"plassstic.tech/trainee/avito/internal/metrics"
+	"plassstic.tech/trainee/avito/internal/ratelimit"
+	"plassstic.tech/trainee/avito/internal/schema"
+)
+
+// failingLimiter stands in for a limiter whose backend is down.
+type failingLimiter struct{}
+
+func (failingLimiter) Allow(context.Context, string, ratelimit.Limit) (bool, time.Duration, error) {
+	return false, 0, errors.New("backend down")
+}
+
+func (failingLimiter) Cleanup(context.Context, time.Duration) error { return nil }
+
+// newLimited serves GET /do behind the limiter, acting as the caller with the
+// given key id when it is set.
+func newLimited(limiter ratelimit.Limiter, limit ratelimit.Limit, byKey bool) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.ContextWithFallback = true
+	r.Use(func(c *gin.Context) {
+		if key := c.GetHeader("X-Test-Key"); key != "" {
+			c.Request = c.Request.WithContext(auth.WithIdentity(c.Request.Context(), auth.Identity{KeyID: key, Role: schema.RoleUser}))
+		}
+	})
+	r.GET("/do", RateLimit(limiter, "group", limit, byKey), func(c *gin.Context) { c.Status(http.StatusNoContent) })
+	return r
+}
+
+func get(r http.Handler, key string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, "/do", nil)
+	req.Header.Set("X-Test-Key", key)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestRateLimitRetryAfter(t *testing.T) {
+	r := newLimited(ratelimit.NewMemory(), ratelimit.Limit{Rate: 0.25, Burst: 1}, true)
+
+	if w := get(r, "a"); w.Code != http.StatusNoContent {
+		t.Fatalf("expected the first request through, got %d", w.Code)
+	}
+	w := get(r, "a")
+	if w.Code != http.StatusTooManyRequests || errorCode(t, w) != schema.RateLimited {
+		t.Fatalf("expected the second request limited, got %d %s", w.Code, w.Body)
+	}
+	if got := w.Header().Get("Retry-After"); got != "4" {
+		t.Fatalf("expected Retry-After 4, got %q", got)
+	}
+}
+
+func TestRateLimitSubject(t *testing.T) {
+	limit := ratelimit.Limit{Rate: 0.001, Burst: 1}
+
+	byKey := newLimited(ratelimit.NewMemory(), limit, true)
+	get(byKey, "a")
+	if w := get(byKey, "b"); w.Code != http.StatusNoContent {
+		t.Fatalf("expected keys limited apart, got %d", w.Code)
+	}
+
+	byIP := newLimited(ratelimit.NewMemory(), limit, false)
+	get(byIP, "a")
+	if w := get(byIP, "b"); w.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected one address limited together, got %d", w.Code)
+	}
+}
+
+func TestRateLimitFailsOpen(t *testing.T) {
+	r := newLimited(failingLimiter{}, ratelimit.Limit{Rate: 1, Burst: 1}, true)
+	before := testutil.ToFloat64(metrics.RateLimitErrors.WithLabelValues("group"))
+
+	for range 3 {
+		if w := get(r, "a"); w.Code != http.StatusNoContent {
+			t.Fatalf("expected the request through, got %d", w.Code)
+		}
+	}
+	if got := testutil.ToFloat64(metrics.RateLimitErrors.WithLabelValues("group")) - before; got != 3 {
+		t.Fatalf("expected 3 limiter errors counted, got %v", got)
+	}
+}
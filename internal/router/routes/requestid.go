@@ -0,0 +1,68 @@
+package routes
+
+import (
+	"time"
+	"unicode"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"plassstic.tech/trainee/avito/internal/tracing"
+	"plassstic.tech/trainee/avito/internal/utils"
+)
+
+const (
+	RequestIDHeader = "X-Request-ID"
+
+	maxRequestIDLength = 128
+)
+
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for _, r := range id {
+		if r > unicode.MaxASCII || !unicode.IsPrint(r) {
+			return false
+		}
+	}
+	return true
+}
+
+// RequestID accepts the caller's X-Request-ID or makes one up, echoes it back
+// and puts a logger tagged with it into the request context.
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(RequestIDHeader)
+		if !validRequestID(id) {
+			id = utils.NewRequestID()
+		}
+		c.Header(RequestIDHeader, id)
+
+		lc := log.With().Str("request_id", id)
+		if traceID, ok := tracing.TraceID(c.Request.Context()); ok {
+			lc = lc.Str("trace_id", traceID)
+		}
+		logger := lc.Logger()
+		ctx := utils.WithRequestID(c.Request.Context(), id)
+		c.Request = c.Request.WithContext(logger.WithContext(ctx))
+		tagSpan(c)
+
+		c.Next()
+	}
+}
+
+func AccessLog() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		zerolog.Ctx(c).Info().
+			Str("method", c.Request.Method).
+			Str("path", c.Request.URL.Path).
+			Int("status", c.Writer.Status()).
+			Dur("latency", time.Since(start)).
+			Str("client_ip", c.ClientIP()).
+			Msg("request")
+	}
+}
@@ -1,6 +1,7 @@
 package routes
 
 import (
+	"context"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
@@ -9,15 +10,21 @@ import (
 )
 
 func SetupTeamRoutes(team *gin.RouterGroup, service service.Service) {
-	team.POST("/add", addTeam(service))
-	team.GET("/get", getTeam(service))
+	team.POST("/add", require(schema.RoleTeamLead), addTeam(service))
+	team.GET("/get", require(schema.RoleUser), getTeam(service))
+	team.POST("/addMembers", require(schema.RoleTeamLead), addTeamMembers(service))
+	team.POST("/removeMembers", require(schema.RoleTeamLead), removeTeamMembers(service))
+	team.POST("/moveMember", require(schema.RoleTeamLead), moveTeamMember(service))
+	team.POST("/setParent", require(schema.RoleTeamLead), setTeamParent(service))
+	team.POST("/delete", require(schema.RoleTeamLead), deleteTeam(service.DeleteTeam))
+	team.POST("/purge", require(schema.RoleAdmin), deleteTeam(service.PurgeTeam))
 }
 
 func addTeam(service service.Service) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		var team schema.Team
-		if err := c.ShouldBindJSON(&team); err != nil {
-			respondError(c, schema.Err{}.Wrap(schema.Unknown, err))
+		if serr := bindJSON(c, &team); serr != nil {
+			respondError(c, serr)
 			return
 		}
 
@@ -33,13 +40,67 @@ func addTeam(service service.Service) gin.HandlerFunc {
 
 func getTeam(service service.Service) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		teamName := c.Query("team_name")
-		if teamName == "" {
-			respondError(c, &schema.Err{Code: schema.Unknown, Msg: "team_name is required"})
+		var query schema.GetTeamQuery
+		if serr := bindQuery(c, &query); serr != nil {
+			respondError(c, serr)
+			return
+		}
+
+		result, serr := service.GetTeam(c, query.TeamName)
+		if serr != nil {
+			respondError(c, serr)
+			return
+		}
+
+		c.JSON(http.StatusOK, result)
+	}
+}
+
+func addTeamMembers(service service.Service) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req schema.AddTeamMembersRequest
+		if serr := bindJSON(c, &req); serr != nil {
+			respondError(c, serr)
+			return
+		}
+
+		result, serr := service.AddTeamMembers(c, req)
+		if serr != nil {
+			respondError(c, serr)
+			return
+		}
+
+		c.JSON(http.StatusOK, schema.AddTeamResponse{Team: *result})
+	}
+}
+
+func removeTeamMembers(service service.Service) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req schema.RemoveTeamMembersRequest
+		if serr := bindJSON(c, &req); serr != nil {
+			respondError(c, serr)
+			return
+		}
+
+		result, serr := service.RemoveTeamMembers(c, req)
+		if serr != nil {
+			respondError(c, serr)
+			return
+		}
+
+		c.JSON(http.StatusOK, schema.AddTeamResponse{Team: *result})
+	}
+}
+
+func moveTeamMember(service service.Service) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req schema.MoveTeamMemberRequest
+		if serr := bindJSON(c, &req); serr != nil {
+			respondError(c, serr)
 			return
 		}
 
-		result, serr := service.GetTeam(c, teamName)
+		result, serr := service.MoveTeamMember(c, req)
 		if serr != nil {
 			respondError(c, serr)
 			return
@@ -48,3 +109,40 @@ func getTeam(service service.Service) gin.HandlerFunc {
 		c.JSON(http.StatusOK, result)
 	}
 }
+
+func setTeamParent(service service.Service) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req schema.SetTeamParentRequest
+		if serr := bindJSON(c, &req); serr != nil {
+			respondError(c, serr)
+			return
+		}
+
+		result, serr := service.SetTeamParent(c, req)
+		if serr != nil {
+			respondError(c, serr)
+			return
+		}
+
+		c.JSON(http.StatusOK, schema.AddTeamResponse{Team: *result})
+	}
+}
+
+// deleteTeam serves both the soft delete and the purge.
+func deleteTeam(remove func(context.Context, string) (*schema.Team, *schema.Err)) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req schema.DeleteTeamRequest
+		if serr := bindJSON(c, &req); serr != nil {
+			respondError(c, serr)
+			return
+		}
+
+		result, serr := remove(c, req.TeamName)
+		if serr != nil {
+			respondError(c, serr)
+			return
+		}
+
+		c.JSON(http.StatusOK, schema.AddTeamResponse{Team: *result})
+	}
+}
@@ -0,0 +1,24 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"plassstic.tech/trainee/avito/internal/utils"
+)
+
+// Tracing opens a server span per request, continuing the caller's trace
+// when a traceparent header is present.
+func Tracing(service string) gin.HandlerFunc {
+	return otelgin.Middleware(service,
+		otelgin.WithGinFilter(func(c *gin.Context) bool {
+			return c.FullPath() != "/metrics"
+		}),
+	)
+}
+
+func tagSpan(c *gin.Context) {
+	span := trace.SpanFromContext(c.Request.Context())
+	span.SetAttributes(attribute.String("request.id", utils.RequestID(c.Request.Context())))
+}
@@ -1,6 +1,7 @@
 package routes
 
 import (
+	"context"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
@@ -9,15 +10,17 @@ import (
 )
 
 func SetupUsersRoutes(users *gin.RouterGroup, service service.Service) {
-	users.POST("/setIsActive", setUserActive(service))
-	users.GET("/getReview", getUserReviews(service))
+	users.POST("/setIsActive", require(schema.RoleUser), setUserActive(service))
+	users.GET("/getReview", require(schema.RoleUser), getUserReviews(service))
+	users.POST("/delete", require(schema.RoleTeamLead), deleteUser(service.DeleteUser))
+	users.POST("/purge", require(schema.RoleAdmin), deleteUser(service.PurgeUser))
 }
 
 func setUserActive(service service.Service) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		var req schema.SetUserActiveRequest
-		if err := c.ShouldBindJSON(&req); err != nil {
-			respondError(c, schema.Err{}.Wrap(schema.Unknown, err))
+		if serr := bindJSON(c, &req); serr != nil {
+			respondError(c, serr)
 			return
 		}
 
@@ -33,21 +36,41 @@ func setUserActive(service service.Service) gin.HandlerFunc {
 
 func getUserReviews(service service.Service) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		userID := c.Query("user_id")
-		if userID == "" {
-			respondError(c, &schema.Err{Code: schema.Unknown, Msg: "user_id is required"})
+		var query schema.GetUserReviewsQuery
+		if serr := bindQuery(c, &query); serr != nil {
+			respondError(c, serr)
 			return
 		}
 
-		prs, serr := service.GetUserReviews(c, userID)
+		prs, serr := service.GetUserReviews(c, query.UserID)
 		if serr != nil {
 			respondError(c, serr)
 			return
 		}
 
 		c.JSON(http.StatusOK, schema.UserReviewsResponse{
-			UserID:       userID,
+			UserID:       query.UserID,
 			PullRequests: prs,
 		})
 	}
 }
+
+// deleteUser serves both the soft delete and the purge, which take and
+// return the same things.
+func deleteUser(remove func(context.Context, string) (*schema.DeleteUserResponse, *schema.Err)) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req schema.DeleteUserRequest
+		if serr := bindJSON(c, &req); serr != nil {
+			respondError(c, serr)
+			return
+		}
+
+		result, serr := remove(c, req.UserID)
+		if serr != nil {
+			respondError(c, serr)
+			return
+		}
+
+		c.JSON(http.StatusOK, result)
+	}
+}
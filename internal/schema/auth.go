@@ -0,0 +1,77 @@
+package schema
+
+import (
+	"plassstic.tech/trainee/avito/gensql"
+)
+
+type Role string
+
+const (
+	RoleAdmin    Role = "admin"
+	RoleTeamLead Role = "team_lead"
+	RoleUser     Role = "user"
+)
+
+func (r Role) rank() int {
+	switch r {
+	case RoleAdmin:
+		return 3
+	case RoleTeamLead:
+		return 2
+	case RoleUser:
+		return 1
+	default:
+		return 0
+	}
+}
+
+func (r Role) Valid() bool {
+	return r.rank() > 0
+}
+
+func (r Role) AtLeast(min Role) bool {
+	return r.rank() >= min.rank()
+}
+
+type APIKey struct {
+	KeyID     string `json:"key_id"`
+	Key       string `json:"key,omitempty"`
+	UserID    string `json:"user_id,omitempty"`
+	Role      Role   `json:"role"`
+	CreatedAt string `json:"created_at"`
+	RevokedAt string `json:"revoked_at,omitempty"`
+
+	Hash []byte `json:"-"`
+}
+
+func (APIKey) FromDDL(ddl gensql.ApiKey) *APIKey {
+	var revokedAt string
+	if ddl.RevokedAt.Valid {
+		revokedAt = ddl.RevokedAt.Time.Format("2006-01-02 15:04:05")
+	}
+	return &APIKey{
+		KeyID:     ddl.KeyID,
+		UserID:    ddl.UserID.String,
+		Role:      Role(ddl.Role),
+		CreatedAt: ddl.CreatedAt.Time.Format("2006-01-02 15:04:05"),
+		RevokedAt: revokedAt,
+		Hash:      ddl.KeyHash,
+	}
+}
+
+func (k APIKey) Revoked() bool {
+	return k.RevokedAt != ""
+}
+
+type IssueAPIKeyRequest struct {
+	UserID string `json:"user_id" validate:"omitempty,id"`
+	Role   Role   `json:"role" validate:"required,oneof=admin team_lead user"`
+}
+
+type RevokeAPIKeyRequest struct {
+	KeyID string `json:"key_id" validate:"required,hexadecimal,len=16"`
+}
+
+type APIKeyResponse struct {
+	Key APIKey `json:"api_key"`
+}
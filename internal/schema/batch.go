@@ -0,0 +1,40 @@
+package schema
+
+type BatchMode string
+
+const (
+	// BatchAtomic creates every PR of the batch or none of them.
+	BatchAtomic BatchMode = "atomic"
+	// BatchBestEffort creates the PRs that can be created and reports the rest.
+	BatchBestEffort BatchMode = "best_effort"
+)
+
+type BatchStatus string
+
+const (
+	BatchCreated    BatchStatus = "created"
+	BatchFailed     BatchStatus = "failed"
+	BatchRolledBack BatchStatus = "rolled_back"
+)
+
+type CreatePRBatchRequest struct {
+	Mode         BatchMode         `json:"mode" validate:"omitempty,oneof=atomic best_effort"`
+	PullRequests []CreatePRRequest `json:"pull_requests" validate:"required,min=1,max=500,dive"`
+}
+
+type PRBatchItem struct {
+	PRId   string       `json:"pull_request_id"`
+	Status BatchStatus  `json:"status"`
+	PR     *PullRequest `json:"pr,omitempty"`
+	Error  *Err         `json:"error,omitempty"`
+}
+
+type CreatePRBatchResponse struct {
+	Mode    BatchMode     `json:"mode"`
+	Created int           `json:"created"`
+	Failed  int           `json:"failed"`
+	Results []PRBatchItem `json:"results"`
+	// Error repeats the first item error when an atomic batch was rolled
+	// back, so the body still reads as a regular error response.
+	Error *Err `json:"error,omitempty"`
+}
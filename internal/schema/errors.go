@@ -12,11 +12,35 @@ const (
 	NoCandidate ErrorCode = "NO_CANDIDATE"
 	NotFound    ErrorCode = "NOT_FOUND"
 	Unknown     ErrorCode = "UNKNOWN"
+
+	Unauthorized     ErrorCode = "UNAUTHORIZED"
+	InsufficientRole ErrorCode = "INSUFFICIENT_ROLE"
+	Forbidden        ErrorCode = "FORBIDDEN"
+
+	IdempotencyKeyReused  ErrorCode = "IDEMPOTENCY_KEY_REUSED"
+	IdempotencyInProgress ErrorCode = "IDEMPOTENCY_IN_PROGRESS"
+	RequestTooLarge       ErrorCode = "REQUEST_TOO_LARGE"
+	RateLimited           ErrorCode = "RATE_LIMITED"
+	ValidationFailed      ErrorCode = "VALIDATION_FAILED"
+
+	TooManyReviewers ErrorCode = "TOO_MANY_REVIEWERS"
+	UserInOtherTeam  ErrorCode = "USER_IN_OTHER_TEAM"
+	TeamCycle        ErrorCode = "TEAM_CYCLE"
+	Conflict         ErrorCode = "CONFLICT"
+
+	PreconditionFailed ErrorCode = "PRECONDITION_FAILED"
 )
 
+const internalMsg = "internal error"
+
 type Err struct {
-	Code ErrorCode `json:"code,omitempty"`
-	Msg  string    `json:"msg,omitempty"`
+	Code      ErrorCode      `json:"code,omitempty"`
+	Msg       string         `json:"msg,omitempty"`
+	Details   map[string]any `json:"details,omitempty"`
+	Fields    []FieldError   `json:"fields,omitempty"`
+	RequestID string         `json:"request_id,omitempty"`
+
+	cause error
 }
 
 type ErrorResponse struct {
@@ -27,6 +51,34 @@ func (e Err) Error() string {
 	return fmt.Sprintf("%s: %s", e.Code, e.Msg)
 }
 
+func (e Err) Unwrap() error {
+	return e.cause
+}
+
 func (Err) Wrap(code ErrorCode, err error) *Err {
-	return &Err{Code: code, Msg: err.Error()}
+	return &Err{Code: code, Msg: err.Error(), cause: err}
+}
+
+func (e *Err) With(key string, value any) *Err {
+	if e.Details == nil {
+		e.Details = make(map[string]any)
+	}
+	e.Details[key] = value
+	return e
+}
+
+func (e Err) Internal() bool {
+	return e.Code == Unknown || e.Code == ""
+}
+
+// Public returns a copy that is safe to send to clients: messages of
+// internal errors may carry driver or SQL details and are replaced.
+func (e Err) Public() Err {
+	e.cause = nil
+	if e.Internal() {
+		e.Code = Unknown
+		e.Msg = internalMsg
+		e.Details = nil
+	}
+	return e
 }
@@ -0,0 +1,27 @@
+package schema
+
+import (
+	"fmt"
+	"slices"
+)
+
+// Precondition is a parsed If-Match header: either "*" or a list of PR versions.
+type Precondition struct {
+	Any      bool
+	Versions []int64
+}
+
+func (p Precondition) Match(version int64) bool {
+	return p.Any || slices.Contains(p.Versions, version)
+}
+
+// Check fails when the PR's current version does not satisfy p; a nil p, a
+// request without If-Match, always passes.
+func (p *Precondition) Check(prID string, version int64) *Err {
+	if p != nil && !p.Match(version) {
+		return Err{}.Wrap(PreconditionFailed, fmt.Errorf("PR %s is at version %d", prID, version)).
+			With("pull_request_id", prID).
+			With("version", version)
+	}
+	return nil
+}
@@ -1,13 +1,19 @@
 package schema
 
 import (
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
 	"plassstic.tech/trainee/avito/gensql"
 )
 
 type TeamMember struct {
-	UserID   string `db:"user_id" json:"user_id"`
-	UserName string `db:"user_name" json:"username"`
+	UserID   string `db:"user_id" json:"user_id" validate:"required,id"`
+	UserName string `db:"user_name" json:"username" validate:"required,name,max=128"`
 	IsActive bool   `db:"is_active" json:"is_active"`
+	// IsPrimary marks the team as the member's primary one. Setting it when
+	// adding a member moves their primary flag here.
+	IsPrimary bool `db:"is_primary" json:"is_primary"`
 }
 
 func (TeamMember) FromDDL(user gensql.User) TeamMember {
@@ -18,11 +24,22 @@ func (TeamMember) FromDDL(user gensql.User) TeamMember {
 	}
 }
 
+func (TeamMember) FromRow(row gensql.GetUsersForTeamRow) TeamMember {
+	return TeamMember{
+		UserID:    row.UserID,
+		UserName:  row.UserName,
+		IsActive:  row.IsActive,
+		IsPrimary: row.IsPrimary,
+	}
+}
+
 type User struct {
-	UserID   string `db:"user_id"`
-	UserName string `db:"user_name"`
-	TeamName string `db:"team_name"`
-	IsActive bool   `db:"is_active"`
+	UserID   string `db:"user_id" json:"user_id"`
+	UserName string `db:"user_name" json:"username"`
+	// TeamName is the primary team, Teams lists every team with it first.
+	TeamName string   `db:"team_name" json:"team_name"`
+	Teams    []string `json:"teams"`
+	IsActive bool     `db:"is_active" json:"is_active"`
 }
 
 func (u User) EnsureSchema() gensql.EnsureUsersParams {
@@ -33,6 +50,14 @@ func (u User) EnsureSchema() gensql.EnsureUsersParams {
 	}
 }
 
+func (u User) InsertSchema() gensql.InsertUsersParams {
+	return gensql.InsertUsersParams{
+		UserID:   u.UserID,
+		UserName: u.UserName,
+		IsActive: u.IsActive,
+	}
+}
+
 func (u User) AddToTeamSchema(teamName string) gensql.AddUsersToTeamParams {
 	return gensql.AddUsersToTeamParams{
 		TeamName: teamName,
@@ -48,11 +73,31 @@ func (User) FromDDL(user gensql.User) User {
 	}
 }
 
+func (User) FromRowWithTeam(row gensql.GetUserWithTeamRow) *User {
+	return &User{
+		UserID:   row.UserID,
+		UserName: row.UserName,
+		TeamName: row.TeamName.String,
+		Teams:    []string{},
+		IsActive: row.IsActive,
+	}
+}
+
 type Team struct {
-	TeamName string       `db:"team_name" json:"team_name"`
-	Members  []TeamMember `json:"members"`
+	TeamName   string       `db:"team_name" json:"team_name" validate:"required,name,max=64"`
+	ParentTeam string       `db:"parent_team" json:"parent_team,omitempty" validate:"omitempty,name,max=64"`
+	Members    []TeamMember `json:"members" validate:"dive"`
 }
 
+// TeamTree is a team with every team below it. ActiveMembers counts the
+// distinct active users of the whole subtree.
+type TeamTree struct {
+	Team
+	ActiveMembers int        `json:"active_members"`
+	SubTeams      []TeamTree `json:"sub_teams"`
+}
+
+// PullRequestShort has an empty AuthorId once the author has been purged.
 type PullRequestShort struct {
 	PRId     string        `db:"pull_req_id" json:"pull_request_id"`
 	Name     string        `db:"pull_req_name" json:"pull_request_name"`
@@ -64,7 +109,7 @@ func (PullRequestShort) FromDDL(ddl gensql.GetPRsReviewedByUserRow) PullRequestS
 	return PullRequestShort{
 		PRId:     ddl.PullReqID,
 		Name:     ddl.PullReqName,
-		AuthorId: ddl.AuthorID,
+		AuthorId: ddl.AuthorID.String,
 		Status:   ddl.PullReqStatus,
 	}
 }
@@ -72,8 +117,10 @@ func (PullRequestShort) FromDDL(ddl gensql.GetPRsReviewedByUserRow) PullRequestS
 type PullRequest struct {
 	PullRequestShort
 	AssignedReviewers []string `json:"assigned_reviewers"`
+	TeamName          string   `db:"team_name" json:"team_name"`
 	CreatedAt         string   `db:"created_at" json:"createdAt"`
 	MergedAt          string   `db:"merged_at" json:"mergedAt"`
+	Version           int64    `db:"version" json:"version"`
 }
 
 func (PullRequest) FromRowWithRevs(ddl gensql.GetPRwithReviewersRow) *PullRequest {
@@ -95,12 +142,14 @@ func (PullRequest) FromRowWithRevs(ddl gensql.GetPRwithReviewersRow) *PullReques
 		PullRequestShort: PullRequestShort{
 			PRId:     ddl.PullReqID,
 			Name:     ddl.PullReqName,
-			AuthorId: ddl.AuthorID,
+			AuthorId: ddl.AuthorID.String,
 			Status:   ddl.PullReqStatus,
 		},
 		AssignedReviewers: assigned,
+		TeamName:          ddl.TeamName.String,
 		CreatedAt:         ddl.CreatedAt.Time.Format("2006-01-02 15:04:05"),
 		MergedAt:          mergedAt,
+		Version:           ddl.Version,
 	}
 }
 
@@ -113,55 +162,150 @@ func (PullRequest) FromDDL(ddl gensql.PullRequest, revs []string) *PullRequest {
 		PullRequestShort: PullRequestShort{
 			PRId:     ddl.PullReqID,
 			Name:     ddl.PullReqName,
-			AuthorId: ddl.AuthorID,
+			AuthorId: ddl.AuthorID.String,
 			Status:   ddl.PullReqStatus,
 		},
 		CreatedAt:         ddl.CreatedAt.Time.Format("2006-01-02 15:04:05"),
 		MergedAt:          mergedAt,
 		AssignedReviewers: revs,
+		TeamName:          ddl.TeamName.String,
+		Version:           ddl.Version,
 	}
 }
 
+func (pr PullRequest) ImportSchema() (params gensql.ImportPRParams, err error) {
+	params = gensql.ImportPRParams{
+		PullReqID:     pr.PRId,
+		PullReqName:   pr.Name,
+		AuthorID:      pgtype.Text{String: pr.AuthorId, Valid: pr.AuthorId != ""},
+		PullReqStatus: pr.Status,
+		Version:       max(pr.Version, 1),
+		TeamName:      pgtype.Text{String: pr.TeamName, Valid: pr.TeamName != ""},
+	}
+
+	created, err := time.Parse("2006-01-02 15:04:05", pr.CreatedAt)
+	if err != nil {
+		return
+	}
+	params.CreatedAt = pgtype.Timestamp{Time: created, Valid: true}
+
+	if pr.MergedAt != "" {
+		var merged time.Time
+		if merged, err = time.Parse("2006-01-02 15:04:05", pr.MergedAt); err != nil {
+			return
+		}
+		params.MergedAt = pgtype.Timestamp{Time: merged, Valid: true}
+	}
+	return
+}
+
 type PullReqCreate struct {
 	PRId     string `json:"pull_request_id"`
 	Name     string `json:"pull_request_name"`
 	AuthorID string `json:"author_id"`
+	// TeamName picks which of the author's teams the PR belongs to, the
+	// author's primary team when empty.
+	TeamName string `json:"team_name"`
 }
 
 func (prc PullReqCreate) ToCreateParams() gensql.CreatePRParams {
 	return gensql.CreatePRParams{
 		PullReqID:   prc.PRId,
 		PullReqName: prc.Name,
-		AuthorID:    prc.AuthorID,
+		AuthorID:    pgtype.Text{String: prc.AuthorID, Valid: true},
+		TeamName:    pgtype.Text{String: prc.TeamName, Valid: prc.TeamName != ""},
 	}
 }
 
 type SetUserActiveRequest struct {
-	UserID   string `json:"user_id" validate:"required"`
+	UserID   string `json:"user_id" validate:"required,id"`
 	IsActive bool   `json:"is_active"`
 }
 
 type CreatePRRequest struct {
-	PRId     string `json:"pull_request_id" validate:"required"`
-	Name     string `json:"pull_request_name" validate:"required"`
-	AuthorID string `json:"author_id" validate:"required"`
+	PRId     string `json:"pull_request_id" validate:"required,id"`
+	Name     string `json:"pull_request_name" validate:"required,name,max=256"`
+	AuthorID string `json:"author_id" validate:"required,id"`
+	TeamName string `json:"team_name,omitempty" validate:"omitempty,name,max=64"`
 }
 
 type MergePRRequest struct {
-	PRId string `json:"pull_request_id" validate:"required"`
+	PRId string `json:"pull_request_id" validate:"required,id"`
 }
 
 type ReassignReviewerRequest struct {
-	PRId      string `json:"pull_request_id" validate:"required"`
-	OldUserID string `json:"old_reviewer_id" validate:"required"`
+	PRId      string `json:"pull_request_id" validate:"required,id"`
+	OldUserID string `json:"old_reviewer_id" validate:"required,id"`
 }
 
 type GetUserReviewsQuery struct {
-	UserID string `query:"user_id" validate:"required"`
+	UserID string `form:"user_id" validate:"required,id"`
 }
 
 type GetTeamQuery struct {
-	TeamName string `query:"team_name" validate:"required"`
+	TeamName string `form:"team_name" validate:"required,name,max=64"`
+}
+
+type AddTeamMembersRequest struct {
+	TeamName string       `json:"team_name" validate:"required,name,max=64"`
+	Members  []TeamMember `json:"members" validate:"required,min=1,dive"`
+}
+
+// SetTeamParentRequest puts a team under ParentTeam, or makes it a top-level
+// team when ParentTeam is empty.
+type SetTeamParentRequest struct {
+	TeamName   string `json:"team_name" validate:"required,name,max=64"`
+	ParentTeam string `json:"parent_team" validate:"omitempty,name,max=64"`
+}
+
+type RemoveTeamMembersRequest struct {
+	TeamName string   `json:"team_name" validate:"required,name,max=64"`
+	UserIDs  []string `json:"user_ids" validate:"required,min=1,dive,id"`
+}
+
+// ReviewsOnMove says what happens to the open reviews a user has in the team
+// they are moved out of.
+type ReviewsOnMove string
+
+const (
+	ReviewsKeep     ReviewsOnMove = "keep"
+	ReviewsReassign ReviewsOnMove = "reassign"
+)
+
+type MoveTeamMemberRequest struct {
+	UserID   string `json:"user_id" validate:"required,id"`
+	TeamName string `json:"team_name" validate:"required,name,max=64"`
+	// FromTeam is the membership to give up, the user's primary team when
+	// empty.
+	FromTeam string        `json:"from_team,omitempty" validate:"omitempty,name,max=64"`
+	Reviews  ReviewsOnMove `json:"reviews" validate:"omitempty,oneof=keep reassign"`
+}
+
+type Reassignment struct {
+	PRId    string `json:"pull_request_id"`
+	NewUser string `json:"replaced_by"`
+}
+
+type MoveTeamMemberResponse struct {
+	User       User           `json:"user"`
+	FromTeam   string         `json:"from_team"`
+	Reassigned []Reassignment `json:"reassigned"`
+}
+
+type DeleteUserRequest struct {
+	UserID string `json:"user_id" validate:"required,id"`
+}
+
+type DeleteTeamRequest struct {
+	TeamName string `json:"team_name" validate:"required,name,max=64"`
+}
+
+// DeleteUserResponse reports the open reviews the user held: Reassigned went
+// to other reviewers, Unassigned had nobody to take them and were dropped.
+type DeleteUserResponse struct {
+	User       User           `json:"user"`
+	Reassigned []Reassignment `json:"reassigned"`
+	Unassigned []string       `json:"unassigned"`
 }
 
 type AddTeamResponse struct {
@@ -189,3 +333,13 @@ type UserReviewsResponse struct {
 type HealthResponse struct {
 	Status string `json:"status"`
 }
+
+type Stats struct {
+	OpenPRs      int64
+	ReviewerLoad map[string]int64
+}
+
+type Dump struct {
+	Teams        []Team        `json:"teams" validate:"dive"`
+	PullRequests []PullRequest `json:"pull_requests"`
+}
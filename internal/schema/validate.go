@@ -0,0 +1,107 @@
+package schema
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/go-playground/validator/v10"
+)
+
+const MaxIDLength = 64
+
+var (
+	idPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._:-]*$`)
+	validate  = newValidator()
+)
+
+type FieldError struct {
+	Field string `json:"field"`
+	Rule  string `json:"rule"`
+	Msg   string `json:"msg"`
+}
+
+func newValidator() *validator.Validate {
+	v := validator.New(validator.WithRequiredStructEnabled())
+
+	v.RegisterTagNameFunc(func(f reflect.StructField) string {
+		for _, tag := range []string{"json", "form"} {
+			name, _, _ := strings.Cut(f.Tag.Get(tag), ",")
+			if name != "" && name != "-" {
+				return name
+			}
+		}
+		return f.Name
+	})
+
+	_ = v.RegisterValidation("id", func(fl validator.FieldLevel) bool {
+		s := fl.Field().String()
+		return len(s) <= MaxIDLength && idPattern.MatchString(s)
+	})
+
+	_ = v.RegisterValidation("name", func(fl validator.FieldLevel) bool {
+		s := fl.Field().String()
+		if !utf8.ValidString(s) || strings.TrimSpace(s) != s || s == "" {
+			return false
+		}
+		return strings.IndexFunc(s, unicode.IsControl) < 0
+	})
+
+	return v
+}
+
+func describe(fe validator.FieldError) string {
+	switch fe.Tag() {
+	case "required":
+		return "is required"
+	case "id":
+		return fmt.Sprintf("must start with a letter or digit and contain only letters, digits, '.', '_', ':' or '-', up to %d characters", MaxIDLength)
+	case "name":
+		return "must be non-blank text without control characters or surrounding spaces"
+	case "max":
+		if fe.Kind() == reflect.Slice {
+			return fmt.Sprintf("must have at most %s items", fe.Param())
+		}
+		return fmt.Sprintf("must be at most %s characters", fe.Param())
+	case "min":
+		return fmt.Sprintf("must have at least %s items", fe.Param())
+	case "len":
+		return fmt.Sprintf("must be exactly %s characters", fe.Param())
+	case "oneof":
+		return fmt.Sprintf("must be one of: %s", fe.Param())
+	default:
+		return fmt.Sprintf("failed on %s rule", fe.Tag())
+	}
+}
+
+// Validate checks v against its validate tags and reports every failing field.
+func Validate(v any) *Err {
+	err := validate.Struct(v)
+	if err == nil {
+		return nil
+	}
+
+	var ves validator.ValidationErrors
+	if !errors.As(err, &ves) {
+		return Err{}.Wrap(ValidationFailed, err)
+	}
+
+	fields := make([]FieldError, 0, len(ves))
+	for _, fe := range ves {
+		field := fe.Namespace()
+		if _, rest, ok := strings.Cut(field, "."); ok {
+			field = rest
+		}
+		fields = append(fields, FieldError{Field: field, Rule: fe.Tag(), Msg: describe(fe)})
+	}
+
+	return &Err{
+		Code:   ValidationFailed,
+		Msg:    fmt.Sprintf("request has %d invalid field(s)", len(fields)),
+		Fields: fields,
+	}
+}
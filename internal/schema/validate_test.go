@@ -0,0 +1,108 @@
+package schema
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	member := TeamMember{UserID: "u1", UserName: "Alice", IsActive: true}
+	pr := CreatePRRequest{PRId: "pr-1", Name: "Change", AuthorID: "u1"}
+
+	tests := []struct {
+		name string
+		v    any
+		// want lists the failing fields as "field rule", none when valid
+		want []string
+	}{
+		{"team", Team{TeamName: "backend", ParentTeam: "eng", Members: []TeamMember{member}}, nil},
+		{"team without name", Team{Members: []TeamMember{member}}, []string{"team_name required"}},
+		{"team with blank parent", Team{TeamName: "backend", ParentTeam: " eng"}, []string{"parent_team name"}},
+		{
+			"team with bad members",
+			Team{TeamName: "backend", Members: []TeamMember{{UserID: "-u1", UserName: "Alice"}, {UserID: "u2", UserName: "Bob\n"}}},
+			[]string{"members[0].user_id id", "members[1].username name"},
+		},
+		{"team with long name", Team{TeamName: strings.Repeat("t", 65)}, []string{"team_name max"}},
+
+		{"set active", SetUserActiveRequest{UserID: "u1"}, nil},
+		{"set active without user", SetUserActiveRequest{}, []string{"user_id required"}},
+		{"set active with long id", SetUserActiveRequest{UserID: strings.Repeat("u", MaxIDLength+1)}, []string{"user_id id"}},
+
+		{"create pr", pr, nil},
+		{"create pr empty", CreatePRRequest{}, []string{"pull_request_id required", "pull_request_name required", "author_id required"}},
+		{"create pr with bad team", CreatePRRequest{PRId: "pr-1", Name: "Change", AuthorID: "u1", TeamName: "\tteam"}, []string{"team_name name"}},
+		{"create pr with long name", CreatePRRequest{PRId: "pr-1", Name: strings.Repeat("n", 257), AuthorID: "u1"}, []string{"pull_request_name max"}},
+
+		{"batch", CreatePRBatchRequest{Mode: BatchBestEffort, PullRequests: []CreatePRRequest{pr}}, nil},
+		{"batch empty", CreatePRBatchRequest{PullRequests: []CreatePRRequest{}}, []string{"pull_requests min"}},
+		{"batch with bad mode", CreatePRBatchRequest{Mode: "some", PullRequests: []CreatePRRequest{pr}}, []string{"mode oneof"}},
+		{"batch with bad item", CreatePRBatchRequest{PullRequests: []CreatePRRequest{pr, {PRId: "pr 2", Name: "Change", AuthorID: "u1"}}}, []string{"pull_requests[1].pull_request_id id"}},
+		{"batch too large", CreatePRBatchRequest{PullRequests: slices.Repeat([]CreatePRRequest{pr}, 501)}, []string{"pull_requests max"}},
+
+		{"merge", MergePRRequest{PRId: "pr-1"}, nil},
+		{"merge without pr", MergePRRequest{}, []string{"pull_request_id required"}},
+		{"reassign", ReassignReviewerRequest{PRId: "pr-1", OldUserID: "u2"}, nil},
+		{"reassign empty", ReassignReviewerRequest{}, []string{"pull_request_id required", "old_reviewer_id required"}},
+
+		{"get reviews", GetUserReviewsQuery{UserID: "u1"}, nil},
+		{"get reviews with bad id", GetUserReviewsQuery{UserID: "u/1"}, []string{"user_id id"}},
+		{"get team", GetTeamQuery{TeamName: "backend"}, nil},
+		{"get team without name", GetTeamQuery{}, []string{"team_name required"}},
+
+		{"add members", AddTeamMembersRequest{TeamName: "backend", Members: []TeamMember{member}}, nil},
+		{"add no members", AddTeamMembersRequest{TeamName: "backend", Members: []TeamMember{}}, []string{"members min"}},
+		{"add member without name", AddTeamMembersRequest{TeamName: "backend", Members: []TeamMember{{UserID: "u1"}}}, []string{"members[0].username required"}},
+		{"remove members", RemoveTeamMembersRequest{TeamName: "backend", UserIDs: []string{"u1"}}, nil},
+		{"remove bad member", RemoveTeamMembersRequest{TeamName: "backend", UserIDs: []string{"u1", ""}}, []string{"user_ids[1] id"}},
+		{"remove no members", RemoveTeamMembersRequest{TeamName: "backend"}, []string{"user_ids required"}},
+		{"set parent", SetTeamParentRequest{TeamName: "backend"}, nil},
+		{"set bad parent", SetTeamParentRequest{TeamName: "backend", ParentTeam: "eng "}, []string{"parent_team name"}},
+
+		{"move", MoveTeamMemberRequest{UserID: "u1", TeamName: "frontend", Reviews: ReviewsReassign}, nil},
+		{"move empty", MoveTeamMemberRequest{}, []string{"user_id required", "team_name required"}},
+		{"move with bad reviews", MoveTeamMemberRequest{UserID: "u1", TeamName: "frontend", Reviews: "drop"}, []string{"reviews oneof"}},
+
+		{"delete user", DeleteUserRequest{UserID: "u1"}, nil},
+		{"delete user without id", DeleteUserRequest{}, []string{"user_id required"}},
+		{"delete team", DeleteTeamRequest{TeamName: "backend"}, nil},
+		{"delete team without name", DeleteTeamRequest{}, []string{"team_name required"}},
+
+		{"issue key", IssueAPIKeyRequest{UserID: "u1", Role: RoleTeamLead}, nil},
+		{"issue key with bad role", IssueAPIKeyRequest{Role: "root"}, []string{"role oneof"}},
+		{"issue key without role", IssueAPIKeyRequest{UserID: "u1"}, []string{"role required"}},
+		{"revoke key", RevokeAPIKeyRequest{KeyID: "0123456789abcdef"}, nil},
+		{"revoke short key", RevokeAPIKeyRequest{KeyID: "0123"}, []string{"key_id len"}},
+		{"revoke bad key", RevokeAPIKeyRequest{KeyID: "0123456789abcdeg"}, []string{"key_id hexadecimal"}},
+
+		{"dump", Dump{Teams: []Team{{TeamName: "backend"}}}, nil},
+		{"dump with bad team", Dump{Teams: []Team{{}}}, []string{"teams[0].team_name required"}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			err := Validate(tc.v)
+			if tc.want == nil {
+				if err != nil {
+					t.Fatalf("expected valid, got %+v", err)
+				}
+				return
+			}
+			if err == nil || err.Code != ValidationFailed {
+				t.Fatalf("expected %s, got %+v", ValidationFailed, err)
+			}
+
+			var got []string
+			for _, f := range err.Fields {
+				if f.Msg == "" {
+					t.Errorf("field %s has no message", f.Field)
+				}
+				got = append(got, f.Field+" "+f.Rule)
+			}
+			if !slices.Equal(got, tc.want) {
+				t.Fatalf("expected fields %q, got %q", tc.want, got)
+			}
+		})
+	}
+}
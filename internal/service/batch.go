@@ -0,0 +1,67 @@
+package service
+
+import (
+	"context"
+
+	"plassstic.tech/trainee/avito/internal/events"
+	"plassstic.tech/trainee/avito/internal/metrics"
+	"plassstic.tech/trainee/avito/internal/repo"
+	"plassstic.tech/trainee/avito/internal/schema"
+)
+
+// CreatePRBatch creates many PRs in one transaction. In atomic mode a single
+// failed item rolls the whole batch back; in best-effort mode the failed
+// items are reported and the rest is committed.
+func (s service) CreatePRBatch(ctx context.Context, req schema.CreatePRBatchRequest) (res *schema.CreatePRBatchResponse, err *schema.Err) {
+	ctx, end := startSpan(ctx, "CreatePRBatch")
+	defer func() { end(err) }()
+
+	res = &schema.CreatePRBatchResponse{Mode: req.Mode}
+	if res.Mode == "" {
+		res.Mode = schema.BatchAtomic
+	}
+
+	prs := make([]schema.PullReqCreate, len(req.PullRequests))
+	for i, r := range req.PullRequests {
+		prs[i] = schema.PullReqCreate{PRId: r.PRId, Name: r.Name, AuthorID: r.AuthorID, TeamName: r.TeamName}
+	}
+
+	var tx repo.Tx
+	if ctx, tx, err = s.begin(ctx); err != nil {
+		return nil, err
+	}
+
+	if res.Results, err = tx.CreatePRBatch(ctx, prs); err != nil {
+		rb(ctx, tx)
+		return nil, err
+	}
+
+	for _, item := range res.Results {
+		if item.Status == schema.BatchFailed {
+			res.Failed++
+		}
+	}
+
+	if res.Failed > 0 && res.Mode == schema.BatchAtomic {
+		rb(ctx, tx)
+		for i := range res.Results {
+			if res.Results[i].Status == schema.BatchCreated {
+				res.Results[i].Status = schema.BatchRolledBack
+				res.Results[i].PR = nil
+			}
+		}
+		return
+	}
+
+	if err = decide(ctx, tx, nil); err != nil {
+		return nil, err
+	}
+	for _, item := range res.Results {
+		if item.Status == schema.BatchCreated {
+			res.Created++
+			metrics.Assignments.Add(float64(len(item.PR.AssignedReviewers)))
+			s.emit(ctx, events.PRCreated, item.PR)
+		}
+	}
+	return
+}
@@ -0,0 +1,120 @@
+package service
+
+import (
+	"context"
+
+	"plassstic.tech/trainee/avito/gensql"
+	"plassstic.tech/trainee/avito/internal/events"
+	"plassstic.tech/trainee/avito/internal/metrics"
+	"plassstic.tech/trainee/avito/internal/repo"
+	"plassstic.tech/trainee/avito/internal/schema"
+)
+
+func (s service) DeleteTeam(ctx context.Context, teamName string) (t *schema.Team, err *schema.Err) {
+	ctx, end := startSpan(ctx, "DeleteTeam")
+	defer func() { end(err) }()
+
+	var tx repo.Tx
+	if ctx, tx, err = s.begin(ctx); err != nil {
+		return
+	}
+
+	t, err = tx.DeleteTeam(ctx, teamName)
+	err = decide(ctx, tx, err)
+	return
+}
+
+func (s service) PurgeTeam(ctx context.Context, teamName string) (t *schema.Team, err *schema.Err) {
+	ctx, end := startSpan(ctx, "PurgeTeam")
+	defer func() { end(err) }()
+
+	var tx repo.Tx
+	if ctx, tx, err = s.begin(ctx); err != nil {
+		return
+	}
+
+	t, err = tx.PurgeTeam(ctx, teamName)
+	err = decide(ctx, tx, err)
+	return
+}
+
+func (s service) DeleteUser(ctx context.Context, userID string) (res *schema.DeleteUserResponse, err *schema.Err) {
+	ctx, end := startSpan(ctx, "DeleteUser")
+	defer func() { end(err) }()
+
+	return s.removeUser(ctx, userID, false)
+}
+
+// PurgeUser also works on a user that is already deleted, whose reviews were
+// handed off back then.
+func (s service) PurgeUser(ctx context.Context, userID string) (res *schema.DeleteUserResponse, err *schema.Err) {
+	ctx, end := startSpan(ctx, "PurgeUser")
+	defer func() { end(err) }()
+
+	return s.removeUser(ctx, userID, true)
+}
+
+// removeUser hands the user's open reviews to other reviewers before they go.
+// A review nobody can take is dropped rather than blocking the deletion.
+func (s service) removeUser(ctx context.Context, userID string, purge bool) (res *schema.DeleteUserResponse, err *schema.Err) {
+	var tx repo.Tx
+	if ctx, tx, err = s.begin(ctx); err != nil {
+		return
+	}
+
+	var (
+		user    *schema.User
+		updated []*schema.PullRequest
+	)
+	res = &schema.DeleteUserResponse{Reassigned: []schema.Reassignment{}, Unassigned: []string{}}
+	defer func() {
+		err = decide(ctx, tx, err)
+		if err != nil {
+			res = nil
+			return
+		}
+		for _, pr := range updated {
+			metrics.Reassignments.Inc()
+			s.emit(ctx, events.PRReassigned, pr)
+		}
+	}()
+
+	if _, err = tx.GetUser(ctx, userID); err == nil {
+		var reviews []schema.PullRequestShort
+		if reviews, err = tx.GetUserReviews(ctx, userID); err != nil {
+			return
+		}
+
+		for _, review := range reviews {
+			if review.Status != gensql.PrstatOpen {
+				continue
+			}
+
+			newUserID, pr, rerr := tx.ReassignReviewer(ctx, review.PRId, userID, nil)
+			if rerr != nil {
+				if rerr.Code != schema.NoCandidate {
+					err = rerr
+					return
+				}
+				metrics.NoCandidate.Inc()
+				res.Unassigned = append(res.Unassigned, review.PRId)
+				continue
+			}
+			res.Reassigned = append(res.Reassigned, schema.Reassignment{PRId: review.PRId, NewUser: newUserID})
+			updated = append(updated, pr)
+		}
+	} else if !purge || err.Code != schema.NotFound {
+		return
+	}
+
+	if purge {
+		user, err = tx.PurgeUser(ctx, userID)
+	} else {
+		user, err = tx.DeleteUser(ctx, userID)
+	}
+	if err != nil {
+		return
+	}
+	res.User = *user
+	return
+}
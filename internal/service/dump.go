@@ -0,0 +1,64 @@
+package service
+
+import (
+	"context"
+
+	"plassstic.tech/trainee/avito/internal/repo"
+	"plassstic.tech/trainee/avito/internal/schema"
+)
+
+func (s service) Export(ctx context.Context) (dump *schema.Dump, err *schema.Err) {
+	ctx, end := startSpan(ctx, "Export")
+	defer func() { end(err) }()
+
+	var tx repo.Tx
+	if ctx, tx, err = s.begin(ctx); err != nil {
+		return
+	}
+	defer func() { err = decide(ctx, tx, err) }()
+
+	dump = &schema.Dump{}
+	if dump.Teams, err = tx.ListTeams(ctx); err != nil {
+		return
+	}
+	dump.PullRequests, err = tx.ListPRs(ctx)
+	return
+}
+
+// Import loads a dump produced by Export in a single transaction, keeping
+// statuses, timestamps and reviewers as they are instead of reassigning.
+func (s service) Import(ctx context.Context, dump schema.Dump) (res *schema.Dump, err *schema.Err) {
+	ctx, end := startSpan(ctx, "Import")
+	defer func() { end(err) }()
+
+	var tx repo.Tx
+	if ctx, tx, err = s.begin(ctx); err != nil {
+		return
+	}
+
+	defer func() { err = decide(ctx, tx, err) }()
+	// parents may come later in the dump, so they are linked once every team
+	// exists
+	for _, team := range dump.Teams {
+		team.ParentTeam = ""
+		if _, err = tx.AddTeamWithMembers(ctx, team); err != nil {
+			return
+		}
+	}
+	for _, team := range dump.Teams {
+		if team.ParentTeam == "" {
+			continue
+		}
+		if _, err = tx.SetTeamParent(ctx, team.TeamName, team.ParentTeam); err != nil {
+			return
+		}
+	}
+	for _, pr := range dump.PullRequests {
+		if err = tx.ImportPR(ctx, pr); err != nil {
+			return
+		}
+	}
+
+	res = &dump
+	return
+}
@@ -0,0 +1,89 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"plassstic.tech/trainee/avito/internal/auth"
+	"plassstic.tech/trainee/avito/internal/repo"
+	"plassstic.tech/trainee/avito/internal/schema"
+)
+
+func (s service) IssueAPIKey(ctx context.Context, req schema.IssueAPIKeyRequest) (key *schema.APIKey, err *schema.Err) {
+	ctx, end := startSpan(ctx, "IssueAPIKey")
+	defer func() { end(err) }()
+
+	if !req.Role.Valid() {
+		err = schema.Err{}.Wrap(schema.ValidationFailed, fmt.Errorf("unknown role %q", req.Role)).With("role", req.Role)
+		return
+	}
+
+	keyID, secret, lerr := auth.GenerateKey()
+	if lerr != nil {
+		err = schema.Err{}.Wrap(schema.Unknown, lerr)
+		return
+	}
+
+	var tx repo.Tx
+	if ctx, tx, err = s.begin(ctx); err != nil {
+		return
+	}
+
+	key, err = tx.CreateAPIKey(ctx, keyID, auth.Hash(secret), req.UserID, req.Role)
+	err = decide(ctx, tx, err)
+	if err != nil {
+		return
+	}
+
+	key.Key = auth.FormatKey(keyID, secret)
+	return
+}
+
+func (s service) RevokeAPIKey(ctx context.Context, keyID string) (key *schema.APIKey, err *schema.Err) {
+	ctx, end := startSpan(ctx, "RevokeAPIKey")
+	defer func() { end(err) }()
+
+	var tx repo.Tx
+	if ctx, tx, err = s.begin(ctx); err != nil {
+		return
+	}
+
+	key, err = tx.RevokeAPIKey(ctx, keyID)
+	err = decide(ctx, tx, err)
+	return
+}
+
+func (s service) Authenticate(ctx context.Context, key string) (id *auth.Identity, err *schema.Err) {
+	ctx, end := startSpan(ctx, "Authenticate")
+	defer func() { end(err) }()
+
+	keyID, secret, lerr := auth.ParseKey(key)
+	if lerr != nil {
+		err = schema.Err{}.Wrap(schema.Unauthorized, lerr)
+		return
+	}
+
+	var tx repo.Tx
+	if ctx, tx, err = s.begin(ctx); err != nil {
+		return
+	}
+
+	var stored *schema.APIKey
+	stored, err = tx.GetAPIKey(ctx, keyID)
+	err = decide(ctx, tx, err)
+
+	if err != nil && err.Code != schema.NotFound {
+		return
+	}
+	if err != nil || stored.Revoked() || !auth.Verify(secret, stored.Hash) {
+		err = schema.Err{}.Wrap(schema.Unauthorized, fmt.Errorf("invalid api key"))
+		return
+	}
+
+	id = &auth.Identity{
+		KeyID:  stored.KeyID,
+		UserID: stored.UserID,
+		Role:   stored.Role,
+	}
+	return
+}
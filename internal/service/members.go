@@ -0,0 +1,114 @@
+package service
+
+import (
+	"context"
+
+	"plassstic.tech/trainee/avito/gensql"
+	"plassstic.tech/trainee/avito/internal/events"
+	"plassstic.tech/trainee/avito/internal/metrics"
+	"plassstic.tech/trainee/avito/internal/repo"
+	"plassstic.tech/trainee/avito/internal/schema"
+)
+
+func (s service) AddTeamMembers(ctx context.Context, req schema.AddTeamMembersRequest) (t *schema.Team, err *schema.Err) {
+	ctx, end := startSpan(ctx, "AddTeamMembers")
+	defer func() { end(err) }()
+
+	var tx repo.Tx
+	if ctx, tx, err = s.begin(ctx); err != nil {
+		return
+	}
+
+	t, err = tx.AddTeamMembers(ctx, req.TeamName, req.Members)
+	err = decide(ctx, tx, err)
+	return
+}
+
+func (s service) RemoveTeamMembers(ctx context.Context, req schema.RemoveTeamMembersRequest) (t *schema.Team, err *schema.Err) {
+	ctx, end := startSpan(ctx, "RemoveTeamMembers")
+	defer func() { end(err) }()
+
+	var tx repo.Tx
+	if ctx, tx, err = s.begin(ctx); err != nil {
+		return
+	}
+
+	t, err = tx.RemoveTeamMembers(ctx, req.TeamName, req.UserIDs)
+	err = decide(ctx, tx, err)
+	return
+}
+
+// MoveTeamMember moves a user from one of their teams to another in one
+// transaction. With ReviewsReassign their open reviews of PRs that belong to
+// the old team are handed to old teammates first, and the move fails if one
+// of them cannot be.
+func (s service) MoveTeamMember(ctx context.Context, req schema.MoveTeamMemberRequest) (res *schema.MoveTeamMemberResponse, err *schema.Err) {
+	ctx, end := startSpan(ctx, "MoveTeamMember")
+	defer func() { end(err) }()
+
+	var tx repo.Tx
+	if ctx, tx, err = s.begin(ctx); err != nil {
+		return
+	}
+
+	var (
+		user    *schema.User
+		updated []*schema.PullRequest
+	)
+	res = &schema.MoveTeamMemberResponse{Reassigned: []schema.Reassignment{}}
+	defer func() {
+		err = decide(ctx, tx, err)
+		if err != nil {
+			res = nil
+			return
+		}
+		for _, pr := range updated {
+			metrics.Reassignments.Inc()
+			s.emit(ctx, events.PRReassigned, pr)
+		}
+	}()
+
+	if user, err = tx.GetUser(ctx, req.UserID); err != nil {
+		return
+	}
+	res.FromTeam = req.FromTeam
+	if res.FromTeam == "" {
+		res.FromTeam = user.TeamName
+	}
+
+	if req.Reviews == schema.ReviewsReassign && res.FromTeam != "" && res.FromTeam != req.TeamName {
+		var reviews []schema.PullRequestShort
+		if reviews, err = tx.GetUserReviews(ctx, req.UserID); err != nil {
+			return
+		}
+
+		for _, review := range reviews {
+			if review.Status != gensql.PrstatOpen {
+				continue
+			}
+			var pr *schema.PullRequest
+			if pr, err = tx.GetPR(ctx, review.PRId); err != nil {
+				return
+			}
+			if pr.TeamName != res.FromTeam {
+				continue
+			}
+
+			var newUserID string
+			if newUserID, pr, err = tx.ReassignReviewer(ctx, review.PRId, req.UserID, nil); err != nil {
+				if err.Code == schema.NoCandidate {
+					metrics.NoCandidate.Inc()
+				}
+				return
+			}
+			res.Reassigned = append(res.Reassigned, schema.Reassignment{PRId: review.PRId, NewUser: newUserID})
+			updated = append(updated, pr)
+		}
+	}
+
+	if user, err = tx.MoveTeamMember(ctx, req.UserID, req.FromTeam, req.TeamName); err != nil {
+		return
+	}
+	res.User = *user
+	return
+}
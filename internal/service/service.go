@@ -2,95 +2,251 @@ package service
 
 import (
 	"context"
+	"maps"
+	"time"
 
-	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
-	"github.com/rs/zerolog/log"
+	"github.com/rs/zerolog"
+	"plassstic.tech/trainee/avito/internal/auth"
+	"plassstic.tech/trainee/avito/internal/events"
+	"plassstic.tech/trainee/avito/internal/metrics"
 	"plassstic.tech/trainee/avito/internal/repo"
 	"plassstic.tech/trainee/avito/internal/schema"
+	"plassstic.tech/trainee/avito/internal/tracing"
 )
 
 var _ Service = service{}
 
 type service struct {
-	pool *pgxpool.Pool
+	store  repo.Store
+	events events.Publisher
+	source string
+	check  Check
 }
 
-func decide(ctx context.Context, tx pgx.Tx, err *schema.Err) {
+// Check authorizes a call with the data it is about to change. It runs first
+// in the transaction of the call, so nothing it read can change before the
+// write.
+type Check func(ctx context.Context, tx repo.Tx) *schema.Err
+
+// decide commits or rolls back depending on err. A commit that fails is
+// returned like any other error, so callers only report success on nil.
+func decide(ctx context.Context, tx repo.Tx, err *schema.Err) *schema.Err {
 	if err != nil {
 		e := tx.Rollback(ctx)
-		log.Debug().Any("e", e).Msg("rollback")
-	} else {
-		e := tx.Commit(ctx)
-		log.Debug().Any("e", e).Msg("commit")
+		metrics.Rollback()
+		zerolog.Ctx(ctx).Debug().Any("e", e).Msg("rollback")
+		return err
+	}
+	if e := tx.Commit(ctx); e != nil {
+		metrics.Rollback()
+		zerolog.Ctx(ctx).Error().Err(e).Msg("commit failed")
+		return schema.Err{}.Wrap(schema.Unknown, e)
 	}
+	metrics.Commit()
+	return nil
 }
 
-func rb(ctx context.Context, tx pgx.Tx) {
+func rb(ctx context.Context, tx repo.Tx) {
 	_ = tx.Rollback(ctx)
+	metrics.Rollback()
+}
+
+func (s service) emit(ctx context.Context, typ events.Type, pr *schema.PullRequest) {
+	logger := zerolog.Ctx(ctx)
+
+	e, err := events.ForPR(s.source, typ, *pr)
+	if err != nil {
+		logger.Error().Err(err).Str("type", string(typ)).Msg("failed to build event")
+		return
+	}
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 10*time.Second)
+		defer cancel()
+		if err := s.events.Publish(ctx, e); err != nil {
+			logger.Error().Err(err).Str("id", e.ID).Str("type", string(typ)).Msg("failed to publish event")
+		}
+	}()
 }
 
-func (s service) begin(ctx context.Context) (pgx.Tx, *schema.Err) {
-	tx, err := s.pool.Begin(ctx)
+func (s service) begin(ctx context.Context) (context.Context, repo.Tx, *schema.Err) {
+	ctx, span := tracing.Start(ctx, "tx")
+	tx, err := s.store.Begin(ctx)
 	if err != nil {
-		return nil, schema.Err{}.Wrap(schema.Unknown, err)
+		tracing.Fail(span, err)
+		span.End()
+		return ctx, nil, schema.Err{}.Wrap(schema.Unknown, err)
+	}
+
+	traced := tracedTx{Tx: tx, span: span}
+	if s.check != nil {
+		if cerr := s.check(ctx, traced); cerr != nil {
+			rb(ctx, traced)
+			return ctx, nil, cerr
+		}
 	}
-	return tx, nil
+	return ctx, traced, nil
+}
+
+// WithCheck returns the service with check added to the ones run at the start
+// of each of its transactions.
+func (s service) WithCheck(check Check) Service {
+	if prev := s.check; prev != nil {
+		s.check = func(ctx context.Context, tx repo.Tx) *schema.Err {
+			if err := prev(ctx, tx); err != nil {
+				return err
+			}
+			return check(ctx, tx)
+		}
+	} else {
+		s.check = check
+	}
+	return s
 }
 
 type Service interface {
 	AddTeam(ctx context.Context, team schema.Team) (*schema.Team, *schema.Err)
-	GetTeam(ctx context.Context, teamName string) (*schema.Team, *schema.Err)
+	GetTeam(ctx context.Context, teamName string) (*schema.TeamTree, *schema.Err)
+	SetTeamParent(ctx context.Context, req schema.SetTeamParentRequest) (*schema.Team, *schema.Err)
+	AddTeamMembers(ctx context.Context, req schema.AddTeamMembersRequest) (*schema.Team, *schema.Err)
+	RemoveTeamMembers(ctx context.Context, req schema.RemoveTeamMembersRequest) (*schema.Team, *schema.Err)
+	MoveTeamMember(ctx context.Context, req schema.MoveTeamMemberRequest) (*schema.MoveTeamMemberResponse, *schema.Err)
+	DeleteTeam(ctx context.Context, teamName string) (*schema.Team, *schema.Err)
+	PurgeTeam(ctx context.Context, teamName string) (*schema.Team, *schema.Err)
 	SetUserActive(ctx context.Context, userID string, isActive bool) (*schema.User, *schema.Err)
+	DeleteUser(ctx context.Context, userID string) (*schema.DeleteUserResponse, *schema.Err)
+	PurgeUser(ctx context.Context, userID string) (*schema.DeleteUserResponse, *schema.Err)
 	CreatePR(ctx context.Context, req schema.CreatePRRequest) (*schema.PullRequest, *schema.Err)
-	MergePR(ctx context.Context, prID string) (*schema.PullRequest, *schema.Err)
-	ReassignReviewer(ctx context.Context, prID, oldUserID string) (string, *schema.PullRequest, *schema.Err)
+	CreatePRBatch(ctx context.Context, req schema.CreatePRBatchRequest) (*schema.CreatePRBatchResponse, *schema.Err)
+	MergePR(ctx context.Context, prID string, ifMatch *schema.Precondition) (*schema.PullRequest, *schema.Err)
+	ReassignReviewer(ctx context.Context, prID, oldUserID string, ifMatch *schema.Precondition) (string, *schema.PullRequest, *schema.Err)
 	GetUserReviews(ctx context.Context, userID string) ([]schema.PullRequestShort, *schema.Err)
+	GetPR(ctx context.Context, prID string) (*schema.PullRequest, *schema.Err)
+	GetUser(ctx context.Context, userID string) (*schema.User, *schema.Err)
+	Stats(ctx context.Context) (*schema.Stats, *schema.Err)
+	Export(ctx context.Context) (*schema.Dump, *schema.Err)
+	Import(ctx context.Context, dump schema.Dump) (*schema.Dump, *schema.Err)
+	IssueAPIKey(ctx context.Context, req schema.IssueAPIKeyRequest) (*schema.APIKey, *schema.Err)
+	RevokeAPIKey(ctx context.Context, keyID string) (*schema.APIKey, *schema.Err)
+	Authenticate(ctx context.Context, key string) (*auth.Identity, *schema.Err)
+	WithCheck(check Check) Service
+}
+
+func New(pool *pgxpool.Pool, pub events.Publisher, source string) Service {
+	return NewWithStore(repo.NewPg(pool), pub, source)
 }
 
-func New(pool *pgxpool.Pool) Service {
+// NewWithStore runs the service over any storage backend, e.g. repo.NewMemory().
+func NewWithStore(store repo.Store, pub events.Publisher, source string) Service {
 	return &service{
-		pool: pool,
+		store:  store,
+		events: pub,
+		source: source,
 	}
 }
 
 func (s service) AddTeam(ctx context.Context, team schema.Team) (t *schema.Team, err *schema.Err) {
-	var tx pgx.Tx
-	if tx, err = s.begin(ctx); err != nil {
+	ctx, end := startSpan(ctx, "AddTeam")
+	defer func() { end(err) }()
+
+	var tx repo.Tx
+	if ctx, tx, err = s.begin(ctx); err != nil {
+		return
+	}
+
+	t, err = tx.AddTeamWithMembers(ctx, team)
+	err = decide(ctx, tx, err)
+
+	return
+}
+
+// GetTeam returns the team together with its whole subtree.
+func (s service) GetTeam(ctx context.Context, teamName string) (t *schema.TeamTree, err *schema.Err) {
+	ctx, end := startSpan(ctx, "GetTeam")
+	defer func() { end(err) }()
+
+	var tx repo.Tx
+	if ctx, tx, err = s.begin(ctx); err != nil {
 		return
 	}
 
-	t, err = repo.R(tx).AddTeamWithMembers(ctx, team)
-	decide(ctx, tx, err)
+	var tree schema.TeamTree
+	if tree, _, err = teamTree(ctx, tx, teamName); err == nil {
+		t = &tree
+	}
+	err = decide(ctx, tx, err)
+	return
+}
+
+// teamTree also returns the active users of the subtree, so that a user in
+// several of its teams is counted once.
+func teamTree(ctx context.Context, tx repo.Tx, teamName string) (tree schema.TeamTree, active map[string]struct{}, err *schema.Err) {
+	var (
+		team     *schema.Team
+		subTeams []string
+	)
+	if team, err = tx.GetTeamWithMembers(ctx, teamName); err != nil {
+		return
+	}
+	if subTeams, err = tx.ListSubTeams(ctx, teamName); err != nil {
+		return
+	}
 
+	tree = schema.TeamTree{Team: *team, SubTeams: make([]schema.TeamTree, 0, len(subTeams))}
+	active = map[string]struct{}{}
+	for _, m := range team.Members {
+		if m.IsActive {
+			active[m.UserID] = struct{}{}
+		}
+	}
+	for _, name := range subTeams {
+		sub, subActive, serr := teamTree(ctx, tx, name)
+		if serr != nil {
+			err = serr
+			return
+		}
+		tree.SubTeams = append(tree.SubTeams, sub)
+		maps.Copy(active, subActive)
+	}
+	tree.ActiveMembers = len(active)
 	return
 }
 
-func (s service) GetTeam(ctx context.Context, teamName string) (t *schema.Team, err *schema.Err) {
-	var tx pgx.Tx
-	if tx, err = s.begin(ctx); err != nil {
+func (s service) SetTeamParent(ctx context.Context, req schema.SetTeamParentRequest) (t *schema.Team, err *schema.Err) {
+	ctx, end := startSpan(ctx, "SetTeamParent")
+	defer func() { end(err) }()
+
+	var tx repo.Tx
+	if ctx, tx, err = s.begin(ctx); err != nil {
 		return
 	}
 
-	t, err = repo.R(tx).GetTeamWithMembers(ctx, teamName)
-	decide(ctx, tx, err)
+	t, err = tx.SetTeamParent(ctx, req.TeamName, req.ParentTeam)
+	err = decide(ctx, tx, err)
 	return
 }
 
 func (s service) SetUserActive(ctx context.Context, userID string, isActive bool) (u *schema.User, err *schema.Err) {
-	var tx pgx.Tx
-	if tx, err = s.begin(ctx); err != nil {
+	ctx, end := startSpan(ctx, "SetUserActive")
+	defer func() { end(err) }()
+
+	var tx repo.Tx
+	if ctx, tx, err = s.begin(ctx); err != nil {
 		return
 	}
 
-	u, err = repo.R(tx).SetUserActive(ctx, userID, isActive)
-	decide(ctx, tx, err)
+	u, err = tx.SetUserActive(ctx, userID, isActive)
+	err = decide(ctx, tx, err)
 	return
 }
 
 func (s service) CreatePR(ctx context.Context, req schema.CreatePRRequest) (pr *schema.PullRequest, err *schema.Err) {
-	var tx pgx.Tx
-	if tx, err = s.begin(ctx); err != nil {
+	ctx, end := startSpan(ctx, "CreatePR")
+	defer func() { end(err) }()
+
+	var tx repo.Tx
+	if ctx, tx, err = s.begin(ctx); err != nil {
 		return
 	}
 
@@ -98,50 +254,118 @@ func (s service) CreatePR(ctx context.Context, req schema.CreatePRRequest) (pr *
 		PRId:     req.PRId,
 		Name:     req.Name,
 		AuthorID: req.AuthorID,
+		TeamName: req.TeamName,
 	}
 
-	if pr, err = repo.R(tx).CreatePR(ctx, prc); err != nil {
+	if pr, err = tx.CreatePR(ctx, prc); err != nil {
 		rb(ctx, tx)
 		return
 	}
 
 	var reviewers []string
-	reviewers, err = repo.R(tx).AssignReviewersToPR(ctx, req.PRId, req.AuthorID)
+	reviewers, err = tx.AssignReviewersToPR(ctx, req.PRId, req.AuthorID)
+
+	err = decide(ctx, tx, err)
+	if err != nil {
+		return
+	}
 
-	decide(ctx, tx, err)
 	pr.AssignedReviewers = reviewers
+	metrics.Assignments.Add(float64(len(reviewers)))
+	s.emit(ctx, events.PRCreated, pr)
 
 	return
 }
 
-func (s service) MergePR(ctx context.Context, prID string) (pr *schema.PullRequest, err *schema.Err) {
-	var tx pgx.Tx
-	if tx, err = s.begin(ctx); err != nil {
+func (s service) MergePR(ctx context.Context, prID string, ifMatch *schema.Precondition) (pr *schema.PullRequest, err *schema.Err) {
+	ctx, end := startSpan(ctx, "MergePR")
+	defer func() { end(err) }()
+
+	var tx repo.Tx
+	if ctx, tx, err = s.begin(ctx); err != nil {
 		return
 	}
-	pr, err = repo.R(tx).MergePR(ctx, prID)
-	decide(ctx, tx, err)
+	pr, err = tx.MergePR(ctx, prID, ifMatch)
+	err = decide(ctx, tx, err)
+	if err == nil {
+		s.emit(ctx, events.PRMerged, pr)
+	}
 	return
 }
 
-func (s service) ReassignReviewer(ctx context.Context, prID, oldUserID string) (newUserID string, updatedPR *schema.PullRequest, err *schema.Err) {
-	var tx pgx.Tx
-	if tx, err = s.begin(ctx); err != nil {
+func (s service) ReassignReviewer(ctx context.Context, prID, oldUserID string, ifMatch *schema.Precondition) (newUserID string, updatedPR *schema.PullRequest, err *schema.Err) {
+	ctx, end := startSpan(ctx, "ReassignReviewer")
+	defer func() { end(err) }()
+
+	var tx repo.Tx
+	if ctx, tx, err = s.begin(ctx); err != nil {
 		return
 	}
 
-	newUserID, updatedPR, err = repo.R(tx).ReassignReviewer(ctx, prID, oldUserID)
-	decide(ctx, tx, err)
+	newUserID, updatedPR, err = tx.ReassignReviewer(ctx, prID, oldUserID, ifMatch)
+	err = decide(ctx, tx, err)
+	switch {
+	case err == nil:
+		metrics.Reassignments.Inc()
+		s.emit(ctx, events.PRReassigned, updatedPR)
+	case err.Code == schema.NoCandidate:
+		metrics.NoCandidate.Inc()
+	}
 	return
 }
 
 func (s service) GetUserReviews(ctx context.Context, userID string) (prs []schema.PullRequestShort, err *schema.Err) {
-	var tx pgx.Tx
-	if tx, err = s.begin(ctx); err != nil {
+	ctx, end := startSpan(ctx, "GetUserReviews")
+	defer func() { end(err) }()
+
+	var tx repo.Tx
+	if ctx, tx, err = s.begin(ctx); err != nil {
+		return
+	}
+
+	prs, err = tx.GetUserReviews(ctx, userID)
+	err = decide(ctx, tx, err)
+	return
+}
+
+func (s service) GetPR(ctx context.Context, prID string) (pr *schema.PullRequest, err *schema.Err) {
+	ctx, end := startSpan(ctx, "GetPR")
+	defer func() { end(err) }()
+
+	var tx repo.Tx
+	if ctx, tx, err = s.begin(ctx); err != nil {
+		return
+	}
+
+	pr, err = tx.GetPR(ctx, prID)
+	err = decide(ctx, tx, err)
+	return
+}
+
+func (s service) GetUser(ctx context.Context, userID string) (u *schema.User, err *schema.Err) {
+	ctx, end := startSpan(ctx, "GetUser")
+	defer func() { end(err) }()
+
+	var tx repo.Tx
+	if ctx, tx, err = s.begin(ctx); err != nil {
+		return
+	}
+
+	u, err = tx.GetUser(ctx, userID)
+	err = decide(ctx, tx, err)
+	return
+}
+
+func (s service) Stats(ctx context.Context) (stats *schema.Stats, err *schema.Err) {
+	ctx, end := startSpan(ctx, "Stats")
+	defer func() { end(err) }()
+
+	var tx repo.Tx
+	if ctx, tx, err = s.begin(ctx); err != nil {
 		return
 	}
 
-	prs, err = repo.R(tx).GetUserReviews(ctx, userID)
-	decide(ctx, tx, err)
+	stats, err = tx.GetStats(ctx)
+	err = decide(ctx, tx, err)
 	return
 }
@@ -0,0 +1,41 @@
+package service
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/trace"
+	"plassstic.tech/trainee/avito/internal/repo"
+	"plassstic.tech/trainee/avito/internal/schema"
+	"plassstic.tech/trainee/avito/internal/tracing"
+)
+
+func startSpan(ctx context.Context, method string) (context.Context, func(*schema.Err)) {
+	ctx, span := tracing.Start(ctx, "service."+method)
+	return ctx, func(err *schema.Err) {
+		if err != nil {
+			tracing.Fail(span, err)
+		}
+		span.End()
+	}
+}
+
+type tracedTx struct {
+	repo.Tx
+	span trace.Span
+}
+
+func (t tracedTx) Commit(ctx context.Context) error {
+	err := t.Tx.Commit(ctx)
+	if err != nil {
+		tracing.Fail(t.span, err)
+	}
+	t.span.End()
+	return err
+}
+
+func (t tracedTx) Rollback(ctx context.Context) error {
+	err := t.Tx.Rollback(ctx)
+	t.span.SetName("tx rollback")
+	t.span.End()
+	return err
+}
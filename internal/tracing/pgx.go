@@ -0,0 +1,49 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var _ pgx.QueryTracer = PgTracer{}
+
+// PgTracer opens a client span around every query run through a pgx connection.
+type PgTracer struct{}
+
+// queryName pulls the sqlc query name out of the "-- name: X :kind" header.
+func queryName(sql string) string {
+	line, _, _ := strings.Cut(strings.TrimSpace(sql), "\n")
+	if rest, ok := strings.CutPrefix(line, "-- name: "); ok {
+		name, _, _ := strings.Cut(rest, " ")
+		return name
+	}
+	op, _, _ := strings.Cut(line, " ")
+	return strings.ToUpper(op)
+}
+
+func (PgTracer) TraceQueryStart(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	ctx, _ = Start(ctx, "db "+queryName(data.SQL),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("db.system", "postgresql"),
+			attribute.String("db.statement", data.SQL),
+			attribute.Int("db.args", len(data.Args)),
+		),
+	)
+	return ctx
+}
+
+func (PgTracer) TraceQueryEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryEndData) {
+	span := trace.SpanFromContext(ctx)
+	if data.Err != nil && !errors.Is(data.Err, pgx.ErrNoRows) {
+		Fail(span, data.Err)
+	} else {
+		span.SetAttributes(attribute.Int64("db.rows_affected", data.CommandTag.RowsAffected()))
+	}
+	span.End()
+}
@@ -0,0 +1,80 @@
+package tracing
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const instrumentation = "plassstic.tech/trainee/avito"
+
+type Config struct {
+	Endpoint    string
+	SampleRatio float64
+	ServiceName string
+}
+
+// Setup installs the global propagator and, when an endpoint is configured,
+// a tracer provider exporting over OTLP/HTTP. The returned func flushes and
+// stops the provider.
+func Setup(ctx context.Context, cfg Config) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+
+	if cfg.Endpoint == "" {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	exporter, err := otlptracehttp.New(ctx, otlptracehttp.WithEndpointURL(cfg.Endpoint))
+	if err != nil {
+		return nil, err
+	}
+
+	tp := NewProvider(exporter, cfg.SampleRatio, cfg.ServiceName)
+	otel.SetTracerProvider(tp)
+	return tp.Shutdown, nil
+}
+
+func NewProvider(exporter sdktrace.SpanExporter, ratio float64, service string) *sdktrace.TracerProvider {
+	res := resource.NewSchemaless(attribute.String("service.name", service))
+	return sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(ratio))),
+	)
+}
+
+func Tracer() trace.Tracer {
+	return otel.Tracer(instrumentation)
+}
+
+func Start(ctx context.Context, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	return Tracer().Start(ctx, name, opts...)
+}
+
+func Fail(span trace.Span, err error) {
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+}
+
+// Inject writes the trace context of ctx into outgoing headers.
+func Inject(ctx context.Context, h propagation.HeaderCarrier) {
+	otel.GetTextMapPropagator().Inject(ctx, h)
+}
+
+func TraceID(ctx context.Context) (string, bool) {
+	sc := trace.SpanContextFromContext(ctx)
+	if !sc.IsValid() {
+		return "", false
+	}
+	return sc.TraceID().String(), true
+}
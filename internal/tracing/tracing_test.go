@@ -0,0 +1,174 @@
+package tracing_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"plassstic.tech/trainee/avito/internal/events"
+	"plassstic.tech/trainee/avito/internal/repo/repotest"
+	"plassstic.tech/trainee/avito/internal/router/routes"
+	"plassstic.tech/trainee/avito/internal/schema"
+	"plassstic.tech/trainee/avito/internal/service"
+	"plassstic.tech/trainee/avito/internal/tracing"
+)
+
+// record installs a provider that keeps every span in memory for the test.
+func record(t *testing.T) *tracetest.SpanRecorder {
+	t.Helper()
+	sr := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(sr))
+
+	prev := otel.GetTracerProvider()
+	otel.SetTracerProvider(tp)
+	t.Cleanup(func() {
+		otel.SetTracerProvider(prev)
+		_ = tp.Shutdown(t.Context())
+	})
+	return sr
+}
+
+func attr(span sdktrace.ReadOnlySpan, key attribute.Key) (attribute.Value, bool) {
+	for _, kv := range span.Attributes() {
+		if kv.Key == key {
+			return kv.Value, true
+		}
+	}
+	return attribute.Value{}, false
+}
+
+func TestRequestSpans(t *testing.T) {
+	t.Run("memory", func(t *testing.T) { testRequestSpans(t, repotest.Memory, false) })
+	t.Run("postgres", func(t *testing.T) { testRequestSpans(t, repotest.Postgres, true) })
+}
+
+// testRequestSpans checks that one request makes a server span holding the
+// request id, with the service call under it, the transaction under that
+// and, on postgres, the queries under the transaction.
+func testRequestSpans(t *testing.T, newStore repotest.Factory, queries bool) {
+	store := newStore(t)
+	sr := record(t)
+	gin.SetMode(gin.ReleaseMode)
+
+	svc := service.NewWithStore(store, events.Nop(), "test")
+	_, err := svc.AddTeam(t.Context(), schema.Team{TeamName: "backend", Members: []schema.TeamMember{
+		{UserID: "u1", UserName: "Alice", IsActive: true},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	engine := gin.New()
+	engine.ContextWithFallback = true
+	engine.Use(routes.Tracing("test"), routes.RequestID(), routes.Authenticate(nil, false))
+	routes.SetupTeamRoutes(engine.Group("/team"), svc)
+
+	req := httptest.NewRequest(http.MethodGet, "/team/get?team_name=backend", nil)
+	req.Header.Set(routes.RequestIDHeader, "req-trace-1")
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("unexpected status %d: %s", w.Code, w.Body)
+	}
+
+	var server sdktrace.ReadOnlySpan
+	for _, span := range sr.Ended() {
+		if span.SpanKind() == trace.SpanKindServer {
+			server = span
+		}
+	}
+	if server == nil {
+		t.Fatal("no server span recorded")
+	}
+	if id, ok := attr(server, "request.id"); !ok || id.AsString() != "req-trace-1" {
+		t.Fatalf("server span has request.id %q", id.AsString())
+	}
+
+	byParent := map[trace.SpanID][]sdktrace.ReadOnlySpan{}
+	for _, span := range sr.Ended() {
+		if span.SpanContext().TraceID() == server.SpanContext().TraceID() {
+			byParent[span.Parent().SpanID()] = append(byParent[span.Parent().SpanID()], span)
+		}
+	}
+
+	children := byParent[server.SpanContext().SpanID()]
+	if len(children) != 1 || children[0].Name() != "service.GetTeam" {
+		t.Fatalf("expected service.GetTeam under the server span, got %v", names(children))
+	}
+	children = byParent[children[0].SpanContext().SpanID()]
+	if len(children) != 1 || children[0].Name() != "tx" {
+		t.Fatalf("expected tx under service.GetTeam, got %v", names(children))
+	}
+
+	children = byParent[children[0].SpanContext().SpanID()]
+	if !queries {
+		if len(children) != 0 {
+			t.Fatalf("unexpected spans under tx: %v", names(children))
+		}
+		return
+	}
+	if len(children) == 0 {
+		t.Fatal("no query spans under tx")
+	}
+	for _, span := range children {
+		if !strings.HasPrefix(span.Name(), "db ") || span.SpanKind() != trace.SpanKindClient {
+			t.Fatalf("unexpected span %q under tx", span.Name())
+		}
+	}
+}
+
+func names(spans []sdktrace.ReadOnlySpan) []string {
+	out := make([]string, len(spans))
+	for i, span := range spans {
+		out[i] = span.Name()
+	}
+	return out
+}
+
+func TestPgTracer(t *testing.T) {
+	sr := record(t)
+
+	tests := []struct {
+		name string
+		sql  string
+		err  error
+		fail bool
+	}{
+		{name: "db GetTeam", sql: "-- name: GetTeam :one\nselect team_name from teams where team_name = $1"},
+		{name: "db SELECT", sql: "select 1"},
+		{name: "db LockPR", sql: "-- name: LockPR :one\nselect 1", err: pgx.ErrNoRows},
+		{name: "db CreatePR", sql: "-- name: CreatePR :one\ninsert into pull_requests", err: errors.New("boom"), fail: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx, parent := tracing.Start(t.Context(), "tx")
+			qctx := tracing.PgTracer{}.TraceQueryStart(ctx, nil, pgx.TraceQueryStartData{SQL: tc.sql, Args: []any{"x"}})
+			tracing.PgTracer{}.TraceQueryEnd(qctx, nil, pgx.TraceQueryEndData{CommandTag: pgconn.NewCommandTag("SELECT 1"), Err: tc.err})
+			parent.End()
+
+			ended := sr.Ended()
+			span := ended[len(ended)-2]
+			if span.Name() != tc.name || span.Parent().SpanID() != parent.SpanContext().SpanID() {
+				t.Fatalf("unexpected span %q under %s", span.Name(), span.Parent().SpanID())
+			}
+			if stmt, _ := attr(span, "db.statement"); stmt.AsString() != tc.sql {
+				t.Fatalf("unexpected db.statement %q", stmt.AsString())
+			}
+			if failed := span.Status().Code == codes.Error; failed != tc.fail {
+				t.Fatalf("span status %v, expected failure %v", span.Status(), tc.fail)
+			}
+		})
+	}
+}
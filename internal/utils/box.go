@@ -2,37 +2,142 @@ package utils
 
 import (
 	"context"
+	"database/sql"
 
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/rs/zerolog/log"
+	"plassstic.tech/trainee/avito/internal/events"
+	"plassstic.tech/trainee/avito/internal/migrate"
+	"plassstic.tech/trainee/avito/internal/repo"
+	"plassstic.tech/trainee/avito/internal/tracing"
 )
 
 type box struct {
 	ctx context.Context
+	cfg *Config
 
 	dbpool *pgxpool.Pool
+	sqlite *sql.DB
+	store  repo.Store
+	events events.Publisher
+	source string
 }
 
 type Box interface {
+	Config() *Config
+	// Pg is nil in demo mode and with the sqlite backend.
 	Pg() *pgxpool.Pool
+	// SQLite is nil unless STORAGE_BACKEND is sqlite.
+	SQLite() *sql.DB
+	Store() repo.Store
+	Events() events.Publisher
+	EventSource() string
 }
 
-func (b *box) setupPg(cfg PgConfig) {
-	var err error
-	b.dbpool, err = pgxpool.New(b.ctx, cfg.URL())
+func ConnectPg(ctx context.Context, cfg PgConfig) *pgxpool.Pool {
+	pcfg, err := pgxpool.ParseConfig(cfg.URL())
+	if err != nil {
+		log.Fatal().AnErr("err", err).Msg("failed to parse database config")
+	}
+	pcfg.ConnConfig.Tracer = tracing.PgTracer{}
+
+	pool, err := pgxpool.NewWithConfig(ctx, pcfg)
 	if err != nil {
 		log.Fatal().AnErr("err", err).Msg("failed to connect to database")
-	} else if b.dbpool.Ping(b.ctx) != nil {
+	} else if err = pool.Ping(ctx); err != nil {
 		log.Fatal().AnErr("err", err).Msg("failed to ping database")
 	}
+	return pool
+}
+
+func (b *box) setupPg(cfg PgConfig) {
+	b.dbpool = ConnectPg(b.ctx, cfg)
+}
+
+func (b *box) migrate() {
+	m, err := migrate.FromPool(b.dbpool)
+	if err != nil {
+		log.Fatal().Err(err).Msg("failed to load migrations")
+	}
+	defer m.Close()
+
+	if err = m.Up(b.ctx); err != nil {
+		log.Fatal().Err(err).Msg("failed to migrate database")
+	}
+}
+
+// OpenSQLite opens and migrates the sqlite database; unlike postgres there is
+// no separate migration job, so it is always brought up to date.
+func OpenSQLite(ctx context.Context, cfg Storage) *sql.DB {
+	db, err := repo.OpenSQLite(cfg.SQLitePath)
+	if err != nil {
+		log.Fatal().Err(err).Str("path", cfg.SQLitePath).Msg("failed to open sqlite database")
+	}
+
+	m, err := migrate.NewSQLite(db)
+	if err != nil {
+		log.Fatal().Err(err).Msg("failed to load sqlite migrations")
+	}
+	if err = m.Up(ctx); err != nil {
+		log.Fatal().Err(err).Msg("failed to migrate sqlite database")
+	}
+	return db
+}
+
+func (b *box) setupEvents(cfg Events) {
+	b.source = cfg.Source
+	if cfg.WebhookURL == "" {
+		b.events = events.Nop()
+		return
+	}
+
+	mode, err := events.ParseMode(cfg.Mode)
+	if err != nil {
+		log.Fatal().Err(err).Msg("failed to setup events")
+	}
+	b.events = events.NewWebhook(nil, cfg.WebhookURL, mode)
 }
 
 func SetupBox(ctx context.Context, cfg *Config) Box {
-	b := box{ctx: ctx}
-	b.setupPg(cfg.PgConfig)
+	b := box{ctx: ctx, cfg: cfg}
+	switch {
+	case cfg.Server.Demo:
+		log.Warn().Msg("demo mode, data is kept in memory and lost on exit")
+		b.store = repo.NewMemory()
+	case cfg.Storage.Backend == "sqlite":
+		b.sqlite = OpenSQLite(ctx, cfg.Storage)
+		b.store = repo.NewSQLite(b.sqlite)
+	default:
+		b.setupPg(cfg.PgConfig)
+		if cfg.PgConfig.AutoMigrate {
+			b.migrate()
+		}
+		b.store = repo.NewPg(b.dbpool)
+	}
+	b.setupEvents(cfg.Events)
 	return &b
 }
 
+func (b box) Config() *Config {
+	return b.cfg
+}
+
 func (b box) Pg() *pgxpool.Pool {
 	return b.dbpool
 }
+
+func (b box) SQLite() *sql.DB {
+	return b.sqlite
+}
+
+func (b box) Store() repo.Store {
+	return b.store
+}
+
+func (b box) Events() events.Publisher {
+	return b.events
+}
+
+func (b box) EventSource() string {
+	return b.source
+}
@@ -1,17 +1,29 @@
 package utils
 
 import (
+	"errors"
 	"fmt"
+	"maps"
+	"os"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
 
 	"github.com/caarlos0/env/v11"
+	"plassstic.tech/trainee/avito/internal/events"
+	"plassstic.tech/trainee/avito/internal/ratelimit"
+	"plassstic.tech/trainee/avito/internal/schema"
 )
 
 type PgConfig struct {
-	User     string `env:"USER,required,notEmpty"`
-	Password string `env:"PASSWORD,required,notEmpty"`
+	User     string `env:"USER"`
+	Password string `env:"PASSWORD"`
 	Host     string `env:"HOST,notEmpty" envDefault:"localhost"`
 	Port     string `env:"PORT,notEmpty" envDefault:"5432"`
 	Db       string `env:"DB"`
+
+	AutoMigrate bool `env:"AUTO_MIGRATE"`
 }
 
 func (p PgConfig) URL() string {
@@ -29,26 +41,179 @@ func (p PgConfig) URL() string {
 }
 
 type Server struct {
-	Port int `env:"PORT" envDefault:"8080"`
+	Port            int           `env:"PORT" envDefault:"8080"`
+	DrainDelay      time.Duration `env:"DRAIN_DELAY" envDefault:"5s"`
+	ShutdownTimeout time.Duration `env:"SHUTDOWN_TIMEOUT" envDefault:"10s"`
+	// Demo serves from an in-memory store seeded with sample teams.
+	Demo bool `env:"DEMO"`
+}
+
+type Storage struct {
+	Backend    string `env:"BACKEND" envDefault:"postgres"`
+	SQLitePath string `env:"SQLITE_PATH" envDefault:"avito.db"`
+}
+
+type Events struct {
+	WebhookURL string `env:"WEBHOOK_URL"`
+	Mode       string `env:"MODE" envDefault:"structured"`
+	Source     string `env:"SOURCE" envDefault:"/avito/pr-service"`
+}
+
+type JWT struct {
+	JWKSURL        string            `env:"JWKS_URL"`
+	JWKSRefresh    time.Duration     `env:"JWKS_REFRESH" envDefault:"10m"`
+	PublicKeyFiles []string          `env:"PUBLIC_KEY_FILES"`
+	Issuer         string            `env:"ISSUER"`
+	Audience       string            `env:"AUDIENCE"`
+	UserClaim      string            `env:"USER_CLAIM" envDefault:"sub"`
+	RoleClaim      string            `env:"ROLE_CLAIM" envDefault:"role"`
+	DefaultRole    string            `env:"DEFAULT_ROLE" envDefault:"user"`
+	RoleMap        map[string]string `env:"ROLE_MAP"`
+	Leeway         time.Duration     `env:"LEEWAY" envDefault:"30s"`
+}
+
+func (j JWT) Enabled() bool {
+	return j.JWKSURL != "" || len(j.PublicKeyFiles) > 0
+}
+
+type Auth struct {
+	Enabled  bool   `env:"ENABLED" envDefault:"true"`
+	AdminKey string `env:"ADMIN_KEY"`
+	JWT      JWT    `envPrefix:"JWT_"`
+}
+
+type Idempotency struct {
+	TTL           time.Duration `env:"TTL" envDefault:"24h"`
+	PurgeInterval time.Duration `env:"PURGE_INTERVAL" envDefault:"10m"`
+	MaxBody       int64         `env:"MAX_BODY" envDefault:"1048576"`
+}
+
+type RateLimit struct {
+	Enabled bool              `env:"ENABLED" envDefault:"true"`
+	Backend string            `env:"BACKEND" envDefault:"memory"`
+	By      string            `env:"BY" envDefault:"key"`
+	Default string            `env:"DEFAULT" envDefault:"10:20"`
+	IP      string            `env:"IP" envDefault:"20:40"`
+	Groups  map[string]string `env:"GROUPS" envKeyValSeparator:"="`
+	Idle    time.Duration     `env:"IDLE" envDefault:"10m"`
+}
+
+type Metrics struct {
+	Enabled bool   `env:"ENABLED" envDefault:"true"`
+	Port    int    `env:"PORT"`
+	Token   string `env:"TOKEN"`
+}
+
+type Tracing struct {
+	Endpoint    string  `env:"ENDPOINT"`
+	SampleRatio float64 `env:"SAMPLE_RATIO" envDefault:"1"`
+	ServiceName string  `env:"SERVICE_NAME" envDefault:"avito-pr-service"`
 }
 
 type Config struct {
-	PgConfig `envPrefix:"POSTGRES_"`
-	Server   `envPrefix:"SERVER_"`
+	PgConfig    `envPrefix:"POSTGRES_"`
+	Server      `envPrefix:"SERVER_"`
+	Storage     `envPrefix:"STORAGE_"`
+	Events      `envPrefix:"EVENTS_"`
+	Auth        `envPrefix:"AUTH_"`
+	Idempotency `envPrefix:"IDEMPOTENCY_"`
+	RateLimit   `envPrefix:"RATELIMIT_"`
+	Metrics     `envPrefix:"METRICS_"`
+	Tracing     `envPrefix:"TRACING_"`
 }
 
-func (c Config) PostgresURL() string {
-	return fmt.Sprintf(
-		"postgres://%s:%s@%s:%s/%s?sslmode=disable",
-		c.PgConfig.User,
-		c.PgConfig.Password,
-		c.PgConfig.Host,
-		c.PgConfig.Port,
-		c.PgConfig.User,
-	)
+// LoadConfig reads the environment with overrides (env var name to value)
+// taking precedence, so command line flags can replace any setting.
+func LoadConfig(overrides map[string]string) (*Config, error) {
+	environ := env.ToMap(os.Environ())
+	maps.Copy(environ, overrides)
+
+	cfg, err := env.ParseAsWithOptions[Config](env.Options{Environment: environ})
+	if err != nil {
+		return nil, err
+	}
+	if cfg.UsesPostgres() {
+		if cfg.PgConfig.User == "" {
+			return nil, errors.New(`env: required environment variable "POSTGRES_USER" is not set`)
+		}
+		if cfg.PgConfig.Password == "" {
+			return nil, errors.New(`env: required environment variable "POSTGRES_PASSWORD" is not set`)
+		}
+	}
+	return &cfg, nil
+}
+
+// UsesPostgres is false in demo mode and with the sqlite backend.
+func (c Config) UsesPostgres() bool {
+	return !c.Server.Demo && c.Storage.Backend == "postgres"
 }
 
 func ParseConfig() *Config {
-	cfg := env.Must(env.ParseAs[Config]())
-	return &cfg
+	return env.Must(LoadConfig(nil))
+}
+
+func (c Config) Validate() error {
+	var errs []error
+
+	if _, err := events.ParseMode(c.Events.Mode); err != nil {
+		errs = append(errs, err)
+	}
+	if c.Storage.Backend != "postgres" && c.Storage.Backend != "sqlite" {
+		errs = append(errs, fmt.Errorf("STORAGE_BACKEND: unknown backend %q", c.Storage.Backend))
+	}
+	if c.RateLimit.Backend != "memory" && c.RateLimit.Backend != "postgres" {
+		errs = append(errs, fmt.Errorf("RATELIMIT_BACKEND: unknown backend %q", c.RateLimit.Backend))
+	}
+	if c.RateLimit.By != "key" && c.RateLimit.By != "ip" {
+		errs = append(errs, fmt.Errorf("RATELIMIT_BY: expected key or ip, got %q", c.RateLimit.By))
+	}
+	if _, err := ratelimit.ParseLimit(c.RateLimit.Default); err != nil {
+		errs = append(errs, fmt.Errorf("RATELIMIT_DEFAULT: %w", err))
+	}
+	for group, raw := range c.RateLimit.Groups {
+		if _, err := ratelimit.ParseLimit(raw); err != nil {
+			errs = append(errs, fmt.Errorf("RATELIMIT_GROUPS[%s]: %w", group, err))
+		}
+	}
+	if !schema.Role(c.Auth.JWT.DefaultRole).Valid() {
+		errs = append(errs, fmt.Errorf("AUTH_JWT_DEFAULT_ROLE: unknown role %q", c.Auth.JWT.DefaultRole))
+	}
+	if c.Tracing.SampleRatio < 0 || c.Tracing.SampleRatio > 1 {
+		errs = append(errs, fmt.Errorf("TRACING_SAMPLE_RATIO: %v is outside [0, 1]", c.Tracing.SampleRatio))
+	}
+	if c.Metrics.Port != 0 && c.Metrics.Port == c.Server.Port {
+		errs = append(errs, fmt.Errorf("METRICS_PORT: %d is already used by SERVER_PORT", c.Metrics.Port))
+	}
+
+	return errors.Join(errs...)
+}
+
+// Describe lists the effective settings as env assignments with secrets masked.
+func (c Config) Describe() []string {
+	var lines []string
+	describe(reflect.ValueOf(c), "", &lines)
+	slices.Sort(lines)
+	return lines
+}
+
+func describe(v reflect.Value, prefix string, lines *[]string) {
+	for i := range v.NumField() {
+		field := v.Type().Field(i)
+		if p, ok := field.Tag.Lookup("envPrefix"); ok {
+			describe(v.Field(i), prefix+p, lines)
+			continue
+		}
+
+		name, _, _ := strings.Cut(field.Tag.Get("env"), ",")
+		if name == "" {
+			continue
+		}
+		name = prefix + name
+
+		value := fmt.Sprint(v.Field(i).Interface())
+		if value != "" && (strings.Contains(name, "PASSWORD") || strings.Contains(name, "KEY") || strings.Contains(name, "TOKEN")) {
+			value = "********"
+		}
+		*lines = append(*lines, name+"="+value)
+	}
 }
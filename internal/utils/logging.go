@@ -1,6 +1,8 @@
 package utils
 
 import (
+	"context"
+	"crypto/rand"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -23,4 +25,20 @@ func SetupLogging() {
 	}
 
 	log.Logger = zerolog.New(output).With().Timestamp().Caller().Logger()
+	zerolog.DefaultContextLogger = &log.Logger
+}
+
+type requestIDKey struct{}
+
+func NewRequestID() string {
+	return rand.Text()
+}
+
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
 }
@@ -0,0 +1,31 @@
+package utils
+
+import (
+	"context"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"plassstic.tech/trainee/avito/internal/tracing"
+)
+
+func SetupTracing(ctx context.Context, cfg Tracing) func() {
+	shutdown, err := tracing.Setup(ctx, tracing.Config{
+		Endpoint:    cfg.Endpoint,
+		SampleRatio: cfg.SampleRatio,
+		ServiceName: cfg.ServiceName,
+	})
+	if err != nil {
+		log.Fatal().Err(err).Msg("failed to setup tracing")
+	}
+	if cfg.Endpoint != "" {
+		log.Info().Str("endpoint", cfg.Endpoint).Float64("ratio", cfg.SampleRatio).Msg("tracing enabled")
+	}
+
+	return func() {
+		tctx, c := context.WithTimeout(context.Background(), 5*time.Second)
+		defer c()
+		if err := shutdown(tctx); err != nil {
+			log.Error().Err(err).Msg("failed to flush traces")
+		}
+	}
+}
@@ -79,4 +79,16 @@ create trigger apply_validatestatus
     on pull_requests
     for each row
 execute function validatestatus();
--- +goose StatementEnd
\ No newline at end of file
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+drop table reviewers_to_pull_requests;
+drop table pull_requests;
+drop table users_to_teams;
+drop table users;
+drop table teams;
+drop function validatestatus();
+drop function reviewersconstr();
+drop type prstat;
+-- +goose StatementEnd
@@ -6,5 +6,5 @@ alter table users_to_teams add constraint one_team_per_user UNIQUE (user_id);
 
 -- +goose Down
 -- +goose StatementBegin
-SELECT 'down SQL query';
+alter table users_to_teams drop constraint one_team_per_user;
 -- +goose StatementEnd
@@ -0,0 +1,21 @@
+-- +goose Up
+-- +goose StatementBegin
+create type api_role as enum ('admin', 'team_lead', 'user');
+
+create table api_keys
+(
+    key_id     text primary key,
+    key_hash   bytea                   not null,
+    user_id    text references users on update restrict on delete cascade,
+    role       api_role                not null default 'user'::api_role,
+
+    created_at timestamp default now() not null,
+    revoked_at timestamp
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+drop table api_keys;
+drop type api_role;
+-- +goose StatementEnd
@@ -0,0 +1,24 @@
+-- +goose Up
+-- +goose StatementBegin
+create table idempotency_keys
+(
+    idem_key         text                    not null,
+    scope            text                    not null,
+    request_hash     bytea                   not null,
+    status_code      int,
+    response_body    bytea,
+
+    created_at       timestamp default now() not null,
+    expires_at       timestamp               not null,
+    -- headers replayed with the body, e.g. ETag
+    response_headers jsonb,
+    primary key (idem_key, scope)
+);
+
+create index idempotency_keys_expires_at on idempotency_keys (expires_at);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+drop table idempotency_keys;
+-- +goose StatementEnd
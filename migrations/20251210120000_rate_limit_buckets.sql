@@ -0,0 +1,15 @@
+-- +goose Up
+-- +goose StatementBegin
+create table rate_limit_buckets
+(
+    bucket_key text primary key,
+    tokens     float8                  not null,
+    allowed    bool                    not null,
+    updated_at timestamp default now() not null
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+drop table rate_limit_buckets;
+-- +goose StatementEnd
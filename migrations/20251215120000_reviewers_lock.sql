@@ -0,0 +1,31 @@
+-- +goose Up
+-- +goose StatementBegin
+create or replace function reviewersconstr()
+    returns trigger as
+$$
+begin
+    -- serialize reviewer inserts per pull request, otherwise two transactions
+    -- can both count one reviewer and commit three
+    perform 1 from pull_requests where pull_req_id = new.pull_req_id for update;
+
+    if (select count(*) from reviewers_to_pull_requests where pull_req_id = new.pull_req_id) = 2 then
+        raise exception 'reviewers count for pull request % already eq to 2', new.pull_req_id;
+    end if;
+    return new;
+end;
+$$ language plpgsql;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+create or replace function reviewersconstr()
+    returns trigger as
+$$
+begin
+    if (select count(*) from reviewers_to_pull_requests where pull_req_id = new.pull_req_id) = 2 then
+        raise exception 'reviewers count for pull request % already eq to 2', new.pull_req_id;
+    end if;
+    return new;
+end;
+$$ language plpgsql;
+-- +goose StatementEnd
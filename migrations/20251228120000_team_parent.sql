@@ -0,0 +1,10 @@
+-- +goose Up
+-- +goose StatementBegin
+alter table teams add column parent_team text references teams on update restrict on delete set null;
+alter table teams add constraint team_not_own_parent check (parent_team <> team_name);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+alter table teams drop column parent_team;
+-- +goose StatementEnd
@@ -0,0 +1,23 @@
+-- +goose Up
+-- +goose StatementBegin
+alter table users add column deleted_at timestamp;
+alter table teams add column deleted_at timestamp;
+
+-- purging a user keeps the PRs they wrote, just without an author
+alter table pull_requests alter column author_id drop not null;
+alter table pull_requests drop constraint pull_requests_author_id_fkey;
+alter table pull_requests add constraint pull_requests_author_id_fkey
+    foreign key (author_id) references users (user_id) on update restrict on delete set null;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+delete from pull_requests where author_id is null;
+alter table pull_requests drop constraint pull_requests_author_id_fkey;
+alter table pull_requests add constraint pull_requests_author_id_fkey
+    foreign key (author_id) references users (user_id) on update restrict on delete cascade;
+alter table pull_requests alter column author_id set not null;
+
+alter table teams drop column deleted_at;
+alter table users drop column deleted_at;
+-- +goose StatementEnd
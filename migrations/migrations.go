@@ -0,0 +1,36 @@
+package migrations
+
+import (
+	"embed"
+	"io/fs"
+	"strconv"
+	"strings"
+)
+
+//go:embed *.sql
+var FS embed.FS
+
+// SQLite holds the same schema for the sqlite backend, without the tables
+// behind Postgres-only stores (idempotency keys, rate limit buckets).
+//
+//go:embed sqlite/*.sql
+var sqlite embed.FS
+
+func SQLite() fs.FS {
+	sub, _ := fs.Sub(sqlite, "sqlite")
+	return sub
+}
+
+// Latest returns the version of the newest migration shipped with the binary.
+func Latest() int64 {
+	files, _ := fs.Glob(FS, "*.sql")
+
+	var latest int64
+	for _, f := range files {
+		prefix, _, _ := strings.Cut(f, "_")
+		if v, err := strconv.ParseInt(prefix, 10, 64); err == nil && v > latest {
+			latest = v
+		}
+	}
+	return latest
+}
@@ -0,0 +1,92 @@
+-- +goose Up
+-- +goose StatementBegin
+create table teams
+(
+    team_name text primary key
+);
+
+create table users
+(
+    user_id   text primary key,
+    user_name text not null,
+    is_active boolean not null default true
+);
+
+create table users_to_teams
+(
+    user_id   text references users on update restrict on delete cascade not null,
+    team_name text references teams on update restrict on delete cascade not null,
+    primary key (user_id, team_name)
+);
+
+create table pull_requests
+(
+    pull_req_id     text primary key,
+    pull_req_name   text not null,
+    author_id       text references users (user_id) on update restrict on delete cascade not null,
+    pull_req_status text not null default 'open' check (pull_req_status in ('open', 'merged')),
+
+    created_at      timestamp not null default (datetime('now')),
+    merged_at       timestamp
+);
+
+create table reviewers_to_pull_requests
+(
+    user_id     text references users on update restrict on delete cascade,
+    pull_req_id text references pull_requests on update restrict on delete cascade,
+    primary key (user_id, pull_req_id)
+);
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+create trigger apply_reviewersconstr
+    before insert
+    on reviewers_to_pull_requests
+    for each row
+    when (select count(*) from reviewers_to_pull_requests where pull_req_id = new.pull_req_id) >= 2
+begin
+    select raise(abort, 'reviewers count for pull request already eq to 2');
+end;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+create trigger apply_reviewersconstr_update
+    before update of pull_req_id
+    on reviewers_to_pull_requests
+    for each row
+    when (select count(*) from reviewers_to_pull_requests where pull_req_id = new.pull_req_id) >= 2
+begin
+    select raise(abort, 'reviewers count for pull request already eq to 2');
+end;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+create trigger apply_validatestatus
+    before update of pull_req_status
+    on pull_requests
+    for each row
+    when old.pull_req_status = 'merged' and new.pull_req_status = 'open'
+begin
+    select raise(abort, 'pr already merged');
+end;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+create trigger apply_mergedat
+    after update of pull_req_status
+    on pull_requests
+    for each row
+    when old.pull_req_status = 'open' and new.pull_req_status = 'merged'
+begin
+    update pull_requests set merged_at = datetime('now') where pull_req_id = new.pull_req_id;
+end;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+drop table reviewers_to_pull_requests;
+drop table pull_requests;
+drop table users_to_teams;
+drop table users;
+drop table teams;
+-- +goose StatementEnd
@@ -0,0 +1,9 @@
+-- +goose Up
+-- +goose StatementBegin
+create unique index one_team_per_user on users_to_teams (user_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+drop index one_team_per_user;
+-- +goose StatementEnd
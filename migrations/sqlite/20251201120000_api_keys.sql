@@ -0,0 +1,18 @@
+-- +goose Up
+-- +goose StatementBegin
+create table api_keys
+(
+    key_id     text primary key,
+    key_hash   blob      not null,
+    user_id    text references users on update restrict on delete cascade,
+    role       text      not null default 'user' check (role in ('admin', 'team_lead', 'user')),
+
+    created_at timestamp not null default (datetime('now')),
+    revoked_at timestamp
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+drop table api_keys;
+-- +goose StatementEnd
@@ -0,0 +1,9 @@
+-- +goose Up
+-- +goose StatementBegin
+alter table pull_requests add column version bigint default 1 not null;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+alter table pull_requests drop column version;
+-- +goose StatementEnd
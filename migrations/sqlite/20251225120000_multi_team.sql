@@ -0,0 +1,24 @@
+-- +goose Up
+-- +goose StatementBegin
+drop index one_team_per_user;
+alter table users_to_teams add column is_primary boolean not null default false;
+update users_to_teams set is_primary = true;
+create unique index one_primary_team_per_user on users_to_teams (user_id) where is_primary;
+
+alter table pull_requests add column team_name text references teams on update restrict on delete set null;
+update pull_requests
+set team_name = (
+    select ut.team_name from users_to_teams ut
+    where ut.user_id = pull_requests.author_id and ut.is_primary
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+alter table pull_requests drop column team_name;
+
+drop index one_primary_team_per_user;
+delete from users_to_teams where not is_primary;
+alter table users_to_teams drop column is_primary;
+create unique index one_team_per_user on users_to_teams (user_id);
+-- +goose StatementEnd
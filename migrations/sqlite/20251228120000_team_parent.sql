@@ -0,0 +1,9 @@
+-- +goose Up
+-- +goose StatementBegin
+alter table teams add column parent_team text references teams on update restrict on delete set null;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+alter table teams drop column parent_team;
+-- +goose StatementEnd
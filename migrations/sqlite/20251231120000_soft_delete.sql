@@ -0,0 +1,102 @@
+-- +goose NO TRANSACTION
+-- sqlite cannot alter a foreign key, so pull_requests is rebuilt. That needs
+-- foreign keys off, which only works outside a transaction.
+
+-- +goose Up
+-- +goose StatementBegin
+pragma foreign_keys = off;
+begin;
+
+alter table users add column deleted_at timestamp;
+alter table teams add column deleted_at timestamp;
+
+create table pull_requests_new
+(
+    pull_req_id     text primary key,
+    pull_req_name   text not null,
+    author_id       text references users (user_id) on update restrict on delete set null,
+    pull_req_status text not null default 'open' check (pull_req_status in ('open', 'merged')),
+
+    created_at      timestamp not null default (datetime('now')),
+    merged_at       timestamp,
+    version         bigint default 1 not null,
+    team_name       text references teams on update restrict on delete set null
+);
+insert into pull_requests_new (pull_req_id, pull_req_name, author_id, pull_req_status, created_at, merged_at, version, team_name)
+select pull_req_id, pull_req_name, author_id, pull_req_status, created_at, merged_at, version, team_name
+from pull_requests;
+drop table pull_requests;
+alter table pull_requests_new rename to pull_requests;
+
+create trigger apply_validatestatus
+    before update of pull_req_status
+    on pull_requests
+    for each row
+    when old.pull_req_status = 'merged' and new.pull_req_status = 'open'
+begin
+    select raise(abort, 'pr already merged');
+end;
+
+create trigger apply_mergedat
+    after update of pull_req_status
+    on pull_requests
+    for each row
+    when old.pull_req_status = 'open' and new.pull_req_status = 'merged'
+begin
+    update pull_requests set merged_at = datetime('now') where pull_req_id = new.pull_req_id;
+end;
+
+commit;
+pragma foreign_keys = on;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+pragma foreign_keys = off;
+begin;
+
+create table pull_requests_old
+(
+    pull_req_id     text primary key,
+    pull_req_name   text not null,
+    author_id       text references users (user_id) on update restrict on delete cascade not null,
+    pull_req_status text not null default 'open' check (pull_req_status in ('open', 'merged')),
+
+    created_at      timestamp not null default (datetime('now')),
+    merged_at       timestamp,
+    version         bigint default 1 not null,
+    team_name       text references teams on update restrict on delete set null
+);
+insert into pull_requests_old (pull_req_id, pull_req_name, author_id, pull_req_status, created_at, merged_at, version, team_name)
+select pull_req_id, pull_req_name, author_id, pull_req_status, created_at, merged_at, version, team_name
+from pull_requests
+where author_id is not null;
+delete from reviewers_to_pull_requests
+where pull_req_id in (select pull_req_id from pull_requests where author_id is null);
+drop table pull_requests;
+alter table pull_requests_old rename to pull_requests;
+
+create trigger apply_validatestatus
+    before update of pull_req_status
+    on pull_requests
+    for each row
+    when old.pull_req_status = 'merged' and new.pull_req_status = 'open'
+begin
+    select raise(abort, 'pr already merged');
+end;
+
+create trigger apply_mergedat
+    after update of pull_req_status
+    on pull_requests
+    for each row
+    when old.pull_req_status = 'open' and new.pull_req_status = 'merged'
+begin
+    update pull_requests set merged_at = datetime('now') where pull_req_id = new.pull_req_id;
+end;
+
+alter table teams drop column deleted_at;
+alter table users drop column deleted_at;
+
+commit;
+pragma foreign_keys = on;
+-- +goose StatementEnd
@@ -4,59 +4,198 @@ values ($1)
 returning team_name;
 
 -- name: GetTeam :one
-select team_name from teams 
+select * from teams 
+where team_name = $1 and deleted_at is null;
+
+-- name: SetTeamParent :execrows
+update teams
+set parent_team = $2
 where team_name = $1;
 
+-- name: GetSubTeams :many
+select team_name from teams
+where parent_team = $1 and deleted_at is null
+order by team_name;
+
+-- name: ListTeamParents :many
+select team_name, parent_team from teams
+where parent_team is not null;
+
+-- name: ReparentSubTeams :exec
+update teams
+set parent_team = @new_parent
+where parent_team = @old_parent;
+
+-- name: SoftDeleteTeam :execrows
+update teams
+set deleted_at = now()
+where team_name = $1 and deleted_at is null;
+
+-- name: PurgeTeam :one
+delete from teams
+where team_name = $1
+returning *;
+
+-- name: RemoveTeamMemberships :many
+delete from users_to_teams
+where team_name = $1
+returning user_id;
+
+-- name: LockTeamHierarchy :exec
+select pg_advisory_xact_lock(hashtext('teams.parent_team'));
+
 -- name: GetUsersForTeam :many
-select u.user_id, u.user_name, u.is_active
+select u.user_id, u.user_name, u.is_active, ut.is_primary
 from users_to_teams ut
 inner join users u using (user_id)
-where ut.team_name = $1;
+where ut.team_name = $1
+order by u.user_id;
+
+-- name: GetDeletedUsers :many
+select user_id from users
+where user_id = any($1::text[]) and deleted_at is not null
+order by user_id;
 
 -- name: EnsureUsers :batchexec
 insert into users (user_id, user_name, is_active) 
 values ($1, $2, $3) 
 on conflict (user_id) do update
-set user_name = excluded.user_name,
-    is_active = excluded.is_active;
+set user_name  = excluded.user_name,
+    is_active  = excluded.is_active,
+    deleted_at = null;
+
+-- name: InsertUsers :batchexec
+insert into users (user_id, user_name, is_active) 
+values ($1, $2, $3) 
+on conflict (user_id) do nothing;
 
 -- name: AddUsersToTeam :batchexec
 insert into users_to_teams (user_id, team_name) 
 values ($1, $2) 
 on conflict (user_id, team_name) do nothing;
 
+-- name: GetTeamsOfUsers :many
+select u.user_id, ut.team_name
+from users u
+left join users_to_teams ut using (user_id)
+where u.user_id = any($1::text[]) and u.deleted_at is null
+order by u.user_id, ut.is_primary desc, ut.team_name;
+
+-- name: GetActiveMembersOfTeams :many
+select utt.team_name, utt.user_id
+from users_to_teams utt
+inner join users u on u.user_id = utt.user_id
+where utt.team_name = any($1::text[])
+  and u.is_active = true
+order by utt.team_name, utt.user_id;
+
+-- name: RemoveUserFromTeam :execrows
+delete from users_to_teams
+where user_id = $1 and team_name = $2;
+
+-- name: ClearPrimaryTeam :exec
+update users_to_teams
+set is_primary = false
+where user_id = $1 and is_primary;
+
+-- name: MarkPrimaryTeam :execrows
+update users_to_teams
+set is_primary = true
+where user_id = $1 and team_name = $2;
+
+-- name: PromotePrimaryTeams :exec
+update users_to_teams ut
+set is_primary = true
+where ut.user_id = any($1::text[])
+  and ut.team_name = (select min(o.team_name) from users_to_teams o where o.user_id = ut.user_id)
+  and not exists(select 1 from users_to_teams p where p.user_id = ut.user_id and p.is_primary);
+
 -- name: UserSetIsActive :one
 update users
 set is_active = $2
+where user_id = $1 and deleted_at is null
+returning *;
+
+-- name: SoftDeleteUser :execrows
+update users
+set deleted_at = now(),
+    is_active  = false
+where user_id = $1 and deleted_at is null;
+
+-- name: PurgeUser :one
+delete from users
 where user_id = $1
 returning *;
 
+-- name: RemoveUserMemberships :exec
+delete from users_to_teams
+where user_id = $1;
+
+-- name: DropOpenReviews :many
+delete from reviewers_to_pull_requests rtp
+using pull_requests pr
+where pr.pull_req_id = rtp.pull_req_id
+  and rtp.user_id = $1
+  and pr.pull_req_status = 'open'::prstat
+returning rtp.pull_req_id;
+
+-- name: RevokeUserAPIKeys :exec
+update api_keys
+set revoked_at = now()
+where user_id = $1 and revoked_at is null;
+
 -- name: GetUserWithTeam :one
 select u.user_id, u.user_name, u.is_active, ut.team_name
 from users u
-left join users_to_teams ut using (user_id)
-where u.user_id = $1;
+left join users_to_teams ut on ut.user_id = u.user_id and ut.is_primary
+where u.user_id = $1 and u.deleted_at is null;
 
--- name: GetUserTeam :one
+-- name: GetUserTeams :many
 select team_name
 from users_to_teams
-where user_id = $1;
+where user_id = $1
+order by is_primary desc, team_name;
 
 -- name: CreatePR :one
-insert into pull_requests (pull_req_id, pull_req_name, author_id)
-values ($1, $2, $3)
+insert into pull_requests (pull_req_id, pull_req_name, author_id, team_name)
+values ($1, $2, $3, $4)
 returning *;
 
 -- name: GetPR :one
 select * from pull_requests
 where pull_req_id = $1;
 
+-- name: LockPR :one
+select pull_req_status, version from pull_requests
+where pull_req_id = $1
+for update;
+
+-- name: GetExistingPRs :many
+select pull_req_id from pull_requests
+where pull_req_id = any($1::text[]);
+
+-- name: CreatePRBatch :batchone
+insert into pull_requests (pull_req_id, pull_req_name, author_id, team_name)
+values ($1, $2, $3, $4)
+returning *;
+
+-- name: AddReviewerBatch :batchexec
+insert into reviewers_to_pull_requests (user_id, pull_req_id)
+values ($1, $2);
+
 -- name: MergePR :one
 update pull_requests
-set pull_req_status = 'merged'::prstat
+set pull_req_status = 'merged'::prstat,
+    version         = version + (pull_req_status = 'open'::prstat)::int
 where pull_req_id = $1
 returning *;
 
+-- name: BumpPRVersion :one
+update pull_requests
+set version = version + 1
+where pull_req_id = $1
+returning version;
+
 -- name: AddReviewer :one
 insert into reviewers_to_pull_requests (user_id, pull_req_id)
 values ($1, $2)
@@ -104,13 +243,13 @@ select exists(
 ) as is_assigned;
 
 -- name: CheckTeamExists :one
-select exists(select 1 from teams where team_name = $1) as exists;
+select exists(select 1 from teams where team_name = $1 and deleted_at is null) as exists;
 
 -- name: CheckPRExists :one
 select exists(select 1 from pull_requests where pull_req_id = $1) as exists;
 
 -- name: CheckUserExists :one
-select exists(select 1 from users where user_id = $1) as exists;
+select exists(select 1 from users where user_id = $1 and deleted_at is null) as exists;
 
 -- name: GetPRwithReviewers :one
 select 
@@ -120,6 +259,8 @@ select
     pr.pull_req_status,
     pr.created_at,
     pr.merged_at,
+    pr.version,
+    pr.team_name,
     coalesce(
         array_agg(rtp.user_id) filter (where rtp.user_id is not null),
         array[]::text[]
@@ -127,4 +268,102 @@ select
 from pull_requests pr
 left join reviewers_to_pull_requests rtp on pr.pull_req_id = rtp.pull_req_id
 where pr.pull_req_id = $1
-group by pr.pull_req_id, pr.pull_req_name, pr.author_id, pr.pull_req_status, pr.created_at, pr.merged_at;
+group by pr.pull_req_id, pr.pull_req_name, pr.author_id, pr.pull_req_status, pr.created_at, pr.merged_at, pr.version, pr.team_name;
+
+-- name: CreateAPIKey :one
+insert into api_keys (key_id, key_hash, user_id, role)
+values ($1, $2, $3, $4)
+returning *;
+
+-- name: GetAPIKey :one
+select * from api_keys
+where key_id = $1;
+
+-- name: RevokeAPIKey :one
+update api_keys
+set revoked_at = now()
+where key_id = $1 and revoked_at is null
+returning *;
+
+-- name: DropExpiredIdempotencyKey :exec
+delete from idempotency_keys
+where idem_key = $1 and scope = $2 and expires_at <= now();
+
+-- name: ClaimIdempotencyKey :execrows
+insert into idempotency_keys (idem_key, scope, request_hash, expires_at)
+values (@idem_key, @scope, @request_hash, now() + make_interval(secs => @ttl_seconds::float8))
+on conflict (idem_key, scope) do nothing;
+
+-- name: GetIdempotencyKey :one
+select * from idempotency_keys
+where idem_key = $1 and scope = $2;
+
+-- name: CompleteIdempotencyKey :exec
+update idempotency_keys
+set status_code = $3, response_body = $4, response_headers = $5
+where idem_key = $1 and scope = $2;
+
+-- name: ReleaseIdempotencyKey :exec
+delete from idempotency_keys
+where idem_key = $1 and scope = $2;
+
+-- name: PurgeIdempotencyKeys :execrows
+delete from idempotency_keys
+where expires_at <= now();
+
+-- name: TakeRateLimitToken :one
+insert into rate_limit_buckets as b (bucket_key, tokens, allowed, updated_at)
+values (@bucket_key, @burst::float8 - 1, true, now())
+on conflict (bucket_key) do update
+set tokens     = case
+                     when least(@burst::float8, b.tokens + extract(epoch from now() - b.updated_at) * @rate::float8) >= 1
+                         then least(@burst::float8, b.tokens + extract(epoch from now() - b.updated_at) * @rate::float8) - 1
+                     else least(@burst::float8, b.tokens + extract(epoch from now() - b.updated_at) * @rate::float8)
+                 end,
+    allowed    = least(@burst::float8, b.tokens + extract(epoch from now() - b.updated_at) * @rate::float8) >= 1,
+    updated_at = now()
+returning tokens, allowed;
+
+-- name: PurgeRateLimitBuckets :execrows
+delete from rate_limit_buckets
+where updated_at < now() - make_interval(secs => @idle_seconds::float8);
+
+-- name: CountOpenPRs :one
+select count(*) as open_count
+from pull_requests
+where pull_req_status = 'open'::prstat;
+
+-- name: GetReviewerLoad :many
+select rtp.user_id, count(*) as open_reviews
+from reviewers_to_pull_requests rtp
+inner join pull_requests pr on pr.pull_req_id = rtp.pull_req_id
+where pr.pull_req_status = 'open'::prstat
+group by rtp.user_id;
+
+-- name: ListTeams :many
+select team_name from teams
+where deleted_at is null
+order by team_name;
+
+-- name: ListPRsWithReviewers :many
+select
+    pr.pull_req_id,
+    pr.pull_req_name,
+    pr.author_id,
+    pr.pull_req_status,
+    pr.created_at,
+    pr.merged_at,
+    pr.version,
+    pr.team_name,
+    coalesce(
+        array_agg(rtp.user_id) filter (where rtp.user_id is not null),
+        array[]::text[]
+    ) as assigned_reviewers
+from pull_requests pr
+left join reviewers_to_pull_requests rtp on pr.pull_req_id = rtp.pull_req_id
+group by pr.pull_req_id, pr.pull_req_name, pr.author_id, pr.pull_req_status, pr.created_at, pr.merged_at, pr.version, pr.team_name
+order by pr.created_at, pr.pull_req_id;
+
+-- name: ImportPR :exec
+insert into pull_requests (pull_req_id, pull_req_name, author_id, pull_req_status, created_at, merged_at, version, team_name)
+values ($1, $2, $3, $4, $5, $6, $7, $8);
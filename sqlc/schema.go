@@ -0,0 +1,8 @@
+package sqlc
+
+import _ "embed"
+
+// Schema is the schema sqlc generates gensql from.
+//
+//go:embed schema.sql
+var Schema string
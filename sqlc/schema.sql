@@ -2,33 +2,41 @@ create type prstat as enum ('open', 'merged');
 
 create table teams
 (
-    team_name text primary key
+    team_name   text primary key,
+    parent_team text references teams on update restrict on delete set null,
+    deleted_at  timestamp,
+    constraint team_not_own_parent check (parent_team <> team_name)
 );
 
 create table users
 (
     user_id   text primary key,
     user_name text not null,
-    is_active bool not null default true
+    is_active bool not null default true,
+    deleted_at timestamp
 );
 
 create table users_to_teams
 (
     user_id   text references users on update restrict on delete cascade not null,
     team_name text references teams on update restrict on delete cascade not null,
-    primary key (user_id, team_name),
-    unique (user_id)
+    is_primary bool not null default false,
+    primary key (user_id, team_name)
 );
 
+create unique index one_primary_team_per_user on users_to_teams (user_id) where is_primary;
+
 create table pull_requests
 (
     pull_req_id     text primary key,
     pull_req_name   text                                   not null,
-    author_id       text references users (user_id) on update restrict on delete cascade not null,
+    author_id       text references users (user_id) on update restrict on delete set null,
     pull_req_status prstat default 'open'::prstat not null,
 
     created_at      timestamp default now() not null,
-    merged_at       timestamp
+    merged_at       timestamp,
+    version         bigint default 1 not null,
+    team_name       text references teams on update restrict on delete set null
 );
 
 create table reviewers_to_pull_requests
@@ -38,10 +46,52 @@ create table reviewers_to_pull_requests
     primary key (user_id, pull_req_id)
 );
 
+create type api_role as enum ('admin', 'team_lead', 'user');
+
+create table api_keys
+(
+    key_id     text primary key,
+    key_hash   bytea                   not null,
+    user_id    text references users on update restrict on delete cascade,
+    role       api_role                not null default 'user'::api_role,
+
+    created_at timestamp default now() not null,
+    revoked_at timestamp
+);
+
+create table idempotency_keys
+(
+    idem_key         text                    not null,
+    scope            text                    not null,
+    request_hash     bytea                   not null,
+    status_code      int,
+    response_body    bytea,
+
+    created_at       timestamp default now() not null,
+    expires_at       timestamp               not null,
+    -- headers replayed with the body, e.g. ETag
+    response_headers jsonb,
+    primary key (idem_key, scope)
+);
+
+create index idempotency_keys_expires_at on idempotency_keys (expires_at);
+
+create table rate_limit_buckets
+(
+    bucket_key text primary key,
+    tokens     float8                  not null,
+    allowed    bool                    not null,
+    updated_at timestamp default now() not null
+);
+
 create function reviewersconstr()
     returns trigger as
 $$
 begin
+    -- serialize reviewer inserts per pull request, otherwise two transactions
+    -- can both count one reviewer and commit three
+    perform 1 from pull_requests where pull_req_id = new.pull_req_id for update;
+
     if (select count(*) from reviewers_to_pull_requests where pull_req_id = new.pull_req_id) = 2 then
         raise exception 'reviewers count for pull request % already eq to 2', new.pull_req_id;
     end if;